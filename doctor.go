@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/admin"
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/health"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+func newDoctorCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that synconik is able to work with the current configuration",
+		Run:   RunDoctor,
+	}
+}
+
+func RunDoctor(cmd *cobra.Command, args []string) {
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.ConfigureLogger()
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	client := icnk_client.NewClient(httpClient, config.Iconik.URL, config.Iconik.AppID, config.Iconik.Token)
+
+	checker := health.NewChecker()
+
+	var badgerStore *store.BadgerStore
+	if !store.BadgerStoreExists(config.Store.DataDir) {
+		// the doctor never creates the store, a missing one hints at a wrong
+		// store.data_dir
+		checker.AddReadiness("store", func(ctx context.Context) error {
+			return fmt.Errorf("store does not exist in %s", config.Store.DataDir)
+		})
+		addReadinessChecks(checker, config, client, nil)
+	} else if badgerStore, err = store.NewReadOnlyBadgerStore(config.Store.DataDir); err != nil {
+		checker.AddReadiness("store", runningStoreCheck(config, err))
+		addReadinessChecks(checker, config, client, nil)
+	} else {
+		defer badgerStore.Close()
+		addReadinessChecks(checker, config, client, badgerStore)
+	}
+
+	report, ok := checker.Ready(context.Background())
+	for _, result := range report.Checks {
+		if result.OK {
+			fmt.Printf("OK    %s\n", result.Name)
+		} else {
+			fmt.Printf("FAIL  %s: %s\n", result.Name, result.Error)
+		}
+	}
+
+	if !ok {
+		if badgerStore != nil {
+			badgerStore.Close()
+		}
+		os.Exit(1)
+	}
+}
+
+// runningStoreCheck checks the store through the admin API of the running
+// instance, which holds the lock of the database
+func runningStoreCheck(config *config.Config, openErr error) health.CheckFunc {
+	return func(ctx context.Context) error {
+		client, err := admin.NewClient(config)
+		if err != nil {
+			return fmt.Errorf(
+				"%w (if synconik is running, enable the admin API to check its store)", openErr,
+			)
+		}
+
+		_, err = client.Status(ctx, 0)
+		return err
+	}
+}
+
+// addReadinessChecks registers the checks telling whether synconik is able
+// to sync files, the store check is skipped when store is nil
+func addReadinessChecks(
+	checker *health.Checker, config *config.Config, client icnk_client.Client, store health.Pinger,
+) {
+	if store != nil {
+		checker.AddReadiness("store", health.StoreCheck(store))
+	}
+	checker.AddReadiness("iconik", health.IconikCheck(client))
+	checker.AddReadiness("storage", health.StorageCheck(client, config.Iconik.StorageID))
+	checker.AddReadiness("scanner_dir", health.DirCheck(config.Scanner.Dir))
+}
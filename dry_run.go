@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/dryrun"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+// RunDryRun prints what a scan would create and upload without changing
+// anything in Iconik or the database
+func RunDryRun(config *config.Config, client icnk_client.Client) {
+	storage, err := client.GetStorage(context.Background(), config.Iconik.StorageID)
+	if err != nil {
+		fmt.Printf("Error getting storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	// a share that was never synced has no database yet
+	var base store.Store
+	if store.BadgerStoreExists(config.Store.DataDir) {
+		badgerStore, err := store.NewReadOnlyBadgerStore(config.Store.DataDir)
+		if err != nil {
+			fmt.Printf("Error opening the database, stop synconik before a dry run: %v\n", err)
+			os.Exit(1)
+		}
+		defer badgerStore.Close()
+		base = badgerStore
+	}
+
+	plan, err := dryrun.Run(config, base, storage)
+	if err != nil {
+		fmt.Printf("Error running the dry run: %v\n", err)
+		os.Exit(1)
+	}
+
+	printPlan(os.Stdout, plan)
+}
+
+func printPlan(w io.Writer, plan *dryrun.Plan) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if len(plan.Collections) > 0 {
+		fmt.Fprintln(tw, "Collections to create:")
+		for _, path := range plan.Collections {
+			fmt.Fprintf(tw, "  %s/\n", path)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if len(plan.Uploads) > 0 {
+		fmt.Fprintln(tw, "Files to upload:")
+		for _, file := range plan.Uploads {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", file.Path, humanize.Bytes(uint64(file.Size)), file.MimeType)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if len(plan.Skipped) > 0 {
+		fmt.Fprintln(tw, "Files skipped:")
+		for _, file := range plan.Skipped {
+			fmt.Fprintf(tw, "  %s\t%s\n", file.Path, file.Reason)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if len(plan.Failed) > 0 {
+		fmt.Fprintln(tw, "Files that would fail:")
+		for _, file := range plan.Failed {
+			fmt.Fprintf(tw, "  %s\t%s\n", file.Path, file.Error)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	tw.Flush()
+
+	fmt.Fprintf(
+		w, "%d collections, %d files to upload (%s), %d skipped, %d failing\n",
+		plan.Totals.Collections,
+		plan.Totals.Uploads,
+		humanize.Bytes(uint64(plan.Totals.UploadBytes)),
+		plan.Totals.Skipped,
+		plan.Totals.Failed,
+	)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/admin"
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+func newInspectCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect <path>",
+		Short: "Show the stored record of a file or directory and its Iconik URL",
+		Args:  cobra.ExactArgs(1),
+		Run:   RunInspect,
+	}
+
+	cmd.Flags().Bool("json", false, "Print the record as JSON")
+
+	return cmd
+}
+
+func RunInspect(cmd *cobra.Command, args []string) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	path := args[0]
+
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var entry usecase.Entry
+
+	err = readState(
+		config,
+		func(store store.Store) (err error) {
+			entry, err = usecase.NewBrowseUseCase(config, store).Inspect(path)
+			return err
+		},
+		func(ctx context.Context, client *admin.Client) error {
+			file, err := client.File(ctx, path)
+			if err != nil {
+				return err
+			}
+			entry = usecase.NewEntry(config.Iconik.URL, path, file)
+			return nil
+		},
+	)
+	if errors.Is(err, store.ErrFileNotFound) {
+		fmt.Printf("%s is not in the store\n", path)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error reading the sync state: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(entry)
+		return
+	}
+
+	printEntry(os.Stdout, entry)
+}
+
+func printEntry(w io.Writer, entry usecase.Entry) {
+	file := entry.File
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	field := func(name string, value interface{}) {
+		if value == "" || value == 0 {
+			return
+		}
+		fmt.Fprintf(tw, "%s:\t%v\n", name, value)
+	}
+
+	field("Path", entry.Path)
+	field("State", file.CurrentState())
+	field("Error", file.Error)
+	field("Updated", file.UpdatedAt)
+	field("Type", file.Type)
+	field("Size", file.Size)
+	field("Frames", file.Frames)
+	field("Members", strings.Join(file.Members, ", "))
+	field("Components", strings.Join(file.Components, ", "))
+	field("Archive", file.Archive)
+	field("Created", file.FileDateCreated)
+	field("Modified", file.FileDateModified)
+
+	if file.IsDir() {
+		field("Collection ID", file.ID)
+	} else {
+		field("Asset ID", file.AssetID)
+		field("Format ID", file.FormatID)
+		field("File set ID", file.FileSetID)
+		field("File ID", file.ID)
+		field("Storage ID", file.StorageID)
+	}
+
+	field("Transcode status", file.TranscodeStatus)
+	field("Transcode job ID", file.TranscodeJobID)
+	field("Transcode attempts", file.TranscodeAttempts)
+	field("Transcode error", file.TranscodeError)
+
+	field("Local action", file.LocalAction)
+	field("Local action at", file.LocalActionAt)
+	field("Local action error", file.LocalActionError)
+	field("Archive path", file.ArchivePath)
+	field("Delete after", file.DeleteAfter)
+
+	field("URL", entry.URL)
+}
@@ -0,0 +1,373 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/progress"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	shutdownTimeout = 5 * time.Second
+
+	defaultStatusFailures = 10
+)
+
+type Scanner interface {
+	TriggerScan() bool
+	Enqueue(relativePath string) error
+}
+
+type Uploader interface {
+	Pause()
+	Resume()
+	Paused() bool
+	WorkerCount() int
+	SetWorkers(ctx context.Context, n int) error
+	InFlight() []uploader.InFlightJob
+}
+
+type UploaderStatus struct {
+	Paused  bool                   `json:"paused"`
+	Workers int                    `json:"workers"`
+	Jobs    []uploader.InFlightJob `json:"jobs"`
+}
+
+// Server is the admin API controlling a running instance. It only listens on
+// a loopback address or a unix socket since it is not authenticated.
+type Server struct {
+	config   *config.Config
+	scanner  Scanner
+	uploader Uploader
+	store    store.Store
+	tracker  *progress.Tracker
+
+	mux      *http.ServeMux
+	server   *http.Server
+	listener net.Listener
+}
+
+func NewServer(
+	config *config.Config,
+	scanner Scanner,
+	uploader Uploader,
+	store store.Store,
+	tracker *progress.Tracker,
+) *Server {
+	s := &Server{
+		config:   config,
+		scanner:  scanner,
+		uploader: uploader,
+		store:    store,
+		tracker:  tracker,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("POST /scan", s.handleScan)
+	s.mux.HandleFunc("GET /uploader", s.handleUploader)
+	s.mux.HandleFunc("POST /uploader/pause", s.handlePause)
+	s.mux.HandleFunc("POST /uploader/resume", s.handleResume)
+	s.mux.HandleFunc("PUT /uploader/workers", s.handleSetWorkers)
+	s.mux.HandleFunc("GET /jobs", s.handleJobs)
+	s.mux.HandleFunc("GET /progress", s.handleProgress)
+	s.mux.HandleFunc("POST /requeue", s.handleRequeue)
+	s.mux.HandleFunc("GET /files", s.handleFile)
+	s.mux.HandleFunc("GET /entries", s.handleEntries)
+	s.mux.HandleFunc("GET /status", s.handleStatus)
+
+	s.server = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// Handler returns the handler serving the admin API
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start listens on the configured unix socket or loopback address, the
+// server is disabled when neither is set
+func (s *Server) Start() error {
+	listener, err := s.listen()
+	if err != nil || listener == nil {
+		return err
+	}
+	s.listener = listener
+
+	log.Info().Str("service", "admin").Msgf("Admin API listening on %s", listener.Addr())
+
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Str("service", "admin").Msg("Admin API failed")
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
+
+	log.Debug().Str("service", "admin").Msg("Stopping the admin API")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Str("service", "admin").Msg("Error stopping the admin API")
+	}
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	adminConfig := s.config.Admin
+
+	if adminConfig.Socket != "" {
+		// remove a socket left behind by a previous run
+		if info, err := os.Stat(adminConfig.Socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(adminConfig.Socket)
+		}
+
+		listener, err := net.Listen("unix", adminConfig.Socket)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(adminConfig.Socket, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+		return listener, nil
+	}
+
+	if adminConfig.Addr == "" {
+		return nil, nil
+	}
+
+	if err := checkLoopback(adminConfig.Addr); err != nil {
+		return nil, err
+	}
+
+	return net.Listen("tcp", adminConfig.Addr)
+}
+
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	return fmt.Errorf("admin API must listen on a loopback address, got %q", addr)
+}
+
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	triggered := s.scanner.TriggerScan()
+	writeJSON(w, http.StatusAccepted, map[string]bool{"triggered": triggered})
+}
+
+func (s *Server) handleUploader(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.uploaderStatus())
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.uploader.Pause()
+	writeJSON(w, http.StatusOK, s.uploaderStatus())
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.uploader.Resume()
+	writeJSON(w, http.StatusOK, s.uploaderStatus())
+}
+
+func (s *Server) handleSetWorkers(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Workers int `json:"workers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.uploader.SetWorkers(r.Context(), body.Workers); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.uploaderStatus())
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.uploader.InFlight())
+}
+
+func (s *Server) handleProgress(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.tracker.Snapshot())
+}
+
+// handleRequeue marks the file as pending and queues it, so it is uploaded
+// again as a new version of the asset it was uploaded to before
+func (s *Server) handleRequeue(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	path, err := s.normalizePath(body.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	previous, err := s.store.GetFile(path)
+	if err != nil && !errors.Is(err, store.ErrFileNotFound) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// the record keeps its asset, so the file is uploaded as a new version of
+	// it rather than as another asset
+	if previous != nil {
+		requeued := *previous
+		requeued.SetState(entity.StatePending, nil)
+		if err := s.store.SaveFile(path, &requeued); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if err := s.scanner.Enqueue(path); err != nil {
+		if previous != nil {
+			s.store.SaveFile(path, previous)
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	log.Info().Str("service", "admin").Msgf("Requeued %s", path)
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"path": path})
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	path, err := s.normalizePath(r.URL.Query().Get("path"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	file, err := s.store.GetFile(path)
+	if errors.Is(err, store.ErrFileNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, file)
+}
+
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	entries, err := usecase.NewBrowseUseCase(s.config, s.store).List(query.Get("prefix"), query.Get("state"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleStatus summarises the sync state of the store together with the
+// uploads in flight
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	failures := defaultStatusFailures
+	if value := r.URL.Query().Get("failures"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid failures: %q", value))
+			return
+		}
+		failures = n
+	}
+
+	summary, err := usecase.NewStatusUseCase(s.store).Summary(failures)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	summary.InFlight = s.tracker.Snapshot()
+
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func (s *Server) uploaderStatus() UploaderStatus {
+	return UploaderStatus{
+		Paused:  s.uploader.Paused(),
+		Workers: s.uploader.WorkerCount(),
+		Jobs:    s.uploader.InFlight(),
+	}
+}
+
+// normalizePath turns a path into its store key, paths outside the scanned
+// directory are rejected unless a file was pushed from there
+func (s *Server) normalizePath(p string) (string, error) {
+	key, err := usecase.NewBrowseUseCase(s.config, s.store).Key(path.Clean(p))
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(key, "/") {
+		return key, nil
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned == "." {
+		return "", errors.New("path is required")
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("path is outside the scanned directory: %s", p)
+	}
+
+	return cleaned, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
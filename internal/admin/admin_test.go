@@ -0,0 +1,313 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/manifest"
+	"github.com/kgantsov/synconik/internal/progress"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakeScanner struct {
+	triggered int
+	queued    []string
+}
+
+func (s *fakeScanner) TriggerScan() bool {
+	s.triggered++
+	return s.triggered == 1
+}
+
+func (s *fakeScanner) Enqueue(relativePath string) error {
+	if strings.HasPrefix(relativePath, "missing") {
+		return errors.New("no such file or directory")
+	}
+	s.queued = append(s.queued, relativePath)
+	return nil
+}
+
+type fakeUploader struct {
+	paused  bool
+	workers int
+	jobs    []uploader.InFlightJob
+}
+
+func (u *fakeUploader) Pause()           { u.paused = true }
+func (u *fakeUploader) Resume()          { u.paused = false }
+func (u *fakeUploader) Paused() bool     { return u.paused }
+func (u *fakeUploader) WorkerCount() int { return u.workers }
+
+func (u *fakeUploader) SetWorkers(ctx context.Context, n int) error {
+	if n <= 0 {
+		return errors.New("number of workers must be positive")
+	}
+	u.workers = n
+	return nil
+}
+
+func (u *fakeUploader) InFlight() []uploader.InFlightJob {
+	return u.jobs
+}
+
+func setupTestServer(t *testing.T) (*Server, *fakeScanner, *fakeUploader, *store.BadgerStore) {
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	t.Cleanup(func() { badgerStore.Close() })
+
+	scanner := &fakeScanner{}
+	uploader := &fakeUploader{
+		workers: 2,
+		jobs: []uploader.InFlightJob{
+			{Worker: "worker-0", Path: "rushes/clip.mov", Size: 1024, StartedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	server := NewServer(&config.Config{}, scanner, uploader, badgerStore, progress.NewTracker())
+
+	return server, scanner, uploader, badgerStore
+}
+
+func request(server *Server, method, target, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
+
+func TestScan(t *testing.T) {
+	server, scanner, _, _ := setupTestServer(t)
+
+	recorder := request(server, "POST", "/scan", "")
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.JSONEq(t, `{"triggered": true}`, recorder.Body.String())
+
+	recorder = request(server, "POST", "/scan", "")
+	assert.JSONEq(t, `{"triggered": false}`, recorder.Body.String())
+	assert.Equal(t, 2, scanner.triggered)
+
+	recorder = request(server, "GET", "/scan", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestUploaderControl(t *testing.T) {
+	server, _, uploader, _ := setupTestServer(t)
+
+	recorder := request(server, "POST", "/uploader/pause", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, uploader.paused)
+
+	var status UploaderStatus
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.True(t, status.Paused)
+	assert.Equal(t, 2, status.Workers)
+	assert.Len(t, status.Jobs, 1)
+
+	recorder = request(server, "POST", "/uploader/resume", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.False(t, uploader.paused)
+
+	recorder = request(server, "PUT", "/uploader/workers", `{"workers": 8}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 8, uploader.workers)
+
+	recorder = request(server, "PUT", "/uploader/workers", `{"workers": 0}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"error": "number of workers must be positive"}`, recorder.Body.String())
+}
+
+func TestJobs(t *testing.T) {
+	server, _, _, _ := setupTestServer(t)
+
+	recorder := request(server, "GET", "/jobs", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(
+		t,
+		`[{"worker": "worker-0", "path": "rushes/clip.mov", "size": 1024, "started_at": "2024-05-01T12:00:00Z"}]`,
+		recorder.Body.String(),
+	)
+}
+
+func TestProgress(t *testing.T) {
+	server, _, _, _ := setupTestServer(t)
+
+	recorder := request(server, "GET", "/progress", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `[]`, recorder.Body.String())
+
+	transfer := server.tracker.Start("S3", "/data/rushes/clip.mov", 2048)
+	defer server.tracker.Finish(transfer)
+
+	recorder = request(server, "GET", "/progress", "")
+	var snapshots []progress.Snapshot
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &snapshots))
+	assert.Len(t, snapshots, 1)
+	assert.Equal(t, "/data/rushes/clip.mov", snapshots[0].Path)
+	assert.Equal(t, int64(2048), snapshots[0].Size)
+}
+
+func TestFilesAndRequeue(t *testing.T) {
+	server, scanner, _, badgerStore := setupTestServer(t)
+
+	err := badgerStore.SaveFile("rushes/clip.mov", &entity.File{Name: "clip.mov", AssetID: "A1"})
+	assert.NoError(t, err)
+
+	recorder := request(server, "GET", "/files?path=/rushes/clip.mov", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"directory_path": "", "name": "clip.mov", "asset_id": "A1"}`, recorder.Body.String())
+
+	recorder = request(server, "GET", "/files?path=rushes/other.mov", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	err = badgerStore.SaveFile("rushes/synced.mov", &entity.File{Name: "synced.mov", AssetID: "A2", State: entity.StateSynced})
+	assert.NoError(t, err)
+
+	recorder = request(server, "POST", "/requeue", `{"path": "rushes/synced.mov"}`)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, []string{"rushes/synced.mov"}, scanner.queued)
+
+	// the record keeps its asset to upload the file as a new version of it
+	requeued, err := badgerStore.GetFile("rushes/synced.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatePending, requeued.State)
+	assert.Equal(t, "A2", requeued.AssetID)
+
+	// the stored state is kept when the file can not be queued
+	err = badgerStore.SaveFile("missing.mov", &entity.File{Name: "missing.mov", State: entity.StateSynced})
+	assert.NoError(t, err)
+
+	recorder = request(server, "POST", "/requeue", `{"path": "missing.mov"}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	missing, err := badgerStore.GetFile("missing.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, missing.State)
+
+	// paths outside the scanned directory are rejected
+	for _, path := range []string{"../../etc/passwd", "rushes/../../etc/passwd", "/.."} {
+		recorder = request(server, "POST", "/requeue", `{"path": "`+path+`"}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+
+		recorder = request(server, "GET", "/files?path="+path, "")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+	}
+	assert.Equal(t, []string{"rushes/synced.mov"}, scanner.queued)
+}
+
+func TestFilesAndRequeuePushed(t *testing.T) {
+	server, scanner, _, badgerStore := setupTestServer(t)
+
+	dir := t.TempDir() + "/"
+	server.config.Scanner.Dir = dir + "media/"
+	server.config.Transcoding.Action = usecase.TranscodeActionSkip
+	assert.NoError(t, os.MkdirAll(dir+"delivery", 0755))
+	assert.NoError(t, os.WriteFile(dir+"delivery/clip.mov", []byte("video"), 0644))
+
+	client := icnk_client.NewMockClient()
+	client.On("CreateAsset", mock.Anything, mock.Anything).Return(&icnk_client.Asset{ID: "A1"}, nil)
+	client.On("CreateAssetFormat", mock.Anything, "A1", mock.Anything).Return(&icnk_client.Format{ID: "FMT"}, nil)
+	client.On("CreateFileSet", mock.Anything, "A1", mock.Anything).Return(&icnk_client.FileSet{ID: "FS"}, nil)
+	client.On("CreateFile", mock.Anything, "A1", mock.Anything).Return(&icnk_client.File{ID: "F1"}, nil)
+	client.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	client.On("CloseFile", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	storage := &icnk_client.Storage{ID: "S1", Method: "S3"}
+	report := usecase.NewPushUseCase(server.config, client, badgerStore, storage).Push(
+		[]manifest.Entry{{Path: dir + "delivery/clip.mov"}},
+	)
+	assert.Equal(t, 1, report.Uploaded)
+
+	// files pushed from outside the scanned directory are addressed by their
+	// absolute path
+	recorder := request(server, "GET", "/files?path="+dir+"delivery/clip.mov", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"asset_id":"A1"`)
+
+	recorder = request(server, "POST", "/requeue", `{"path": "`+dir+`delivery/clip.mov"}`)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, []string{dir + "delivery/clip.mov"}, scanner.queued)
+
+	requeued, err := badgerStore.GetFile(dir + "delivery/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatePending, requeued.State)
+	assert.Equal(t, "A1", requeued.AssetID)
+
+	// other absolute paths are still relative to the scanned directory
+	recorder = request(server, "POST", "/requeue", `{"path": "`+dir+`delivery/other.mov"}`)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+	assert.Equal(t, strings.TrimPrefix(dir, "/")+"delivery/other.mov", scanner.queued[1])
+}
+
+func TestStatus(t *testing.T) {
+	server, _, _, badgerStore := setupTestServer(t)
+
+	err := badgerStore.SaveFile("rushes/a.mov", &entity.File{Name: "a.mov", Size: 10, State: entity.StateSynced})
+	assert.NoError(t, err)
+	err = badgerStore.SaveFile("rushes/b.mov", &entity.File{Name: "b.mov", State: entity.StateFailed, Error: "forbidden"})
+	assert.NoError(t, err)
+
+	transfer := server.tracker.Start("S3", "/data/rushes/c.mov", 2048)
+	defer server.tracker.Finish(transfer)
+
+	recorder := request(server, "GET", "/status", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var summary usecase.StatusSummary
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Files[entity.StateSynced])
+	assert.Equal(t, int64(10), summary.SyncedBytes)
+	assert.Len(t, summary.RecentFailures, 1)
+	assert.Len(t, summary.InFlight, 1)
+
+	recorder = request(server, "GET", "/status?failures=0", "")
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &summary))
+	assert.Len(t, summary.RecentFailures, 0)
+
+	recorder = request(server, "GET", "/status?failures=x", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestStart(t *testing.T) {
+	server := NewServer(&config.Config{Admin: config.AdminConfig{Addr: "0.0.0.0:0"}}, nil, nil, nil, nil)
+	assert.Error(t, server.Start())
+
+	server = NewServer(&config.Config{}, nil, nil, nil, nil)
+	assert.NoError(t, server.Start())
+	server.Stop()
+
+	dir, err := os.MkdirTemp("", "admin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "admin.sock")
+	server, _, _, _ = setupTestServer(t)
+	server.config.Admin.Socket = socket
+	assert.NoError(t, server.Start())
+	defer server.Stop()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	resp, err := client.Get("http://admin/jobs")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,127 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+var ErrNotConfigured = errors.New("admin API is not configured")
+
+// errNotFound is returned by get when the admin API answers 404
+var errNotFound = errors.New("not found")
+
+// Client talks to the admin API of a running instance
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient returns a client for the unix socket or loopback address of the
+// admin API from the config
+func NewClient(config *config.Config) (*Client, error) {
+	adminConfig := config.Admin
+
+	if adminConfig.Socket != "" {
+		socket := adminConfig.Socket
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+
+		return &Client{
+			httpClient: &http.Client{Transport: transport, Timeout: 30 * time.Second},
+			baseURL:    "http://synconik",
+		}, nil
+	}
+
+	if adminConfig.Addr == "" {
+		return nil, ErrNotConfigured
+	}
+
+	return &Client{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    "http://" + adminConfig.Addr,
+	}, nil
+}
+
+// Status returns the sync summary with up to failures recent failures
+func (c *Client) Status(ctx context.Context, failures int) (*usecase.StatusSummary, error) {
+	query := url.Values{"failures": {strconv.Itoa(failures)}}
+
+	summary := &usecase.StatusSummary{}
+	if err := c.get(ctx, "/status?"+query.Encode(), summary); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// File returns the stored record of a path, store.ErrFileNotFound is returned
+// when the path is not in the store
+func (c *Client) File(ctx context.Context, path string) (*entity.File, error) {
+	query := url.Values{"path": {path}}
+
+	file := &entity.File{}
+	err := c.get(ctx, "/files?"+query.Encode(), file)
+	if errors.Is(err, errNotFound) {
+		return nil, store.ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Entries returns the stored records under prefix in the given sync state,
+// all states are returned when state is empty
+func (c *Client) Entries(ctx context.Context, prefix, state string) ([]usecase.Entry, error) {
+	query := url.Values{"prefix": {prefix}, "state": {state}}
+
+	entries := []usecase.Entry{}
+	if err := c.get(ctx, "/entries?"+query.Encode(), &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("admin API returned %s: %s", resp.Status, body.Error)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientStatus(t *testing.T) {
+	server, _, _, badgerStore := setupTestServer(t)
+
+	err := badgerStore.SaveFile("rushes/a.mov", &entity.File{Name: "a.mov", Size: 10, State: entity.StateSynced})
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	_, err = NewClient(&config.Config{})
+	assert.Equal(t, ErrNotConfigured, err)
+
+	client, err := NewClient(&config.Config{Admin: config.AdminConfig{Addr: ts.Listener.Addr().String()}})
+	assert.NoError(t, err)
+
+	summary, err := client.Status(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Files[entity.StateSynced])
+	assert.Equal(t, int64(10), summary.SyncedBytes)
+
+	_, err = client.Status(context.Background(), -1)
+	assert.EqualError(t, err, "admin API returned 400 Bad Request: invalid failures: \"-1\"")
+}
+
+func TestClientFiles(t *testing.T) {
+	server, _, _, badgerStore := setupTestServer(t)
+	server.config.Iconik.URL = "https://app.iconik.io"
+
+	err := badgerStore.SaveFile("rushes/a.mov", &entity.File{Name: "a.mov", AssetID: "A1"})
+	assert.NoError(t, err)
+	err = badgerStore.SaveFile("rushes/b.mov", &entity.File{Name: "b.mov", State: entity.StateFailed})
+	assert.NoError(t, err)
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	client, err := NewClient(&config.Config{Admin: config.AdminConfig{Addr: ts.Listener.Addr().String()}})
+	assert.NoError(t, err)
+
+	file, err := client.File(context.Background(), "rushes/a.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "A1", file.AssetID)
+
+	_, err = client.File(context.Background(), "rushes/missing.mov")
+	assert.Equal(t, store.ErrFileNotFound, err)
+
+	entries, err := client.Entries(context.Background(), "rushes/", "")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "https://app.iconik.io/asset/A1", entries[0].URL)
+
+	entries, err = client.Entries(context.Background(), "rushes/", entity.StateFailed)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "rushes/b.mov", entries[0].Path)
+}
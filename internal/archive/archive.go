@@ -0,0 +1,149 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Extensions of the supported archives
+var Extensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// Member is a regular file of an archive
+type Member struct {
+	// Path is the slash separated path of the member in the archive
+	Path string
+	Info os.FileInfo
+	// Err is set for a member that is not extracted since its path leaves
+	// the archive, Path is then the name stored in the archive
+	Err error
+}
+
+// IsArchive reports whether the file name has the extension of a supported
+// archive
+func IsArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, extension := range Extensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk calls fn for each regular file of the archive in the archive order
+// with a reader of its content, which is only valid until fn returns. Tar
+// archives are read as a stream, so no member is read twice. Unsafe members
+// are passed with their Err set and a nil reader, and the walk goes on. Walk
+// stops at the first error returned by fn.
+func Walk(archivePath string, fn func(member Member, r io.Reader) error) error {
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		return walkZip(archivePath, fn)
+	}
+	return walkTar(archivePath, fn)
+}
+
+func walkZip(archivePath string, fn func(member Member, r io.Reader) error) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		memberPath, err := cleanPath(file.Name)
+		if err != nil {
+			if err := fn(Member{Path: file.Name, Info: file.FileInfo(), Err: err}, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", memberPath, err)
+		}
+
+		err = fn(Member{Path: memberPath, Info: file.FileInfo()}, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkTar(archivePath string, fn func(member Member, r io.Reader) error) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+
+	name := strings.ToLower(archivePath)
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		memberPath, err := cleanPath(header.Name)
+		if err != nil {
+			if err := fn(Member{Path: header.Name, Info: header.FileInfo(), Err: err}, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = fn(Member{Path: memberPath, Info: header.FileInfo()}, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// cleanPath returns the member path without leading slashes or dots, paths
+// leaving the archive are rejected
+func cleanPath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("unsafe member path %q", name)
+		}
+	}
+
+	cleaned := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if cleaned == "" {
+		return "", fmt.Errorf("unsafe member path %q", name)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,133 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var contents = map[string]string{
+	"readme.txt":     "hello",
+	"shots/a001.mov": "video",
+}
+
+func writeZip(t *testing.T, path string, names ...string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	_, err = w.Create("shots/")
+	assert.NoError(t, err)
+	for _, name := range names {
+		fw, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = fw.Write([]byte(contents[name]))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+}
+
+func writeTarGz(t *testing.T, path string, names ...string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	w := tar.NewWriter(gz)
+	assert.NoError(t, w.WriteHeader(&tar.Header{Name: "shots/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for _, name := range names {
+		content := contents[name]
+		assert.NoError(t, w.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.NoError(t, gz.Close())
+}
+
+func readAll(t *testing.T, path string) (map[string]string, error) {
+	read := map[string]string{}
+	err := Walk(path, func(member Member, r io.Reader) error {
+		if member.Err != nil {
+			read[member.Path] = member.Err.Error()
+			return nil
+		}
+
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Base(member.Path), member.Info.Name())
+		assert.Equal(t, int64(len(data)), member.Info.Size())
+		read[member.Path] = string(data)
+		return nil
+	})
+	return read, err
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+
+	writeZip(t, filepath.Join(dir, "delivery.zip"), "readme.txt", "shots/a001.mov")
+	writeTarGz(t, filepath.Join(dir, "delivery.tar.gz"), "readme.txt", "shots/a001.mov")
+
+	for _, name := range []string{"delivery.zip", "delivery.tar.gz"} {
+		read, err := readAll(t, filepath.Join(dir, name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, contents, read, name)
+	}
+}
+
+func TestWalkUnsafePath(t *testing.T) {
+	dir := t.TempDir()
+	contents["../escape.txt"] = "escape"
+	defer delete(contents, "../escape.txt")
+
+	writeZip(t, filepath.Join(dir, "delivery.zip"), "readme.txt", "../escape.txt", "shots/a001.mov")
+	writeTarGz(t, filepath.Join(dir, "delivery.tar.gz"), "readme.txt", "../escape.txt", "shots/a001.mov")
+
+	// the unsafe member is reported and the members after it are still read
+	for _, name := range []string{"delivery.zip", "delivery.tar.gz"} {
+		read, err := readAll(t, filepath.Join(dir, name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, map[string]string{
+			"readme.txt":     "hello",
+			"../escape.txt":  `unsafe member path "../escape.txt"`,
+			"shots/a001.mov": "video",
+		}, read, name)
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for _, name := range []string{"a.zip", "a.TAR", "a.tar.gz", "a.tgz"} {
+		assert.True(t, IsArchive(name), name)
+	}
+	for _, name := range []string{"a.mov", "a.gz", "zip"} {
+		assert.False(t, IsArchive(name), name)
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	for name, expected := range map[string]string{
+		"a.mov":         "a.mov",
+		"/abs/a.mov":    "abs/a.mov",
+		"./shots/a.mov": "shots/a.mov",
+		"shots\\a.mov":  "shots/a.mov",
+		"shots//a.mov":  "shots/a.mov",
+		"a..b/c...mov":  "a..b/c...mov",
+	} {
+		cleaned, err := cleanPath(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, cleaned, name)
+	}
+
+	for _, name := range []string{"../a.mov", "shots/../../a.mov", "..\\a.mov", "/"} {
+		_, err := cleanPath(name)
+		assert.Error(t, err, name)
+	}
+}
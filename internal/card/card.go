@@ -0,0 +1,339 @@
+package card
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Layouts of camera cards
+const (
+	LayoutXDCAM = "XDCAM"
+	LayoutP2    = "P2"
+	LayoutAVCHD = "AVCHD"
+)
+
+// Layouts are the supported layouts in the order they are detected
+var Layouts = []string{LayoutP2, LayoutXDCAM, LayoutAVCHD}
+
+// Types of the files of a clip, they match the Iconik component types
+const (
+	TypeVideo    = "VIDEO"
+	TypeAudio    = "AUDIO"
+	TypeImage    = "IMAGE"
+	TypeMetadata = "METADATA"
+)
+
+// File is a file of a clip
+type File struct {
+	// Path is relative to the card root
+	Path string
+	Info os.FileInfo
+	Type string
+}
+
+// Clip is a recording spanning several files of a card
+type Clip struct {
+	// Root is the store key of the card root with a trailing slash, empty for
+	// the scanned directory
+	Root   string
+	Layout string
+	Name   string
+	// Files of the clip, the first one is the main video file
+	Files []File
+}
+
+// Key returns the store key of the clip, the key of its main video file
+func (c *Clip) Key() string {
+	return c.Root + c.Files[0].Path
+}
+
+// Keys returns the store keys of the other files of the clip
+func (c *Clip) Keys() []string {
+	keys := make([]string, 0, len(c.Files)-1)
+	for _, file := range c.Files[1:] {
+		keys = append(keys, c.Root+file.Path)
+	}
+	return keys
+}
+
+// Info returns the file info of the main video file with the size of all
+// files of the clip
+func (c *Clip) Info() os.FileInfo {
+	size := int64(0)
+	for _, file := range c.Files {
+		size += file.Info.Size()
+	}
+	return &clipInfo{FileInfo: c.Files[0].Info, size: size}
+}
+
+// Card is a directory with the layout of a camera card
+type Card struct {
+	Root   string
+	Layout string
+	Clips  []*Clip
+}
+
+// Detect returns the card with its clips when the local directory dir has one
+// of the layouts, root is its store key with a trailing slash. It returns nil
+// for other directories.
+func Detect(root, dir string, layouts []string) *Card {
+	if len(layouts) == 0 {
+		layouts = Layouts
+	}
+
+	for _, layout := range layouts {
+		var clips []*Clip
+		var ok bool
+
+		switch strings.ToUpper(layout) {
+		case LayoutP2:
+			clips, ok = detectP2(dir)
+		case LayoutXDCAM:
+			clips, ok = detectXDCAM(dir)
+		case LayoutAVCHD:
+			clips, ok = detectAVCHD(dir)
+		}
+		if !ok {
+			continue
+		}
+
+		card := &Card{Root: root, Layout: strings.ToUpper(layout), Clips: clips}
+		for _, clip := range clips {
+			clip.Root = root
+			clip.Layout = card.Layout
+		}
+		return card
+	}
+
+	return nil
+}
+
+// rootNames are the entries in the root of a card of each layout
+var rootNames = map[string][]string{
+	LayoutP2:    {"CONTENTS"},
+	LayoutXDCAM: {"XDROOT", "MEDIAPRO.XML"},
+	LayoutAVCHD: {"PRIVATE", "AVCHD", "BDMV"},
+}
+
+// DetectEntries detects a card in dir like Detect given the listing of dir,
+// its subdirectories are only read when the listing holds an entry of the
+// root of one of the layouts
+func DetectEntries(root, dir string, entries []os.DirEntry, layouts []string) *Card {
+	if len(layouts) == 0 {
+		layouts = Layouts
+	}
+
+	for _, layout := range layouts {
+		for _, name := range rootNames[strings.ToUpper(layout)] {
+			for _, entry := range entries {
+				if strings.EqualFold(entry.Name(), name) {
+					return Detect(root, dir, layouts)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// detectP2 reads a P2 card: CONTENTS/CLIP/0001AB.XML describes the clip with
+// its video in CONTENTS/VIDEO/0001AB.MXF and audio tracks in
+// CONTENTS/AUDIO/0001AB00.MXF...
+func detectP2(dir string) ([]*Clip, bool) {
+	contents := child(dir, "CONTENTS")
+	clipDir := child(contents, "CLIP")
+	videoDir := child(contents, "VIDEO")
+	if clipDir == "" || videoDir == "" {
+		return nil, false
+	}
+
+	folders := []folder{
+		{path: videoDir, typ: TypeVideo},
+		{path: child(contents, "AUDIO"), typ: TypeAudio},
+		{path: clipDir, typ: TypeMetadata},
+		{path: child(contents, "ICON"), typ: TypeImage},
+		{path: child(contents, "PROXY"), typ: TypeVideo},
+		{path: child(contents, "VOICE"), typ: TypeAudio},
+	}
+
+	return readClips(dir, videoDir, ".MXF", folders), true
+}
+
+// detectXDCAM reads an XDCAM card with its clips in XDROOT/Clip, or an XDCAM
+// disc with MEDIAPRO.XML and the clips in Clip. C0001.MXF is the clip with
+// its metadata in Clip/C0001M01.XML, its proxy in Sub/C0001S01.MXF and its
+// thumbnail in Thmbnl/C0001T01.JPG.
+func detectXDCAM(dir string) ([]*Clip, bool) {
+	base := child(dir, "XDROOT")
+	if base == "" {
+		if file(dir, "MEDIAPRO.XML") == "" {
+			return nil, false
+		}
+		base = dir
+	}
+
+	clipDir := child(base, "Clip")
+	if clipDir == "" {
+		return nil, false
+	}
+
+	folders := []folder{
+		{path: clipDir, typ: TypeVideo, metadata: true},
+		{path: child(base, "Sub"), typ: TypeVideo},
+		{path: child(base, "Thmbnl"), typ: TypeImage},
+	}
+
+	return readClips(dir, clipDir, ".MXF", folders), true
+}
+
+// detectAVCHD reads an AVCHD card with the streams in BDMV/STREAM/00000.MTS
+// and their clip information in BDMV/CLIPINF/00000.CPI, the BDMV directory
+// may be nested in PRIVATE/AVCHD
+func detectAVCHD(dir string) ([]*Clip, bool) {
+	bdmv := child(child(child(dir, "PRIVATE"), "AVCHD"), "BDMV")
+	if bdmv == "" {
+		bdmv = child(child(dir, "AVCHD"), "BDMV")
+	}
+	if bdmv == "" {
+		bdmv = child(dir, "BDMV")
+	}
+
+	streamDir := child(bdmv, "STREAM")
+	if streamDir == "" {
+		return nil, false
+	}
+
+	folders := []folder{
+		{path: streamDir, typ: TypeVideo},
+		{path: child(bdmv, "CLIPINF"), typ: TypeMetadata},
+	}
+
+	return readClips(dir, streamDir, ".MTS", folders), true
+}
+
+// folder holds files of the clips of a given type
+type folder struct {
+	path string
+	typ  string
+	// metadata files next to the video files are of the METADATA type
+	metadata bool
+}
+
+// readClips returns a clip for each file with the main extension in the main
+// folder, with the files of the folders whose name starts with the clip name
+func readClips(root, mainDir, extension string, folders []folder) []*Clip {
+	clips := []*Clip{}
+
+	for _, info := range readDir(mainDir) {
+		if !strings.EqualFold(filepath.Ext(info.Name()), extension) {
+			continue
+		}
+
+		name := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+		clip := &Clip{
+			Name:  name,
+			Files: []File{{Path: relative(root, filepath.Join(mainDir, info.Name())), Info: info, Type: TypeVideo}},
+		}
+
+		for _, folder := range folders {
+			for _, related := range readDir(folder.path) {
+				if !strings.HasPrefix(strings.ToUpper(related.Name()), strings.ToUpper(name)) {
+					continue
+				}
+				if folder.path == mainDir && related.Name() == info.Name() {
+					continue
+				}
+
+				typ := folder.typ
+				if folder.metadata && !strings.EqualFold(filepath.Ext(related.Name()), extension) {
+					typ = TypeMetadata
+				}
+
+				clip.Files = append(clip.Files, File{
+					Path: relative(root, filepath.Join(folder.path, related.Name())),
+					Info: related,
+					Type: typ,
+				})
+			}
+		}
+
+		clips = append(clips, clip)
+	}
+
+	return clips
+}
+
+// child returns the path of the subdirectory with the given name in any case,
+// or an empty string when there is none
+func child(dir, name string) string {
+	if dir == "" {
+		return ""
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.EqualFold(entry.Name(), name) {
+			return filepath.Join(dir, entry.Name())
+		}
+	}
+	return ""
+}
+
+// file returns the path of the file with the given name in any case, or an
+// empty string when there is none
+func file(dir, name string) string {
+	for _, info := range readDir(dir) {
+		if strings.EqualFold(info.Name(), name) {
+			return filepath.Join(dir, info.Name())
+		}
+	}
+	return ""
+}
+
+// readDir returns the regular files of a directory sorted by name
+func readDir(dir string) []os.FileInfo {
+	if dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	return files
+}
+
+func relative(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+type clipInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i *clipInfo) Size() int64 { return i.size }
@@ -0,0 +1,136 @@
+package card
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, paths ...string) {
+	for _, path := range paths {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte("data"), 0644))
+	}
+}
+
+func paths(clip *Clip) []string {
+	paths := []string{}
+	for _, file := range clip.Files {
+		paths = append(paths, file.Path+" "+file.Type)
+	}
+	return paths
+}
+
+func TestDetectP2(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir,
+		"LASTCLIP.TXT",
+		"CONTENTS/CLIP/0001AB.XML",
+		"CONTENTS/VIDEO/0001AB.MXF",
+		"CONTENTS/AUDIO/0001AB00.MXF",
+		"CONTENTS/AUDIO/0001AB01.MXF",
+		"CONTENTS/ICON/0001AB.BMP",
+		"CONTENTS/PROXY/0001AB.MP4",
+		"CONTENTS/CLIP/0002CD.XML",
+		"CONTENTS/VIDEO/0002CD.MXF",
+	)
+
+	card := Detect("A001/", dir, nil)
+	assert.NotNil(t, card)
+	assert.Equal(t, LayoutP2, card.Layout)
+	assert.Len(t, card.Clips, 2)
+
+	clip := card.Clips[0]
+	assert.Equal(t, "0001AB", clip.Name)
+	assert.Equal(t, "A001/CONTENTS/VIDEO/0001AB.MXF", clip.Key())
+	assert.Equal(t, []string{
+		"CONTENTS/VIDEO/0001AB.MXF VIDEO",
+		"CONTENTS/AUDIO/0001AB00.MXF AUDIO",
+		"CONTENTS/AUDIO/0001AB01.MXF AUDIO",
+		"CONTENTS/CLIP/0001AB.XML METADATA",
+		"CONTENTS/ICON/0001AB.BMP IMAGE",
+		"CONTENTS/PROXY/0001AB.MP4 VIDEO",
+	}, paths(clip))
+	assert.Equal(t, "A001/CONTENTS/AUDIO/0001AB00.MXF", clip.Keys()[0])
+
+	info := clip.Info()
+	assert.Equal(t, "0001AB.MXF", info.Name())
+	assert.Equal(t, int64(6*len("data")), info.Size())
+
+	assert.Len(t, card.Clips[1].Files, 2)
+}
+
+func TestDetectXDCAM(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir,
+		"XDROOT/MEDIAPRO.XML",
+		"XDROOT/Clip/C0001.MXF",
+		"XDROOT/Clip/C0001M01.XML",
+		"XDROOT/Sub/C0001S01.MXF",
+		"XDROOT/Thmbnl/C0001T01.JPG",
+		"XDROOT/Clip/C0002.MXF",
+	)
+
+	card := Detect("", dir, nil)
+	assert.NotNil(t, card)
+	assert.Equal(t, LayoutXDCAM, card.Layout)
+	assert.Len(t, card.Clips, 2)
+
+	assert.Equal(t, "XDROOT/Clip/C0001.MXF", card.Clips[0].Key())
+	assert.Equal(t, []string{
+		"XDROOT/Clip/C0001.MXF VIDEO",
+		"XDROOT/Clip/C0001M01.XML METADATA",
+		"XDROOT/Sub/C0001S01.MXF VIDEO",
+		"XDROOT/Thmbnl/C0001T01.JPG IMAGE",
+	}, paths(card.Clips[0]))
+	assert.Empty(t, card.Clips[1].Keys())
+}
+
+func TestDetectAVCHD(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(
+		t, dir,
+		"PRIVATE/AVCHD/BDMV/INDEX.BDM",
+		"PRIVATE/AVCHD/BDMV/STREAM/00000.MTS",
+		"PRIVATE/AVCHD/BDMV/CLIPINF/00000.CPI",
+		"PRIVATE/AVCHD/BDMV/PLAYLIST/00000.MPL",
+	)
+
+	card := Detect("cards/sony/", dir, nil)
+	assert.NotNil(t, card)
+	assert.Equal(t, LayoutAVCHD, card.Layout)
+	assert.Len(t, card.Clips, 1)
+	assert.Equal(t, "cards/sony/PRIVATE/AVCHD/BDMV/STREAM/00000.MTS", card.Clips[0].Key())
+	assert.Equal(t, []string{"cards/sony/PRIVATE/AVCHD/BDMV/CLIPINF/00000.CPI"}, card.Clips[0].Keys())
+
+	// only the configured layouts are detected
+	assert.Nil(t, Detect("cards/sony/", dir, []string{LayoutP2, LayoutXDCAM}))
+}
+
+func TestDetectNoCard(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "clip.mov", "Clip/C0001.MXF")
+
+	assert.Nil(t, Detect("", dir, nil))
+	assert.Nil(t, Detect("", filepath.Join(dir, "missing"), nil))
+}
+
+func TestDetectEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "XDROOT/Clip/C0001.MXF")
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+
+	card := DetectEntries("", dir, entries, nil)
+	assert.NotNil(t, card)
+	assert.Equal(t, LayoutXDCAM, card.Layout)
+
+	// the layouts without an entry in the listing are not read
+	assert.Nil(t, DetectEntries("", dir, entries, []string{LayoutP2, LayoutAVCHD}))
+	assert.Nil(t, DetectEntries("", dir, nil, nil))
+}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/kgantsov/synconik/internal/metadata"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -16,6 +18,71 @@ var cfgFile string
 type ScannerConfig struct {
 	Dir      string `mapstructure:"dir"`
 	Interval int32  `mapstructure:"interval"`
+	// Symlinks is the policy for symbolic links, one of files, follow or skip
+	Symlinks string `mapstructure:"symlinks"`
+	// OneFilesystem skips directories on other filesystems than Dir, like
+	// network shares mounted in it
+	OneFilesystem bool `mapstructure:"one_filesystem"`
+	// Walkers is the number of directories read at once by a scan
+	Walkers    int              `mapstructure:"walkers"`
+	DropFolder DropFolderConfig `mapstructure:"drop_folder"`
+	Sequences  SequencesConfig  `mapstructure:"sequences"`
+	// Groups upload files with the same name as one asset with several formats
+	Groups   []GroupRuleConfig `mapstructure:"groups"`
+	Cards    CardsConfig       `mapstructure:"cards"`
+	Archives ArchivesConfig    `mapstructure:"archives"`
+}
+
+type ArchivesConfig struct {
+	// Enabled uploads the members of zip and tar archives found by the scanner
+	// into a collection named after the archive
+	Enabled bool `mapstructure:"enabled"`
+	// UploadOriginal uploads the archive itself into its collection too
+	UploadOriginal bool `mapstructure:"upload_original"`
+	// TempDir is where members are extracted one at a time for their upload,
+	// the system temporary directory by default
+	TempDir string `mapstructure:"temp_dir"`
+}
+
+type CardsConfig struct {
+	// Enabled uploads the clips of camera cards as one asset per clip
+	Enabled bool `mapstructure:"enabled"`
+	// Layouts are the detected card layouts, XDCAM, P2 and AVCHD by default
+	Layouts []string `mapstructure:"layouts"`
+}
+
+type GroupRuleConfig struct {
+	// Primary are the extensions of the file uploaded as the ORIGINAL format,
+	// the first one found wins
+	Primary []string `mapstructure:"primary"`
+	// Secondary are the files uploaded as the other formats of the asset
+	Secondary []GroupFormatConfig `mapstructure:"secondary"`
+}
+
+// GroupFormatConfig names the format of the secondary files with an extension
+type GroupFormatConfig struct {
+	Extension string `mapstructure:"extension"`
+	// Format is the name of the Iconik format, the extension in upper case
+	// by default
+	Format string `mapstructure:"format"`
+}
+
+type SequencesConfig struct {
+	// Enabled uploads numbered frames in a directory as one asset per sequence
+	Enabled bool `mapstructure:"enabled"`
+	// Extensions of the frames, .dpx and .exr by default
+	Extensions []string `mapstructure:"extensions"`
+	// MinFrames is the number of frames making a sequence, 2 by default
+	MinFrames int `mapstructure:"min_frames"`
+	// Parallel is the number of frames of a sequence uploaded at once
+	Parallel int `mapstructure:"parallel"`
+}
+
+type DropFolderConfig struct {
+	// Markers are the names of the files signalling that a delivery folder is
+	// complete, e.g. ".done" or "READY". Setting them turns on the drop folder
+	// mode.
+	Markers []string `mapstructure:"markers"`
 }
 
 type LoggingConfig struct {
@@ -24,6 +91,8 @@ type LoggingConfig struct {
 
 type UploaderConfig struct {
 	Workers int `mapstructure:"workers"`
+	// ProgressInterval in seconds between progress logs of uploads, 0 disables them
+	ProgressInterval int32 `mapstructure:"progress_interval"`
 }
 
 type Iconik struct {
@@ -37,12 +106,188 @@ type Store struct {
 	DataDir string `mapstructure:"data_dir"`
 }
 
+// FieldMappingConfig maps an extracted key to an Iconik metadata field. It is
+// a list entry rather than a map since viper splits map keys on dots.
+type FieldMappingConfig struct {
+	Key   string `mapstructure:"key"`
+	Field string `mapstructure:"field"`
+}
+
+type SidecarConfig struct {
+	// Patterns are sidecar file name templates, e.g. "{name}.json" or "{stem}.xml"
+	Patterns []string             `mapstructure:"patterns"`
+	ViewID   string               `mapstructure:"view_id"`
+	Mapping  []FieldMappingConfig `mapstructure:"mapping"`
+}
+
+type PathFieldConfig struct {
+	Name  string `mapstructure:"name"`
+	Group string `mapstructure:"group"`
+	// Value is a static value that may reference named groups as $name
+	Value string `mapstructure:"value"`
+	// Transform is one of upper, lower, int or date
+	Transform string `mapstructure:"transform"`
+	// Layout is the Go time layout used by the date transform
+	Layout string `mapstructure:"layout"`
+}
+
+type PathRuleConfig struct {
+	Pattern string            `mapstructure:"pattern"`
+	Fields  []PathFieldConfig `mapstructure:"fields"`
+}
+
+// Compile returns the path rule, it fails when the pattern is not a valid
+// regular expression or a field refers to a group the pattern does not have
+func (c PathRuleConfig) Compile() (*metadata.PathRule, error) {
+	fields := []metadata.PathField{}
+	for _, field := range c.Fields {
+		fields = append(fields, metadata.PathField{
+			Name:      field.Name,
+			Group:     field.Group,
+			Value:     field.Value,
+			Transform: field.Transform,
+			Layout:    field.Layout,
+		})
+	}
+
+	return metadata.NewPathRule(c.Pattern, fields)
+}
+
+type PathMetadataConfig struct {
+	ViewID string           `mapstructure:"view_id"`
+	Rules  []PathRuleConfig `mapstructure:"rules"`
+}
+
+type EmbeddedMetadataConfig struct {
+	// Enabled turns on EXIF, IPTC and XMP extraction for JPEG and TIFF files
+	Enabled bool                 `mapstructure:"enabled"`
+	ViewID  string               `mapstructure:"view_id"`
+	Mapping []FieldMappingConfig `mapstructure:"mapping"`
+
+	TitleFromCaptureTime bool `mapstructure:"title_from_capture_time"`
+	// TitleLayout is the Go time layout of the title, 2006-01-02 15:04:05 by default
+	TitleLayout         string `mapstructure:"title_layout"`
+	DateFromCaptureTime bool   `mapstructure:"date_from_capture_time"`
+}
+
+// ManifestMetadataConfig is the view of the metadata columns of a push
+// manifest
+type ManifestMetadataConfig struct {
+	ViewID string `mapstructure:"view_id"`
+}
+
+type MetadataConfig struct {
+	Sidecar  SidecarConfig          `mapstructure:"sidecar"`
+	Path     PathMetadataConfig     `mapstructure:"path"`
+	Embedded EmbeddedMetadataConfig `mapstructure:"embedded"`
+	Manifest ManifestMetadataConfig `mapstructure:"manifest"`
+}
+
+type ProxyConfig struct {
+	// Enabled generates proxies and keyframes of still images locally instead
+	// of triggering transcoding in Iconik
+	Enabled         bool   `mapstructure:"enabled"`
+	StorageID       string `mapstructure:"storage_id"`
+	MaxSize         int    `mapstructure:"max_size"`
+	KeyframeMaxSize int    `mapstructure:"keyframe_max_size"`
+	Quality         int    `mapstructure:"quality"`
+	// MaxPixels is the size of the largest image decoded for a proxy, larger
+	// images are transcoded in Iconik
+	MaxPixels int `mapstructure:"max_pixels"`
+}
+
+type TranscodeRuleConfig struct {
+	// Paths are glob patterns matched against the relative path and each of
+	// its parent directories
+	Paths      []string `mapstructure:"paths"`
+	Extensions []string `mapstructure:"extensions"`
+	// Action is one of transcode, skip or defer
+	Action   string `mapstructure:"action"`
+	Priority int    `mapstructure:"priority"`
+}
+
+type TranscodeWindowConfig struct {
+	// Start and End are local times formatted as 15:04, the window may wrap
+	// around midnight
+	Start string `mapstructure:"start"`
+	End   string `mapstructure:"end"`
+}
+
+type TranscodingConfig struct {
+	Action   string `mapstructure:"action"`
+	Priority int    `mapstructure:"priority"`
+	// UseStorageIgnorePattern defaults to true when unset
+	UseStorageIgnorePattern *bool                 `mapstructure:"use_storage_ignore_pattern"`
+	Window                  TranscodeWindowConfig `mapstructure:"window"`
+	// Interval in seconds between runs of deferred transcoding and job polling
+	Interval int32 `mapstructure:"interval"`
+	// MaxAttempts limits how many times a failed transcode job is triggered
+	MaxAttempts int                   `mapstructure:"max_attempts"`
+	Rules       []TranscodeRuleConfig `mapstructure:"rules"`
+}
+
+type HTTPConfig struct {
+	// Addr is the listen address of the metrics endpoint, empty disables it
+	Addr string `mapstructure:"addr"`
+}
+
+type AdminConfig struct {
+	// Addr is a loopback listen address of the admin API, e.g. 127.0.0.1:9091
+	Addr string `mapstructure:"addr"`
+	// Socket is the path of a unix socket for the admin API, it takes
+	// precedence over Addr
+	Socket string `mapstructure:"socket"`
+}
+
+// Policies for symbolic links found by the scanner
+const (
+	// SymlinksFiles uploads the targets of links to files and skips links to
+	// directories
+	SymlinksFiles = "files"
+	// SymlinksFollow also walks linked directories, links leading back to a
+	// directory being walked are skipped
+	SymlinksFollow = "follow"
+	SymlinksSkip   = "skip"
+)
+
+// Post-upload actions
+const (
+	PostUploadKeep   = "keep"
+	PostUploadMove   = "move"
+	PostUploadDelete = "delete"
+	PostUploadStub   = "stub"
+)
+
+// Verification of an upload before the post-upload action
+const (
+	VerifySize     = "size"
+	VerifyChecksum = "checksum"
+)
+
+type PostUploadConfig struct {
+	// Action is one of keep, move, delete or stub
+	Action string `mapstructure:"action"`
+	// ArchiveDir receives moved files under their path relative to scanner.dir
+	ArchiveDir string `mapstructure:"archive_dir"`
+	// Retention delays the delete action, e.g. 168h
+	Retention time.Duration `mapstructure:"retention"`
+	// Verify is size or checksum
+	Verify string `mapstructure:"verify"`
+}
+
 type Config struct {
 	Scanner  ScannerConfig
 	Logging  LoggingConfig
 	Uploader UploaderConfig
 	Iconik   Iconik
 	Store    Store
+	Metadata MetadataConfig
+	Proxy    ProxyConfig
+	HTTP     HTTPConfig
+	Admin    AdminConfig
+
+	Transcoding TranscodingConfig
+	PostUpload  PostUploadConfig `mapstructure:"post_upload"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -54,13 +299,39 @@ func LoadConfig() (*Config, error) {
 	}
 
 	// make sure that config.Scanner.Dir has a trailing slash
-	if config.Scanner.Dir[len(config.Scanner.Dir)-1] != '/' {
+	if config.Scanner.Dir != "" && config.Scanner.Dir[len(config.Scanner.Dir)-1] != '/' {
 		config.Scanner.Dir = config.Scanner.Dir + "/"
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// Validate checks the settings that are compiled when the config is used, so
+// that a mistake fails the startup instead of being skipped at runtime
+func (c *Config) Validate() error {
+	for i, rule := range c.Metadata.Path.Rules {
+		if _, err := rule.Compile(); err != nil {
+			return fmt.Errorf("metadata.path.rules[%d]: %w", i, err)
+		}
+	}
+
+	switch c.PostUpload.Action {
+	case "", PostUploadKeep, PostUploadDelete, PostUploadStub:
+	case PostUploadMove:
+		if c.PostUpload.ArchiveDir == "" {
+			return errors.New("post_upload.archive_dir is required by the move action")
+		}
+	default:
+		return fmt.Errorf("post_upload.action must be keep, move, delete or stub, got %q", c.PostUpload.Action)
+	}
+
+	return nil
+}
+
 func InitCobraCommand(runFunc func(cmd *cobra.Command, args []string)) *cobra.Command {
 	if cfgFile != "" {
 		// Use config file from the flag
@@ -104,34 +375,90 @@ func InitCobraCommand(runFunc func(cmd *cobra.Command, args []string)) *cobra.Co
 
 	// Command-line flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is config.yaml)")
-	rootCmd.Flags().String("logging.level", "info", "Log level")
+	rootCmd.PersistentFlags().String("logging.level", "info", "Log level")
 
-	rootCmd.Flags().String("scanner.dir", "", "Directory to scan for files")
-	rootCmd.Flags().Int32("scanner.interval", 10, "Interval in seconds to scan the directory")
+	rootCmd.PersistentFlags().String("scanner.dir", "", "Directory to scan for files")
+	rootCmd.PersistentFlags().Int32("scanner.interval", 10, "Interval in seconds to scan the directory")
+	rootCmd.PersistentFlags().String("scanner.symlinks", "files", "Policy for symbolic links: files, follow or skip")
+	rootCmd.PersistentFlags().Bool("scanner.one_filesystem", false, "Skip directories on other filesystems than the scanned one")
+	rootCmd.PersistentFlags().Int("scanner.walkers", 4, "Number of directories read at once by a scan")
 
-	rootCmd.Flags().Int("uploader.workers", 5, "Number of workers to upload files")
+	rootCmd.PersistentFlags().Int("uploader.workers", 5, "Number of workers to upload files")
+	rootCmd.PersistentFlags().Int32("uploader.progress_interval", 30, "Interval in seconds to log the progress of uploads, 0 to disable")
 
-	rootCmd.Flags().String("iconik.url", "https://app.iconik.io", "Iconik URL")
-	rootCmd.Flags().String("iconik.app_id", "", "Iconik app ID")
-	rootCmd.Flags().String("iconik.token", "", "Iconik token")
-	rootCmd.Flags().String("iconik.storage_id", "", "Iconik storage ID")
+	rootCmd.PersistentFlags().String("iconik.url", "https://app.iconik.io", "Iconik URL")
+	rootCmd.PersistentFlags().String("iconik.app_id", "", "Iconik app ID")
+	rootCmd.PersistentFlags().String("iconik.token", "", "Iconik token")
+	rootCmd.PersistentFlags().String("iconik.storage_id", "", "Iconik storage ID")
 
-	rootCmd.Flags().String("store.data_dir", "db", "Data directory")
+	rootCmd.PersistentFlags().String("store.data_dir", "db", "Data directory")
 
-	// Bind CLI flags to Viper settings
-	viper.BindPFlag("logging.level", rootCmd.Flags().Lookup("logging.level"))
+	rootCmd.PersistentFlags().String("http.addr", "127.0.0.1:9090", "Listen address of the metrics and health endpoints, empty to disable")
 
-	viper.BindPFlag("scanner.dir", rootCmd.Flags().Lookup("scanner.dir"))
-	viper.BindPFlag("scanner.interval", rootCmd.Flags().Lookup("scanner.interval"))
+	rootCmd.PersistentFlags().String("admin.addr", "", "Loopback listen address of the admin API, empty to disable")
+	rootCmd.PersistentFlags().String("admin.socket", "", "Unix socket of the admin API, empty to disable")
 
-	viper.BindPFlag("uploader.workers", rootCmd.Flags().Lookup("uploader.workers"))
+	rootCmd.PersistentFlags().String("transcoding.action", "transcode", "Default transcoding action: transcode, skip or defer")
+	rootCmd.PersistentFlags().Int("transcoding.priority", 5, "Default transcoding priority")
+	rootCmd.PersistentFlags().String("transcoding.window.start", "", "Start of the deferred transcoding window (15:04)")
+	rootCmd.PersistentFlags().String("transcoding.window.end", "", "End of the deferred transcoding window (15:04)")
+	rootCmd.PersistentFlags().Int32("transcoding.interval", 60, "Interval in seconds to trigger deferred transcoding and poll jobs")
+	rootCmd.PersistentFlags().Int("transcoding.max_attempts", 3, "Maximum number of attempts to transcode a file")
 
-	viper.BindPFlag("iconik.url", rootCmd.Flags().Lookup("iconik.url"))
-	viper.BindPFlag("iconik.app_id", rootCmd.Flags().Lookup("iconik.app_id"))
-	viper.BindPFlag("iconik.token", rootCmd.Flags().Lookup("iconik.token"))
-	viper.BindPFlag("iconik.storage_id", rootCmd.Flags().Lookup("iconik.storage_id"))
+	rootCmd.PersistentFlags().String("post_upload.action", "keep", "Action on local files once uploaded: keep, move, delete or stub")
+	rootCmd.PersistentFlags().String("post_upload.archive_dir", "", "Directory receiving the files of the move action")
+	rootCmd.PersistentFlags().Duration("post_upload.retention", 0, "How long uploaded files are kept before the delete action")
+	rootCmd.PersistentFlags().String("post_upload.verify", "size", "Verification before the post-upload action: size or checksum")
 
-	viper.BindPFlag("store.data_dir", rootCmd.Flags().Lookup("store.data_dir"))
+	rootCmd.PersistentFlags().Bool("proxy.enabled", false, "Generate image proxies locally instead of transcoding in Iconik")
+	rootCmd.PersistentFlags().String("proxy.storage_id", "", "Iconik storage ID for proxies and keyframes")
+	rootCmd.PersistentFlags().Int("proxy.max_size", 1024, "Longest side of generated proxies in pixels")
+	rootCmd.PersistentFlags().Int("proxy.keyframe_max_size", 400, "Longest side of generated keyframes in pixels")
+	rootCmd.PersistentFlags().Int("proxy.quality", 85, "JPEG quality of generated proxies and keyframes")
+	rootCmd.PersistentFlags().Int("proxy.max_pixels", 100_000_000, "Pixels of the largest image decoded for a proxy")
+
+	// Bind CLI flags to Viper settings
+	viper.BindPFlag("logging.level", rootCmd.PersistentFlags().Lookup("logging.level"))
+
+	viper.BindPFlag("scanner.dir", rootCmd.PersistentFlags().Lookup("scanner.dir"))
+	viper.BindPFlag("scanner.interval", rootCmd.PersistentFlags().Lookup("scanner.interval"))
+	viper.BindPFlag("scanner.symlinks", rootCmd.PersistentFlags().Lookup("scanner.symlinks"))
+	viper.BindPFlag("scanner.one_filesystem", rootCmd.PersistentFlags().Lookup("scanner.one_filesystem"))
+	viper.BindPFlag("scanner.walkers", rootCmd.PersistentFlags().Lookup("scanner.walkers"))
+
+	viper.BindPFlag("uploader.workers", rootCmd.PersistentFlags().Lookup("uploader.workers"))
+	viper.BindPFlag("uploader.progress_interval", rootCmd.PersistentFlags().Lookup("uploader.progress_interval"))
+
+	viper.BindPFlag("iconik.url", rootCmd.PersistentFlags().Lookup("iconik.url"))
+	viper.BindPFlag("iconik.app_id", rootCmd.PersistentFlags().Lookup("iconik.app_id"))
+	viper.BindPFlag("iconik.token", rootCmd.PersistentFlags().Lookup("iconik.token"))
+	viper.BindPFlag("iconik.storage_id", rootCmd.PersistentFlags().Lookup("iconik.storage_id"))
+
+	viper.BindPFlag("store.data_dir", rootCmd.PersistentFlags().Lookup("store.data_dir"))
+
+	viper.BindPFlag("http.addr", rootCmd.PersistentFlags().Lookup("http.addr"))
+
+	viper.BindPFlag("admin.addr", rootCmd.PersistentFlags().Lookup("admin.addr"))
+	viper.BindPFlag("admin.socket", rootCmd.PersistentFlags().Lookup("admin.socket"))
+
+	viper.BindPFlag("transcoding.action", rootCmd.PersistentFlags().Lookup("transcoding.action"))
+	viper.BindPFlag("transcoding.priority", rootCmd.PersistentFlags().Lookup("transcoding.priority"))
+	viper.BindPFlag("transcoding.window.start", rootCmd.PersistentFlags().Lookup("transcoding.window.start"))
+	viper.BindPFlag("transcoding.window.end", rootCmd.PersistentFlags().Lookup("transcoding.window.end"))
+	viper.BindPFlag("transcoding.interval", rootCmd.PersistentFlags().Lookup("transcoding.interval"))
+	viper.BindPFlag("transcoding.max_attempts", rootCmd.PersistentFlags().Lookup("transcoding.max_attempts"))
+
+	viper.BindPFlag("post_upload.action", rootCmd.PersistentFlags().Lookup("post_upload.action"))
+	viper.BindPFlag("post_upload.archive_dir", rootCmd.PersistentFlags().Lookup("post_upload.archive_dir"))
+	viper.BindPFlag("post_upload.retention", rootCmd.PersistentFlags().Lookup("post_upload.retention"))
+	viper.BindPFlag("post_upload.verify", rootCmd.PersistentFlags().Lookup("post_upload.verify"))
+
+	viper.BindPFlag("proxy.enabled", rootCmd.PersistentFlags().Lookup("proxy.enabled"))
+	viper.BindPFlag("proxy.storage_id", rootCmd.PersistentFlags().Lookup("proxy.storage_id"))
+	viper.BindPFlag("proxy.max_size", rootCmd.PersistentFlags().Lookup("proxy.max_size"))
+	viper.BindPFlag("proxy.keyframe_max_size", rootCmd.PersistentFlags().Lookup("proxy.keyframe_max_size"))
+	viper.BindPFlag("proxy.quality", rootCmd.PersistentFlags().Lookup("proxy.quality"))
+	viper.BindPFlag("proxy.max_pixels", rootCmd.PersistentFlags().Lookup("proxy.max_pixels"))
 
 	return rootCmd
 }
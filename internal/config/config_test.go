@@ -52,3 +52,32 @@ func TestConfig(t *testing.T) {
 	assert.Equal(t, config.Iconik.StorageID, "5B4BAE0D-5E07-4B36-A2C2-0DF79F558F6F")
 	assert.Equal(t, config.Store.DataDir, "db_dir")
 }
+
+func TestValidate(t *testing.T) {
+	config := &Config{Metadata: MetadataConfig{Path: PathMetadataConfig{Rules: []PathRuleConfig{
+		{Pattern: `^(?P<show>[^/]+)/`, Fields: []PathFieldConfig{{Name: "show", Group: "show"}}},
+	}}}}
+	assert.NoError(t, config.Validate())
+
+	config.Metadata.Path.Rules = append(config.Metadata.Path.Rules, PathRuleConfig{
+		Pattern: `^(?P<show>[^/]+)/`, Fields: []PathFieldConfig{{Name: "episode", Group: "episode"}},
+	})
+	assert.EqualError(t, config.Validate(), `metadata.path.rules[1]: path rule "^(?P<show>[^/]+)/" has no group "episode"`)
+
+	config.Metadata.Path.Rules[1] = PathRuleConfig{Pattern: `^(?P<show>[^/]+`}
+	assert.Error(t, config.Validate())
+}
+
+func TestValidatePostUpload(t *testing.T) {
+	config := &Config{PostUpload: PostUploadConfig{Action: PostUploadStub}}
+	assert.NoError(t, config.Validate())
+
+	config.PostUpload.Action = "archive"
+	assert.EqualError(t, config.Validate(), `post_upload.action must be keep, move, delete or stub, got "archive"`)
+
+	config.PostUpload.Action = PostUploadMove
+	assert.EqualError(t, config.Validate(), "post_upload.archive_dir is required by the move action")
+
+	config.PostUpload.ArchiveDir = "/archive"
+	assert.NoError(t, config.Validate())
+}
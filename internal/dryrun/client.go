@@ -0,0 +1,158 @@
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/storage"
+)
+
+// Client is an Iconik client that changes nothing. Created objects get
+// generated IDs, uploads send no data and nothing can be found.
+type Client struct {
+	storage *icnk_client.Storage
+	nextID  atomic.Int64
+}
+
+// NewClient returns a client whose GetStorage answers with the given storage
+func NewClient(storage *icnk_client.Storage) *Client {
+	return &Client{storage: storage}
+}
+
+func (c *Client) id() string {
+	return fmt.Sprintf("dry-run-%d", c.nextID.Add(1))
+}
+
+func (c *Client) CreateAsset(ctx context.Context, asset *icnk_client.Asset) (*icnk_client.Asset, error) {
+	created := *asset
+	created.ID = c.id()
+	return &created, nil
+}
+
+func (c *Client) GetAsset(ctx context.Context, id string) (*icnk_client.Asset, error) {
+	return nil, fmt.Errorf("dry run: asset %s: %w", id, icnk_client.ErrNotFound)
+}
+
+func (c *Client) CreateAssetVersion(ctx context.Context, id string) (*icnk_client.AssetVersion, error) {
+	return &icnk_client.AssetVersion{ID: c.id()}, nil
+}
+
+func (c *Client) CreateCollection(
+	ctx context.Context, collection *icnk_client.Collection,
+) (*icnk_client.Collection, error) {
+	created := *collection
+	created.ID = c.id()
+	return &created, nil
+}
+
+func (c *Client) GetCollection(ctx context.Context, id string) (*icnk_client.Collection, error) {
+	return nil, fmt.Errorf("dry run: collection %s: %w", id, icnk_client.ErrNotFound)
+}
+
+func (c *Client) ListCollections(ctx context.Context, page, perPage int) (*icnk_client.CollectionList, error) {
+	return &icnk_client.CollectionList{Objects: []icnk_client.Collection{}, Page: page, PerPage: perPage}, nil
+}
+
+func (c *Client) ListCollectionContents(
+	ctx context.Context, id string, page, perPage int,
+) (*icnk_client.CollectionList, error) {
+	return &icnk_client.CollectionList{Objects: []icnk_client.Collection{}, Page: page, PerPage: perPage}, nil
+}
+
+func (c *Client) CreateFileSet(
+	ctx context.Context, id string, fileSet *icnk_client.FileSet,
+) (*icnk_client.FileSet, error) {
+	created := *fileSet
+	created.ID = c.id()
+	return &created, nil
+}
+
+func (c *Client) CreateFile(ctx context.Context, asset_id string, file *icnk_client.File) (*icnk_client.File, error) {
+	created := *file
+	created.ID = c.id()
+	created.AssetID = asset_id
+	created.Name = file.OriginalName
+	return &created, nil
+}
+
+func (c *Client) GetFile(ctx context.Context, asset_id, file_id string) (*icnk_client.File, error) {
+	return nil, fmt.Errorf("dry run: file %s: %w", file_id, icnk_client.ErrNotFound)
+}
+
+func (c *Client) ListStorageFiles(
+	ctx context.Context, storage_id string, page, perPage int,
+) (*icnk_client.FileList, error) {
+	return &icnk_client.FileList{Objects: []icnk_client.File{}, Page: page, PerPage: perPage}, nil
+}
+
+func (c *Client) TriggerTranscoding(
+	ctx context.Context, asset_id, file_id string, options *icnk_client.TranscodeOptions,
+) (string, error) {
+	return c.id(), nil
+}
+
+func (c *Client) CloseFile(ctx context.Context, id, file_id string) error {
+	return nil
+}
+
+func (c *Client) CreateAssetFormat(
+	ctx context.Context, id string, format *icnk_client.Format,
+) (*icnk_client.Format, error) {
+	created := *format
+	created.ID = c.id()
+	created.Components = make([]icnk_client.Component, len(format.Components))
+	for i, component := range format.Components {
+		component.ID = c.id()
+		created.Components[i] = component
+	}
+	return &created, nil
+}
+
+func (c *Client) UpdateAssetMetadata(
+	ctx context.Context, asset_id, view_id string, metadata *icnk_client.Metadata,
+) (*icnk_client.Metadata, error) {
+	return metadata, nil
+}
+
+func (c *Client) CreateProxy(ctx context.Context, asset_id string, proxy *icnk_client.Proxy) (*icnk_client.Proxy, error) {
+	created := *proxy
+	created.ID = c.id()
+	return &created, nil
+}
+
+func (c *Client) CloseProxy(ctx context.Context, asset_id, proxy_id string) error {
+	return nil
+}
+
+func (c *Client) CreateKeyframe(
+	ctx context.Context, asset_id string, keyframe *icnk_client.Keyframe,
+) (*icnk_client.Keyframe, error) {
+	created := *keyframe
+	created.ID = c.id()
+	return &created, nil
+}
+
+func (c *Client) CloseKeyframe(ctx context.Context, asset_id, keyframe_id string) error {
+	return nil
+}
+
+func (c *Client) GetJob(ctx context.Context, id string) (*icnk_client.Job, error) {
+	return &icnk_client.Job{ID: id, Status: icnk_client.JobStatusFinished}, nil
+}
+
+func (c *Client) GetCurrentUser(ctx context.Context) (*icnk_client.User, error) {
+	return &icnk_client.User{}, nil
+}
+
+func (c *Client) GetStorage(ctx context.Context, id string) (*icnk_client.Storage, error) {
+	storage := *c.storage
+	storage.ID = id
+	return &storage, nil
+}
+
+// Upload sends nothing, the file is only planned to be uploaded
+func (c *Client) Upload(ctx context.Context, storage storage.Storage, filePath string, file *icnk_client.File) error {
+	return nil
+}
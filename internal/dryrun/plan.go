@@ -0,0 +1,93 @@
+package dryrun
+
+import (
+	"sort"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/pipeline"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+type PlannedFile struct {
+	Path     string `json:"path"`
+	Size     int    `json:"size"`
+	MimeType string `json:"mime_type"`
+}
+
+type Totals struct {
+	Collections int   `json:"collections"`
+	Uploads     int   `json:"uploads"`
+	UploadBytes int64 `json:"upload_bytes"`
+	Skipped     int   `json:"skipped"`
+	Failed      int   `json:"failed"`
+}
+
+// Plan is what a scan would do
+type Plan struct {
+	Collections []string               `json:"collections"`
+	Uploads     []PlannedFile          `json:"uploads"`
+	Skipped     []pipeline.SkippedFile `json:"skipped"`
+	Failed      []pipeline.FailedFile  `json:"failed"`
+	Totals      Totals                 `json:"totals"`
+}
+
+// Run scans the configured directory and runs the uploads against a client
+// that changes nothing in Iconik. The records are written to an overlay on
+// top of base, which is only read and may be nil.
+func Run(config *config.Config, base store.Store, storage *icnk_client.Storage) (*Plan, error) {
+	planConfig := *config
+	// proxies are not part of the plan, so there is no point generating them
+	planConfig.Proxy.Enabled = false
+	// local files are left in place
+	planConfig.PostUpload.Action = ""
+
+	overlay := store.NewOverlayStore(base)
+
+	summary, err := pipeline.RunOnce(&planConfig, overlay, NewClient(storage), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPlan(overlay.Changes(), summary.Skipped), nil
+}
+
+// NewPlan builds the plan from the records written by a dry run
+func NewPlan(changes map[string]*entity.File, skipped []pipeline.SkippedFile) *Plan {
+	plan := &Plan{
+		Collections: []string{},
+		Uploads:     []PlannedFile{},
+		Skipped:     skipped,
+		Failed:      []pipeline.FailedFile{},
+	}
+
+	paths := make([]string, 0, len(changes))
+	for path := range changes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		file := changes[path]
+
+		switch {
+		case file.IsDir():
+			plan.Collections = append(plan.Collections, path)
+		case file.CurrentState() == entity.StateSynced:
+			plan.Uploads = append(plan.Uploads, PlannedFile{Path: path, Size: file.Size, MimeType: file.MimeType})
+			plan.Totals.UploadBytes += int64(file.Size)
+		case file.Error != "":
+			plan.Failed = append(plan.Failed, pipeline.FailedFile{Path: path, Error: file.Error})
+		default:
+			plan.Failed = append(plan.Failed, pipeline.FailedFile{Path: path, Error: "not uploaded"})
+		}
+	}
+
+	plan.Totals.Collections = len(plan.Collections)
+	plan.Totals.Uploads = len(plan.Uploads)
+	plan.Totals.Skipped = len(plan.Skipped)
+	plan.Totals.Failed = len(plan.Failed)
+
+	return plan
+}
@@ -0,0 +1,83 @@
+package dryrun
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/pipeline"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	base, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	defer base.Close()
+
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	files := map[string]string{
+		"rushes/clip.mov":      "video",
+		"rushes/clip.mov.json": `{"scene": "1"}`,
+		"rushes/synced.mov":    "video",
+		"stills/photo.jpg":     "photo",
+	}
+	for path, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte(content), 0644))
+	}
+
+	assert.NoError(t, base.SaveFile("", &entity.File{Name: "media", Type: "directory", ID: "C1"}))
+	assert.NoError(t, base.SaveFile("rushes", &entity.File{Name: "rushes", Type: "directory", ID: "C2"}))
+	assert.NoError(t, base.SaveFile("rushes/synced.mov", &entity.File{Name: "synced.mov", AssetID: "A1", ID: "F1"}))
+
+	cfg := &config.Config{
+		Scanner:  config.ScannerConfig{Dir: dir, Interval: 10},
+		Uploader: config.UploaderConfig{Workers: 2},
+		Iconik:   config.Iconik{StorageID: "S1"},
+		Metadata: config.MetadataConfig{Sidecar: config.SidecarConfig{Patterns: []string{"{name}.json"}}},
+	}
+
+	plan, err := Run(cfg, base, &icnk_client.Storage{ID: "S1", Method: "S3"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"stills"}, plan.Collections)
+	assert.Equal(t, []PlannedFile{
+		{Path: "rushes/clip.mov", Size: 5, MimeType: "video/quicktime"},
+		{Path: "stills/photo.jpg", Size: 5, MimeType: "image/jpeg"},
+	}, plan.Uploads)
+	assert.Equal(t, []pipeline.SkippedFile{
+		{Path: "rushes/clip.mov.json", Reason: "sidecar"},
+		{Path: "rushes/synced.mov", Reason: "synced"},
+	}, plan.Skipped)
+	assert.Empty(t, plan.Failed)
+	assert.Equal(t, Totals{Collections: 1, Uploads: 2, UploadBytes: 10, Skipped: 2}, plan.Totals)
+
+	// nothing is written to the base store
+	exists, err := base.ExistsFile("rushes/clip.mov")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = base.ExistsFile("stills")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestNewPlan(t *testing.T) {
+	plan := NewPlan(map[string]*entity.File{
+		"rushes":       {Type: "directory", ID: "dry-run-1"},
+		"rushes/a.mov": {State: entity.StateSynced, Size: 10, MimeType: "video/quicktime"},
+		"rushes/b.mov": {State: entity.StateFailed, Error: "unknown storage method"},
+		"rushes/c.mov": {State: entity.StatePending},
+	}, []pipeline.SkippedFile{{Path: "a.mov", Reason: "failed"}, {Path: "z.mov", Reason: "synced"}})
+
+	assert.Equal(t, []string{"rushes"}, plan.Collections)
+	assert.Equal(t, []pipeline.FailedFile{
+		{Path: "rushes/b.mov", Error: "unknown storage method"},
+		{Path: "rushes/c.mov", Error: "not uploaded"},
+	}, plan.Failed)
+	assert.Equal(t, "a.mov", plan.Skipped[0].Path)
+	assert.Equal(t, Totals{Collections: 1, Uploads: 1, UploadBytes: 10, Skipped: 2, Failed: 2}, plan.Totals)
+}
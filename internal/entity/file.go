@@ -1,19 +1,131 @@
 package entity
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Sync states of a file
+const (
+	StatePending   = "PENDING"
+	StateUploading = "UPLOADING"
+	StateSynced    = "SYNCED"
+	StateFailed    = "FAILED"
+)
+
+const (
+	TranscodeStatusQueued   = "QUEUED"
+	TranscodeStatusDeferred = "DEFERRED"
+	TranscodeStatusSkipped  = "SKIPPED"
+	TranscodeStatusLocal    = "LOCAL"
+	TranscodeStatusFinished = "FINISHED"
+	TranscodeStatusFailed   = "FAILED"
+)
+
+// Post-upload actions applied to the local file of a synced file
+const (
+	LocalActionMoved           = "MOVED"
+	LocalActionDeleted         = "DELETED"
+	LocalActionDeleteScheduled = "DELETE_SCHEDULED"
+	LocalActionStubbed         = "STUBBED"
+
+	// the action is recorded before it runs, a record left in one of these
+	// states was interrupted and its local file may be gone
+	LocalActionMoving   = "MOVING"
+	LocalActionDeleting = "DELETING"
+	LocalActionStubbing = "STUBBING"
+)
 
 type File struct {
-	DirectoryPath    string `json:"directory_path"`
-	Name             string `json:"name"`
-	Type             string `json:"type,omitempty"`
-	AssetID          string `json:"asset_id,omitempty"`
-	StorageID        string `json:"storage_id,omitempty"`
-	FormatID         string `json:"format_id,omitempty"`
-	FileSetID        string `json:"file_set_id,omitempty"`
-	ID               string `json:"id,omitempty"`
-	Size             int    `json:"size,omitempty"`
+	DirectoryPath string `json:"directory_path"`
+	Name          string `json:"name"`
+	Type          string `json:"type,omitempty"`
+	MimeType      string `json:"mime_type,omitempty"`
+	AssetID       string `json:"asset_id,omitempty"`
+	StorageID     string `json:"storage_id,omitempty"`
+	FormatID      string `json:"format_id,omitempty"`
+	FileSetID     string `json:"file_set_id,omitempty"`
+	ID            string `json:"id,omitempty"`
+	Size          int    `json:"size,omitempty"`
+	// VersionID is the asset version of a file uploaded again to the asset it
+	// was uploaded to before
+	VersionID string `json:"version_id,omitempty"`
+	// Frames is the number of frames of an image sequence
+	Frames int `json:"frames,omitempty"`
+	// Members are the names of the files uploaded as the other formats of the
+	// asset
+	Members []string `json:"members,omitempty"`
+	// Components are the store keys of the other files of a camera card clip
+	Components []string `json:"components,omitempty"`
+	// Archive is the store key of the archive holding the file or directory
+	Archive          string `json:"archive,omitempty"`
 	FileDateCreated  string `json:"file_date_created,omitempty"`
 	FileDateModified string `json:"file_date_modified,omitempty"`
+
+	TranscodeStatus   string `json:"transcode_status,omitempty"`
+	TranscodeJobID    string `json:"transcode_job_id,omitempty"`
+	TranscodePriority int    `json:"transcode_priority,omitempty"`
+	TranscodeAttempts int    `json:"transcode_attempts,omitempty"`
+	TranscodeError    string `json:"transcode_error,omitempty"`
+
+	State     string `json:"state,omitempty"`
+	Error     string `json:"error,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+
+	LocalAction      string `json:"local_action,omitempty"`
+	LocalActionAt    string `json:"local_action_at,omitempty"`
+	LocalActionError string `json:"local_action_error,omitempty"`
+	// ArchivePath is where a moved file was archived
+	ArchivePath string `json:"archive_path,omitempty"`
+	// DeleteAfter is when a file kept for the retention period is deleted
+	DeleteAfter string `json:"delete_after,omitempty"`
+}
+
+// IsDir reports whether the record is a directory synced as a collection
+func (f *File) IsDir() bool {
+	return f.Type == "directory"
+}
+
+// SetState records the sync state of the file, the error of a failed sync
+// and when the state changed
+func (f *File) SetState(state string, err error) {
+	f.State = state
+	f.Error = ""
+	if err != nil {
+		f.Error = err.Error()
+	}
+	f.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// CurrentState returns the sync state, records written before states were
+// tracked are synced once Iconik assigned them an ID
+func (f *File) CurrentState() string {
+	if f.State != "" {
+		return f.State
+	}
+	if f.ID != "" {
+		return StateSynced
+	}
+	return StatePending
+}
+
+// SetLocalAction records the post-upload action applied to the local file
+func (f *File) SetLocalAction(action string) {
+	f.LocalAction = action
+	f.LocalActionError = ""
+	f.LocalActionAt = time.Now().UTC().Format(time.RFC3339)
+}
+
+// LocalFileRemoved reports whether the local file was moved, deleted or
+// replaced by a stub after the upload, or may have been by an interrupted
+// action
+func (f *File) LocalFileRemoved() bool {
+	switch f.LocalAction {
+	case LocalActionMoved, LocalActionDeleted, LocalActionStubbed,
+		LocalActionMoving, LocalActionDeleting, LocalActionStubbing:
+		return true
+	}
+	return false
 }
 
 func (f *File) Marshal() ([]byte, error) {
@@ -2,6 +2,7 @@ package entity
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -78,3 +79,23 @@ func TestUploadFile(t *testing.T) {
 	assert.Equal(t, uploadFile.FileDateCreated, "2023-01-01T00:00:00Z")
 	assert.Equal(t, uploadFile.FileDateModified, "2023-01-01T00:00:00Z")
 }
+
+func TestFileState(t *testing.T) {
+	file := &File{Name: "clip.mov"}
+	assert.Equal(t, StatePending, file.CurrentState())
+
+	file.ID = "D025605F-CF64-4EE5-9F48-E6DD5D363473"
+	assert.Equal(t, StateSynced, file.CurrentState())
+
+	file.SetState(StateFailed, errors.New("upload failed"))
+	assert.Equal(t, StateFailed, file.CurrentState())
+	assert.Equal(t, "upload failed", file.Error)
+	assert.NotEmpty(t, file.UpdatedAt)
+
+	file.SetState(StateSynced, nil)
+	assert.Equal(t, StateSynced, file.CurrentState())
+	assert.Equal(t, "", file.Error)
+
+	assert.False(t, file.IsDir())
+	assert.True(t, (&File{Type: "directory"}).IsDir())
+}
@@ -0,0 +1,187 @@
+package group
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rule groups files with the same name in a directory. A file with one of
+// the primary extensions becomes the ORIGINAL format of the asset, and the
+// files with the same name and a secondary extension its other formats.
+type Rule struct {
+	Primary   []string
+	Secondary []Secondary
+}
+
+type Secondary struct {
+	Extension string
+	// Format is the name of the Iconik format, the extension in upper case
+	// without the dot by default
+	Format string
+}
+
+// Member is a secondary file of a group
+type Member struct {
+	Info   os.FileInfo
+	Format string
+}
+
+// Group is a primary file with its secondary files
+type Group struct {
+	// Dir is the store key of the directory with a trailing slash, empty for
+	// the scanned directory
+	Dir     string
+	Primary os.FileInfo
+	Members []Member
+}
+
+// Key returns the store key of the group, the key of its primary file
+func (g *Group) Key() string {
+	return g.Dir + g.Primary.Name()
+}
+
+// Names returns the names of the secondary files
+func (g *Group) Names() []string {
+	names := make([]string, 0, len(g.Members))
+	for _, member := range g.Members {
+		names = append(names, member.Info.Name())
+	}
+	return names
+}
+
+// Info returns the file info of the primary file with the size of all files
+func (g *Group) Info() os.FileInfo {
+	size := g.Primary.Size()
+	for _, member := range g.Members {
+		size += member.Info.Size()
+	}
+	return &groupInfo{FileInfo: g.Primary, size: size}
+}
+
+// Detect returns the groups formed by the files of a directory. A primary
+// file without any secondary file is not a group.
+func Detect(dir string, files []os.FileInfo, rules []Rule) []*Group {
+	byStem := map[string][]os.FileInfo{}
+	for _, info := range files {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		stem := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+		byStem[stem] = append(byStem[stem], info)
+	}
+
+	stems := make([]string, 0, len(byStem))
+	for stem := range byStem {
+		stems = append(stems, stem)
+	}
+	sort.Strings(stems)
+
+	groups := []*Group{}
+	grouped := map[string]bool{}
+
+	for _, rule := range rules {
+		for _, stem := range stems {
+			group := match(dir, byStem[stem], rule, grouped)
+			if group == nil {
+				continue
+			}
+
+			grouped[group.Primary.Name()] = true
+			for _, member := range group.Members {
+				grouped[member.Info.Name()] = true
+			}
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+// DetectEntries detects the groups of a directory listing like Detect, only
+// the entries with an extension of one of the rules are stat
+func DetectEntries(dir string, entries []os.DirEntry, rules []Rule) []*Group {
+	files := []os.FileInfo{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !hasExtension(rules, filepath.Ext(entry.Name())) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+
+	return Detect(dir, files, rules)
+}
+
+// hasExtension reports whether the extension is a primary or secondary
+// extension of one of the rules
+func hasExtension(rules []Rule, extension string) bool {
+	for _, rule := range rules {
+		for _, primary := range rule.Primary {
+			if strings.EqualFold(primary, extension) {
+				return true
+			}
+		}
+		for _, secondary := range rule.Secondary {
+			if strings.EqualFold(secondary.Extension, extension) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// match returns the group of files with the same stem for a rule, files
+// that are already grouped by a previous rule are left out
+func match(dir string, files []os.FileInfo, rule Rule, grouped map[string]bool) *Group {
+	var primary os.FileInfo
+	for _, extension := range rule.Primary {
+		if primary = find(files, extension, grouped); primary != nil {
+			break
+		}
+	}
+	if primary == nil {
+		return nil
+	}
+
+	group := &Group{Dir: dir, Primary: primary}
+	for _, secondary := range rule.Secondary {
+		info := find(files, secondary.Extension, grouped)
+		if info == nil || info == primary {
+			continue
+		}
+
+		format := secondary.Format
+		if format == "" {
+			format = strings.ToUpper(strings.TrimPrefix(secondary.Extension, "."))
+		}
+		group.Members = append(group.Members, Member{Info: info, Format: format})
+	}
+
+	if len(group.Members) == 0 {
+		return nil
+	}
+
+	return group
+}
+
+func find(files []os.FileInfo, extension string, grouped map[string]bool) os.FileInfo {
+	for _, info := range files {
+		if !grouped[info.Name()] && strings.EqualFold(filepath.Ext(info.Name()), extension) {
+			return info
+		}
+	}
+	return nil
+}
+
+type groupInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (i *groupInfo) Size() int64 { return i.size }
@@ -0,0 +1,101 @@
+package group
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readDir(t *testing.T, names ...string) []os.FileInfo {
+	dir := t.TempDir()
+
+	infos := []os.FileInfo{}
+	for _, name := range names {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+		info, err := os.Stat(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+var rules = []Rule{
+	{
+		Primary:   []string{".cr3", ".nef"},
+		Secondary: []Secondary{{Extension: ".jpg", Format: "JPEG"}, {Extension: ".xmp"}},
+	},
+	{
+		Primary:   []string{".mov"},
+		Secondary: []Secondary{{Extension: ".wav", Format: "AUDIO"}},
+	},
+}
+
+func TestDetect(t *testing.T) {
+	files := readDir(
+		t,
+		"IMG_001.CR3", "IMG_001.JPG", "IMG_001.xmp",
+		"IMG_002.NEF", "IMG_002.jpg",
+		"IMG_003.jpg",
+		"IMG_004.CR3",
+		"A001.mov", "A001.wav",
+	)
+
+	groups := Detect("card/", files, rules)
+	assert.Len(t, groups, 3)
+
+	assert.Equal(t, "card/IMG_001.CR3", groups[0].Key())
+	assert.Equal(t, []string{"IMG_001.JPG", "IMG_001.xmp"}, groups[0].Names())
+	assert.Equal(t, "JPEG", groups[0].Members[0].Format)
+	assert.Equal(t, "XMP", groups[0].Members[1].Format)
+
+	info := groups[0].Info()
+	assert.Equal(t, "IMG_001.CR3", info.Name())
+	assert.Equal(t, int64(len("IMG_001.CR3")+len("IMG_001.JPG")+len("IMG_001.xmp")), info.Size())
+
+	assert.Equal(t, "card/IMG_002.NEF", groups[1].Key())
+	assert.Equal(t, []string{"IMG_002.jpg"}, groups[1].Names())
+
+	assert.Equal(t, "card/A001.mov", groups[2].Key())
+	assert.Equal(t, "AUDIO", groups[2].Members[0].Format)
+}
+
+func TestDetectNoRules(t *testing.T) {
+	assert.Empty(t, Detect("", readDir(t, "IMG_001.CR3", "IMG_001.JPG"), nil))
+}
+
+// statEntry records the entries whose info was read
+type statEntry struct {
+	os.DirEntry
+	stat map[string]bool
+}
+
+func (e *statEntry) Info() (os.FileInfo, error) {
+	e.stat[e.Name()] = true
+	return e.DirEntry.Info()
+}
+
+func TestDetectEntries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a001.CR3", "a001.jpg", "notes.txt", "cover.png"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+
+	listed, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+
+	stat := map[string]bool{}
+	entries := []os.DirEntry{}
+	for _, entry := range listed {
+		entries = append(entries, &statEntry{DirEntry: entry, stat: stat})
+	}
+
+	groups := DetectEntries("stills/", entries, rules)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "stills/a001.CR3", groups[0].Key())
+	assert.Equal(t, []string{"a001.jpg"}, groups[0].Names())
+
+	// only the entries with an extension of a rule are stat
+	assert.Equal(t, map[string]bool{"a001.CR3": true, "a001.jpg": true}, stat)
+}
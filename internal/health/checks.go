@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+)
+
+// Pinger is implemented by stores that can report whether they are usable
+type Pinger interface {
+	Ping() error
+}
+
+// StoreCheck verifies that the store is open
+func StoreCheck(store Pinger) CheckFunc {
+	return func(ctx context.Context) error {
+		return store.Ping()
+	}
+}
+
+// IconikCheck verifies the Iconik credentials with a request for the current
+// user
+func IconikCheck(client icnk_client.Client) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := client.GetCurrentUser(ctx)
+		return err
+	}
+}
+
+// StorageCheck verifies that the Iconik storage exists and is active
+func StorageCheck(client icnk_client.Client, storageID string) CheckFunc {
+	return func(ctx context.Context) error {
+		if storageID == "" {
+			return fmt.Errorf("storage ID is not configured")
+		}
+
+		storage, err := client.GetStorage(ctx, storageID)
+		if err != nil {
+			return err
+		}
+
+		if storage.Status != "" && storage.Status != "ACTIVE" {
+			return fmt.Errorf("storage %s is %s", storage.Name, storage.Status)
+		}
+
+		return nil
+	}
+}
+
+// DirCheck verifies that the directory exists and can be listed
+func DirCheck(dir string) CheckFunc {
+	return func(ctx context.Context) error {
+		if dir == "" {
+			return fmt.Errorf("directory is not configured")
+		}
+
+		f, err := os.Open(dir)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+
+		_, err = f.Readdirnames(1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// RunningCheck reports whether a background loop is running
+func RunningCheck(name string, running func() bool) CheckFunc {
+	return func(ctx context.Context) error {
+		if !running() {
+			return fmt.Errorf("%s is not running", name)
+		}
+		return nil
+	}
+}
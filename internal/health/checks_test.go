@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type pinger struct {
+	err error
+}
+
+func (p pinger) Ping() error {
+	return p.err
+}
+
+func TestStoreCheck(t *testing.T) {
+	assert.NoError(t, StoreCheck(pinger{})(context.Background()))
+	assert.Error(t, StoreCheck(pinger{err: errors.New("store is closed")})(context.Background()))
+}
+
+func TestIconikCheck(t *testing.T) {
+	client := icnk_client.NewMockClient()
+	client.On("GetCurrentUser", mock.Anything).Return(nil, errors.New("request failed: invalid token")).Once()
+	client.On("GetCurrentUser", mock.Anything).Return(&icnk_client.User{ID: "1"}, nil).Once()
+
+	assert.EqualError(t, IconikCheck(client)(context.Background()), "request failed: invalid token")
+	assert.NoError(t, IconikCheck(client)(context.Background()))
+}
+
+func TestStorageCheck(t *testing.T) {
+	client := icnk_client.NewMockClient()
+	client.On("GetStorage", mock.Anything, "S1").Return(&icnk_client.Storage{Name: "ingest", Status: "ACTIVE"}, nil)
+	client.On("GetStorage", mock.Anything, "S2").Return(&icnk_client.Storage{Name: "archive", Status: "INACTIVE"}, nil)
+
+	assert.NoError(t, StorageCheck(client, "S1")(context.Background()))
+	assert.EqualError(t, StorageCheck(client, "S2")(context.Background()), "storage archive is INACTIVE")
+	assert.EqualError(t, StorageCheck(client, "")(context.Background()), "storage ID is not configured")
+}
+
+func TestDirCheck(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, DirCheck(dir)(context.Background()))
+
+	file := filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("data"), 0644))
+	assert.NoError(t, DirCheck(dir)(context.Background()))
+	assert.Error(t, DirCheck(file)(context.Background()))
+	assert.Error(t, DirCheck(filepath.Join(dir, "missing"))(context.Background()))
+}
+
+func TestRunningCheck(t *testing.T) {
+	running := false
+	check := RunningCheck("scanner", func() bool { return running })
+
+	assert.EqualError(t, check(context.Background()), "scanner is not running")
+	running = true
+	assert.NoError(t, check(context.Background()))
+}
@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// CheckFunc returns an error when the checked dependency is not healthy
+type CheckFunc func(ctx context.Context) error
+
+type Result struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs the liveness and readiness checks of the service
+type Checker struct {
+	Timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  []check
+	readiness []check
+}
+
+func NewChecker() *Checker {
+	return &Checker{Timeout: defaultTimeout}
+}
+
+// AddLiveness registers a check telling whether the process is alive
+func (c *Checker) AddLiveness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.liveness = append(c.liveness, check{name: name, fn: fn})
+}
+
+// AddReadiness registers a check telling whether the process is able to work
+func (c *Checker) AddReadiness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readiness = append(c.readiness, check{name: name, fn: fn})
+}
+
+// Live runs the liveness checks
+func (c *Checker) Live(ctx context.Context) (Report, bool) {
+	c.mu.RLock()
+	checks := c.liveness
+	c.mu.RUnlock()
+
+	return c.run(ctx, checks)
+}
+
+// Ready runs the readiness checks
+func (c *Checker) Ready(ctx context.Context) (Report, bool) {
+	c.mu.RLock()
+	checks := c.readiness
+	c.mu.RUnlock()
+
+	return c.run(ctx, checks)
+}
+
+// LivenessHandler serves the liveness report, 503 when a check fails
+func (c *Checker) LivenessHandler() http.Handler {
+	return reportHandler(c.Live)
+}
+
+// ReadinessHandler serves the readiness report, 503 when a check fails
+func (c *Checker) ReadinessHandler() http.Handler {
+	return reportHandler(c.Ready)
+}
+
+// run executes the checks concurrently, each bounded by the timeout
+func (c *Checker) run(ctx context.Context, checks []check) (Report, bool) {
+	results := make([]Result, len(checks))
+
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+			defer cancel()
+
+			results[i] = Result{Name: chk.name, OK: true}
+			if err := chk.fn(checkCtx); err != nil {
+				results[i].OK = false
+				results[i].Error = err.Error()
+			}
+		}(i, chk)
+	}
+	wg.Wait()
+
+	ok := true
+	for _, result := range results {
+		ok = ok && result.OK
+	}
+
+	report := Report{Status: "ok", Checks: results}
+	if !ok {
+		report.Status = "fail"
+	}
+
+	return report, ok
+}
+
+func reportHandler(fn func(ctx context.Context) (Report, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report, ok := fn(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker()
+	checker.AddLiveness("loop", func(ctx context.Context) error { return nil })
+	checker.AddReadiness("store", func(ctx context.Context) error { return nil })
+	checker.AddReadiness("iconik", func(ctx context.Context) error { return errors.New("invalid token") })
+
+	report, ok := checker.Live(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, "ok", report.Status)
+	assert.Equal(t, []Result{{Name: "loop", OK: true}}, report.Checks)
+
+	report, ok = checker.Ready(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, "fail", report.Status)
+	assert.Equal(t, []Result{
+		{Name: "store", OK: true},
+		{Name: "iconik", OK: false, Error: "invalid token"},
+	}, report.Checks)
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	checker := NewChecker()
+	checker.Timeout = 10 * time.Millisecond
+	checker.AddReadiness("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report, ok := checker.Ready(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestHandlers(t *testing.T) {
+	checker := NewChecker()
+	checker.AddLiveness("loop", func(ctx context.Context) error { return nil })
+	checker.AddReadiness("scanner_dir", DirCheck(""))
+
+	recorder := httptest.NewRecorder()
+	checker.LivenessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, "fail", report.Status)
+	assert.Equal(t, "directory is not configured", report.Checks[0].Error)
+}
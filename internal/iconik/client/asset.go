@@ -2,10 +2,14 @@ package client
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/rs/zerolog/log"
 )
 
+// AssetStatusDeleted is the status of assets moved to the Iconik bin
+const AssetStatusDeleted = "DELETED"
+
 type Asset struct {
 	ExternalID   string `json:"external_id"`
 	ID           string `json:"id"`
@@ -15,6 +19,7 @@ type Asset struct {
 	Title        string `json:"title"`
 	Type         string `json:"type"`
 	CollectionID string `json:"collection_id,omitempty"`
+	DateCreated  string `json:"date_created,omitempty"`
 }
 
 func (c *APIClient) CreateAsset(ctx context.Context, asset *Asset) (*Asset, error) {
@@ -33,3 +38,42 @@ func (c *APIClient) CreateAsset(ctx context.Context, asset *Asset) (*Asset, erro
 
 	return &newAsset, nil
 }
+
+func (c *APIClient) GetAsset(ctx context.Context, id string) (*Asset, error) {
+	req, err := c.NewRequest(ctx, "GET", fmt.Sprintf("/API/assets/v1/assets/%s/", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var asset Asset
+	err = c.Do(req, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// AssetVersion is a version of an asset, the formats and file sets created
+// with its ID replace the files of the previous version
+type AssetVersion struct {
+	ID          string `json:"id"`
+	Status      string `json:"status,omitempty"`
+	DateCreated string `json:"date_created,omitempty"`
+}
+
+func (c *APIClient) CreateAssetVersion(ctx context.Context, id string) (*AssetVersion, error) {
+	req, err := c.NewRequest(ctx, "POST", fmt.Sprintf("/API/assets/v1/assets/%s/versions/", id), struct{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var version AssetVersion
+	err = c.Do(req, &version)
+	if err != nil {
+		log.Error().Err(err).Str("service", "iconik_client").Msg("Error creating asset version")
+		return nil, err
+	}
+
+	return &version, nil
+}
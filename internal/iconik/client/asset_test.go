@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -64,3 +65,46 @@ func TestCreateAsset(t *testing.T) {
 	assert.Equal(t, "ACTIVE", asset.Status)
 	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", asset.CollectionID)
 }
+
+func TestGetAsset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+
+		if r.URL.Path == "/API/assets/v1/assets/missing/" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": ["Asset not found"]}`))
+			return
+		}
+
+		assert.Equal(t, "/API/assets/v1/assets/47265105-BE2B-4C3F-8997-66BAB2893D0D/", r.URL.Path)
+		w.Write([]byte(`{"id": "47265105-BE2B-4C3F-8997-66BAB2893D0D", "title": "clip.mov", "status": "DELETED"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	asset, err := client.GetAsset(context.Background(), "47265105-BE2B-4C3F-8997-66BAB2893D0D")
+	assert.NoError(t, err)
+	assert.Equal(t, "clip.mov", asset.Title)
+	assert.Equal(t, AssetStatusDeleted, asset.Status)
+
+	_, err = client.GetAsset(context.Background(), "missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestCreateAssetVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/API/assets/v1/assets/A1/versions/", r.URL.Path)
+
+		w.Write([]byte(`{"id": "V2", "status": "ACTIVE"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	version, err := client.CreateAssetVersion(context.Background(), "A1")
+	assert.NoError(t, err)
+	assert.Equal(t, "V2", version.ID)
+	assert.Equal(t, "ACTIVE", version.Status)
+}
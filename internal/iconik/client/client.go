@@ -4,26 +4,50 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 
+	"github.com/kgantsov/synconik/internal/metrics"
 	"github.com/kgantsov/synconik/internal/storage"
 )
 
+// ErrNotFound is wrapped by the errors of requests answered with 404
+var ErrNotFound = errors.New("not found")
+
 type Client interface {
 	CreateAsset(ctx context.Context, asset *Asset) (*Asset, error)
+	GetAsset(ctx context.Context, id string) (*Asset, error)
+	CreateAssetVersion(ctx context.Context, id string) (*AssetVersion, error)
 
 	CreateCollection(ctx context.Context, collection *Collection) (*Collection, error)
+	GetCollection(ctx context.Context, id string) (*Collection, error)
+	ListCollections(ctx context.Context, page, perPage int) (*CollectionList, error)
+	ListCollectionContents(ctx context.Context, id string, page, perPage int) (*CollectionList, error)
 
 	CreateFileSet(ctx context.Context, id string, fileSet *FileSet) (*FileSet, error)
 
 	CreateFile(ctx context.Context, asset_id string, file *File) (*File, error)
-	TriggerTranscoding(ctx context.Context, asset_id, file_id string) (string, error)
+	GetFile(ctx context.Context, asset_id, file_id string) (*File, error)
+	ListStorageFiles(ctx context.Context, storage_id string, page, perPage int) (*FileList, error)
+	TriggerTranscoding(ctx context.Context, asset_id, file_id string, options *TranscodeOptions) (string, error)
 	CloseFile(ctx context.Context, id, file_id string) error
 
 	CreateAssetFormat(ctx context.Context, id string, format *Format) (*Format, error)
 
+	UpdateAssetMetadata(ctx context.Context, asset_id, view_id string, metadata *Metadata) (*Metadata, error)
+
+	CreateProxy(ctx context.Context, asset_id string, proxy *Proxy) (*Proxy, error)
+	CloseProxy(ctx context.Context, asset_id, proxy_id string) error
+	CreateKeyframe(ctx context.Context, asset_id string, keyframe *Keyframe) (*Keyframe, error)
+	CloseKeyframe(ctx context.Context, asset_id, keyframe_id string) error
+
+	GetJob(ctx context.Context, id string) (*Job, error)
+
+	GetCurrentUser(ctx context.Context) (*User, error)
+
 	GetStorage(ctx context.Context, id string) (*Storage, error)
 	Upload(ctx context.Context, storage storage.Storage, filePath string, file *File) error
 }
@@ -67,12 +91,21 @@ func (c *APIClient) NewRequest(
 }
 
 func (c *APIClient) Do(req *http.Request, v interface{}) error {
+	start := time.Now()
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
+		metrics.ObserveIconikRequest(req.Method, req.URL.Path, 0, time.Since(start))
 		return err
 	}
 	defer resp.Body.Close()
 
+	metrics.ObserveIconikRequest(req.Method, req.URL.Path, resp.StatusCode, time.Since(start))
+
+	if resp.StatusCode == http.StatusNotFound {
+		bodyBytes, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("request failed: %s: %w", bodyBytes, ErrNotFound)
+	}
+
 	if resp.StatusCode >= http.StatusBadRequest {
 		bodyBytes, _ := ioutil.ReadAll(resp.Body)
 		return fmt.Errorf("request failed: %s", bodyBytes)
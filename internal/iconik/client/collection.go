@@ -5,11 +5,26 @@ import (
 	"fmt"
 )
 
+// CollectionStatusDeleted is the status of collections moved to the Iconik bin
+const CollectionStatusDeleted = "DELETED"
+
 type Collection struct {
 	ID        string `json:"id,omitempty"`
 	Title     string `json:"title"`
 	ParentID  string `json:"parent_id,omitempty"`
 	StorageID string `json:"storage_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	// ObjectType is set on the items of the collection contents
+	ObjectType string `json:"object_type,omitempty"`
+}
+
+// CollectionList is a page of collections
+type CollectionList struct {
+	Objects []Collection `json:"objects"`
+	Page    int          `json:"page"`
+	Pages   int          `json:"pages"`
+	PerPage int          `json:"per_page"`
+	Total   int          `json:"total"`
 }
 
 func (c *APIClient) CreateCollection(ctx context.Context, collection *Collection) (*Collection, error) {
@@ -28,3 +43,63 @@ func (c *APIClient) CreateCollection(ctx context.Context, collection *Collection
 
 	return &newCollection, nil
 }
+
+func (c *APIClient) GetCollection(ctx context.Context, id string) (*Collection, error) {
+	req, err := c.NewRequest(ctx, "GET", fmt.Sprintf("/API/assets/v1/collections/%s/", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var collection Collection
+	err = c.Do(req, &collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &collection, nil
+}
+
+// ListCollections returns a page of all collections, pages start at 1
+func (c *APIClient) ListCollections(ctx context.Context, page, perPage int) (*CollectionList, error) {
+	req, err := c.NewRequest(
+		ctx, "GET", fmt.Sprintf("/API/assets/v1/collections/?page=%d&per_page=%d", page, perPage), nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var collections CollectionList
+	err = c.Do(req, &collections)
+	if err != nil {
+		return nil, err
+	}
+
+	return &collections, nil
+}
+
+// ListCollectionContents returns a page of the collections inside a
+// collection, pages start at 1
+func (c *APIClient) ListCollectionContents(
+	ctx context.Context, id string, page, perPage int,
+) (*CollectionList, error) {
+	req, err := c.NewRequest(
+		ctx,
+		"GET",
+		fmt.Sprintf(
+			"/API/assets/v1/collections/%s/contents/?object_types=collections&page=%d&per_page=%d",
+			id, page, perPage,
+		),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var contents CollectionList
+	err = c.Do(req, &contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contents, nil
+}
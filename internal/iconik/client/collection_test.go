@@ -57,3 +57,67 @@ func TestCreateCollection(t *testing.T) {
 	assert.Equal(t, "Test Collection", collection.Title)
 	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", collection.ParentID)
 }
+
+func TestGetCollection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/API/assets/v1/collections/6ba7b811-9dad-11d1-80b4-00c04fd430c8/", r.URL.Path)
+
+		w.Write([]byte(`{"id": "6ba7b811-9dad-11d1-80b4-00c04fd430c8", "title": "rushes", "status": "ACTIVE"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	collection, err := client.GetCollection(context.Background(), "6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	assert.NoError(t, err)
+	assert.Equal(t, "rushes", collection.Title)
+	assert.Equal(t, "ACTIVE", collection.Status)
+}
+
+func TestListCollections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/API/assets/v1/collections/", r.URL.Path)
+		assert.Equal(t, "1", r.URL.Query().Get("page"))
+		assert.Equal(t, "50", r.URL.Query().Get("per_page"))
+
+		w.Write([]byte(`{
+			"objects": [{"id": "C1", "title": "data"}, {"id": "C2", "title": "rushes", "parent_id": "C1"}],
+			"page": 1,
+			"pages": 1
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	collections, err := client.ListCollections(context.Background(), 1, 50)
+	assert.NoError(t, err)
+	assert.Len(t, collections.Objects, 2)
+	assert.Equal(t, "C1", collections.Objects[1].ParentID)
+}
+
+func TestListCollectionContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/API/assets/v1/collections/C1/contents/", r.URL.Path)
+		assert.Equal(t, "collections", r.URL.Query().Get("object_types"))
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+
+		w.Write([]byte(`{
+			"objects": [{"id": "C2", "title": "rushes", "object_type": "collections"}],
+			"page": 2,
+			"pages": 2
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	contents, err := client.ListCollectionContents(context.Background(), "C1", 2, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, contents.Pages)
+	assert.Equal(t, "rushes", contents.Objects[0].Title)
+	assert.Equal(t, "collections", contents.Objects[0].ObjectType)
+}
@@ -7,6 +7,12 @@ import (
 
 const ChunkSize = 5 * 1024 * 1024 // 5 MB
 
+// Statuses of a file
+const (
+	FileStatusClosed  = "CLOSED"
+	FileStatusDeleted = "DELETED"
+)
+
 type File struct {
 	Name              string            `json:"name"`
 	OriginalName      string            `json:"original_name"`
@@ -19,6 +25,9 @@ type File struct {
 	UploadURL         string            `json:"upload_url"`
 	UploadCredentials map[string]string `json:"upload_credentials"`
 	ID                string            `json:"id"`
+	AssetID           string            `json:"asset_id,omitempty"`
+	Status            string            `json:"status,omitempty"`
+	Checksum          string            `json:"checksum,omitempty"`
 
 	FileDateCreated  string `json:"file_date_created,omitempty"`
 	FileDateModified string `json:"file_date_modified,omitempty"`
@@ -41,15 +50,73 @@ func (c *APIClient) CreateFile(ctx context.Context, asset_id string, file *File)
 	return &newFile, nil
 }
 
-func (c *APIClient) TriggerTranscoding(ctx context.Context, asset_id, file_id string) (string, error) {
-	type Body struct {
-		UseStorageTranscodeIgnorePattern bool `json:"use_storage_transcode_ignore_pattern"`
-		Priority                         int  `json:"priority"`
+// FileList is a page of files
+type FileList struct {
+	Objects []File `json:"objects"`
+	Page    int    `json:"page"`
+	Pages   int    `json:"pages"`
+	PerPage int    `json:"per_page"`
+	Total   int    `json:"total"`
+}
+
+func (c *APIClient) GetFile(ctx context.Context, asset_id, file_id string) (*File, error) {
+	req, err := c.NewRequest(
+		ctx, "GET", fmt.Sprintf("/API/files/v1/assets/%s/files/%s/", asset_id, file_id), nil,
+	)
+	if err != nil {
+		return nil, err
 	}
 
-	body := Body{
-		UseStorageTranscodeIgnorePattern: true,
-		Priority:                         5,
+	var file File
+	err = c.Do(req, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// ListStorageFiles returns a page of the files on a storage, pages start at 1
+func (c *APIClient) ListStorageFiles(
+	ctx context.Context, storage_id string, page, perPage int,
+) (*FileList, error) {
+	req, err := c.NewRequest(
+		ctx,
+		"GET",
+		fmt.Sprintf("/API/files/v1/storages/%s/files/?page=%d&per_page=%d", storage_id, page, perPage),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var files FileList
+	err = c.Do(req, &files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &files, nil
+}
+
+// TranscodeOptions controls how Iconik transcodes a file
+type TranscodeOptions struct {
+	Priority                         int  `json:"priority"`
+	UseStorageTranscodeIgnorePattern bool `json:"use_storage_transcode_ignore_pattern"`
+}
+
+// DefaultTranscodeOptions are used when no options are given
+var DefaultTranscodeOptions = TranscodeOptions{
+	Priority:                         5,
+	UseStorageTranscodeIgnorePattern: true,
+}
+
+func (c *APIClient) TriggerTranscoding(
+	ctx context.Context, asset_id, file_id string, options *TranscodeOptions,
+) (string, error) {
+	body := DefaultTranscodeOptions
+	if options != nil {
+		body = *options
 	}
 
 	req, err := c.NewRequest(
@@ -12,6 +12,7 @@ type FileSet struct {
 	Name         string   `json:"name"`
 	ComponentIds []string `json:"component_ids"`
 	ID           string   `json:"id"`
+	VersionID    string   `json:"version_id,omitempty"`
 }
 
 func (c *APIClient) CreateFileSet(ctx context.Context, id string, fileSet *FileSet) (*FileSet, error) {
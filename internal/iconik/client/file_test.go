@@ -124,6 +124,7 @@ func TestTriggerTranscoding(t *testing.T) {
 		context.Background(),
 		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
 		"6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+		&TranscodeOptions{Priority: 5, UseStorageTranscodeIgnorePattern: true},
 	)
 	assert.NoError(t, err)
 	assert.Equal(t, "49b8b43e-074e-11f0-b783-8ab8b108bac0", jobID)
@@ -168,3 +169,47 @@ func TestCloseFile(t *testing.T) {
 	err := client.CloseFile(context.Background(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b811-9dad-11d1-80b4-00c04fd430c8")
 	assert.NoError(t, err)
 }
+
+func TestGetFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/API/files/v1/assets/A1/files/F1/", r.URL.Path)
+
+		w.Write([]byte(`{"id": "F1", "name": "clip.mov", "directory_path": "rushes", "size": 1024, "status": "CLOSED"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	file, err := client.GetFile(context.Background(), "A1", "F1")
+	assert.NoError(t, err)
+	assert.Equal(t, FileStatusClosed, file.Status)
+	assert.Equal(t, int64(1024), file.Size)
+}
+
+func TestListStorageFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/API/files/v1/storages/S1/files/", r.URL.Path)
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+
+		w.Write([]byte(`{
+			"objects": [{"id": "F1", "asset_id": "A1", "name": "clip.mov", "directory_path": "rushes"}],
+			"page": 2,
+			"pages": 2,
+			"per_page": 100,
+			"total": 101
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	files, err := client.ListStorageFiles(context.Background(), "S1", 2, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, files.Pages)
+	assert.Equal(t, 101, files.Total)
+	assert.Len(t, files.Objects, 1)
+	assert.Equal(t, "A1", files.Objects[0].AssetID)
+}
@@ -12,6 +12,26 @@ type Format struct {
 	Name           string              `json:"name"`
 	Status         string              `json:"status"`
 	StorageMethods []string            `json:"storage_methods"`
+	Components     []Component         `json:"components,omitempty"`
+	// VersionID is the version of the asset the format belongs to, the
+	// current version when empty
+	VersionID string `json:"version_id,omitempty"`
+}
+
+// Types of format components
+const (
+	ComponentVideo    = "VIDEO"
+	ComponentAudio    = "AUDIO"
+	ComponentImage    = "IMAGE"
+	ComponentMetadata = "METADATA"
+)
+
+// Component is a part of a format held by its own file, e.g. an audio track
+// of a camera clip, file sets refer to them by their IDs
+type Component struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	Type string `json:"type"`
 }
 
 func (c *APIClient) CreateAssetFormat(ctx context.Context, id string, format *Format) (*Format, error) {
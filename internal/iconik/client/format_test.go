@@ -61,3 +61,37 @@ func TestCreateAssetFormat(t *testing.T) {
 	assert.Equal(t, true, format.IsOnline)
 	assert.Equal(t, "ACTIVE", format.Status)
 }
+
+func TestCreateAssetFormatComponents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var format Format
+		err := json.NewDecoder(r.Body).Decode(&format)
+		assert.NoError(t, err)
+		assert.Equal(t, []Component{
+			{Name: "0001AB.MXF", Type: ComponentVideo},
+			{Name: "0001AB00.MXF", Type: ComponentAudio},
+		}, format.Components)
+
+		format.ID = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+		for i := range format.Components {
+			format.Components[i].ID = fmt.Sprintf("C%d", i+1)
+		}
+
+		json.NewEncoder(w).Encode(format)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app", "token")
+
+	format, err := client.CreateAssetFormat(context.Background(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", &Format{
+		Name:   "ORIGINAL",
+		Status: "ACTIVE",
+		Components: []Component{
+			{Name: "0001AB.MXF", Type: ComponentVideo},
+			{Name: "0001AB00.MXF", Type: ComponentAudio},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "C1", format.Components[0].ID)
+	assert.Equal(t, "C2", format.Components[1].ID)
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	JobStatusFinished = "FINISHED"
+	JobStatusFailed   = "FAILED"
+	JobStatusAborted  = "ABORTED"
+)
+
+type Job struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	ErrorMessage string `json:"error_message"`
+}
+
+func (c *APIClient) GetJob(ctx context.Context, id string) (*Job, error) {
+	req, err := c.NewRequest(ctx, "GET", fmt.Sprintf("/API/jobs/v1/jobs/%s/", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	err = c.Do(req, &job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
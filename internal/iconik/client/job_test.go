@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/API/jobs/v1/jobs/49b8b43e-074e-11f0-b783-8ab8b108bac0/", r.URL.Path)
+		assert.Equal(t, "app-id", r.Header.Get("App-ID"))
+
+		w.Write([]byte(`{
+			"id": "49b8b43e-074e-11f0-b783-8ab8b108bac0",
+			"status": "FAILED",
+			"type": "TRANSCODE",
+			"error_message": "unsupported codec"
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	job, err := client.GetJob(context.Background(), "49b8b43e-074e-11f0-b783-8ab8b108bac0")
+	assert.NoError(t, err)
+	assert.Equal(t, JobStatusFailed, job.Status)
+	assert.Equal(t, "TRANSCODE", job.Type)
+	assert.Equal(t, "unsupported codec", job.ErrorMessage)
+}
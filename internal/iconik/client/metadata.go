@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+type MetadataFieldValue struct {
+	Value string `json:"value"`
+}
+
+type MetadataField struct {
+	FieldValues []MetadataFieldValue `json:"field_values"`
+}
+
+type Metadata struct {
+	MetadataValues map[string]MetadataField `json:"metadata_values"`
+}
+
+// NewMetadata builds the metadata body from field values
+func NewMetadata(values map[string][]string) *Metadata {
+	metadata := &Metadata{MetadataValues: map[string]MetadataField{}}
+
+	for field, fieldValues := range values {
+		metadataField := MetadataField{FieldValues: []MetadataFieldValue{}}
+		for _, value := range fieldValues {
+			metadataField.FieldValues = append(metadataField.FieldValues, MetadataFieldValue{Value: value})
+		}
+		metadata.MetadataValues[field] = metadataField
+	}
+
+	return metadata
+}
+
+func (c *APIClient) UpdateAssetMetadata(
+	ctx context.Context, asset_id, view_id string, metadata *Metadata,
+) (*Metadata, error) {
+	req, err := c.NewRequest(
+		ctx,
+		"PUT",
+		fmt.Sprintf("/API/metadata/v1/assets/%s/views/%s/", asset_id, view_id),
+		metadata,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var newMetadata Metadata
+	err = c.Do(req, &newMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newMetadata, nil
+}
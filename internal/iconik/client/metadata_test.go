@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateAssetMetadata(t *testing.T) {
+	cfg := &config.Config{
+		Iconik: config.Iconik{
+			URL:   "https://app.iconik.io",
+			AppID: "123e4567-e89b-12d3-a456-426614174000",
+			Token: "abcdef0123456789abcdef0123456789",
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Equal(
+			t,
+			fmt.Sprintf(
+				"/API/metadata/v1/assets/%s/views/%s/",
+				"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				"1e4a1e5a-9dad-11d1-80b4-00c04fd430c8",
+			),
+			r.URL.Path,
+		)
+		assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", r.Header.Get("App-ID"))
+		assert.Equal(t, "abcdef0123456789abcdef0123456789", r.Header.Get("Auth-Token"))
+
+		var metadata Metadata
+		err := json.NewDecoder(r.Body).Decode(&metadata)
+		assert.NoError(t, err)
+		assert.Equal(t, "12", metadata.MetadataValues["scene"].FieldValues[0].Value)
+		assert.Len(t, metadata.MetadataValues["keywords"].FieldValues, 2)
+
+		json.NewEncoder(w).Encode(metadata)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, cfg.Iconik.AppID, cfg.Iconik.Token)
+
+	metadata, err := client.UpdateAssetMetadata(
+		context.Background(),
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"1e4a1e5a-9dad-11d1-80b4-00c04fd430c8",
+		NewMetadata(map[string][]string{
+			"scene":    {"12"},
+			"keywords": {"beach", "sunset"},
+		}),
+	)
+	assert.NoError(t, err)
+	assert.NotNil(t, metadata)
+	assert.Equal(t, "sunset", metadata.MetadataValues["keywords"].FieldValues[1].Value)
+}
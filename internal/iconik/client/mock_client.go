@@ -21,6 +21,24 @@ func (m *MockClient) CreateAsset(ctx context.Context, asset *Asset) (*Asset, err
 	return args.Get(0).(*Asset), args.Error(1)
 }
 
+// GetAsset mocks the GetAsset method
+func (m *MockClient) GetAsset(ctx context.Context, id string) (*Asset, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Asset), args.Error(1)
+}
+
+// CreateAssetVersion mocks the CreateAssetVersion method
+func (m *MockClient) CreateAssetVersion(ctx context.Context, id string) (*AssetVersion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AssetVersion), args.Error(1)
+}
+
 // CreateCollection mocks the CreateCollection method
 func (m *MockClient) CreateCollection(ctx context.Context, collection *Collection) (*Collection, error) {
 	args := m.Called(ctx, collection)
@@ -30,6 +48,35 @@ func (m *MockClient) CreateCollection(ctx context.Context, collection *Collectio
 	return args.Get(0).(*Collection), args.Error(1)
 }
 
+// GetCollection mocks the GetCollection method
+func (m *MockClient) GetCollection(ctx context.Context, id string) (*Collection, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Collection), args.Error(1)
+}
+
+// ListCollections mocks the ListCollections method
+func (m *MockClient) ListCollections(ctx context.Context, page, perPage int) (*CollectionList, error) {
+	args := m.Called(ctx, page, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CollectionList), args.Error(1)
+}
+
+// ListCollectionContents mocks the ListCollectionContents method
+func (m *MockClient) ListCollectionContents(
+	ctx context.Context, id string, page, perPage int,
+) (*CollectionList, error) {
+	args := m.Called(ctx, id, page, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CollectionList), args.Error(1)
+}
+
 // CreateFileSet mocks the CreateFileSet method
 func (m *MockClient) CreateFileSet(ctx context.Context, id string, fileSet *FileSet) (*FileSet, error) {
 	args := m.Called(ctx, id, fileSet)
@@ -48,9 +95,31 @@ func (m *MockClient) CreateFile(ctx context.Context, asset_id string, file *File
 	return args.Get(0).(*File), args.Error(1)
 }
 
-// TriggerTranscoding mocks the TriggerTranscoding method
-func (m *MockClient) TriggerTranscoding(ctx context.Context, asset_id, file_id string) (string, error) {
+// GetFile mocks the GetFile method
+func (m *MockClient) GetFile(ctx context.Context, asset_id, file_id string) (*File, error) {
 	args := m.Called(ctx, asset_id, file_id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*File), args.Error(1)
+}
+
+// ListStorageFiles mocks the ListStorageFiles method
+func (m *MockClient) ListStorageFiles(
+	ctx context.Context, storage_id string, page, perPage int,
+) (*FileList, error) {
+	args := m.Called(ctx, storage_id, page, perPage)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FileList), args.Error(1)
+}
+
+// TriggerTranscoding mocks the TriggerTranscoding method
+func (m *MockClient) TriggerTranscoding(
+	ctx context.Context, asset_id, file_id string, options *TranscodeOptions,
+) (string, error) {
+	args := m.Called(ctx, asset_id, file_id, options)
 	return args.String(0), args.Error(1)
 }
 
@@ -69,6 +138,65 @@ func (m *MockClient) CreateAssetFormat(ctx context.Context, id string, format *F
 	return args.Get(0).(*Format), args.Error(1)
 }
 
+// UpdateAssetMetadata mocks the UpdateAssetMetadata method
+func (m *MockClient) UpdateAssetMetadata(
+	ctx context.Context, asset_id, view_id string, metadata *Metadata,
+) (*Metadata, error) {
+	args := m.Called(ctx, asset_id, view_id, metadata)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Metadata), args.Error(1)
+}
+
+// CreateProxy mocks the CreateProxy method
+func (m *MockClient) CreateProxy(ctx context.Context, asset_id string, proxy *Proxy) (*Proxy, error) {
+	args := m.Called(ctx, asset_id, proxy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Proxy), args.Error(1)
+}
+
+// CloseProxy mocks the CloseProxy method
+func (m *MockClient) CloseProxy(ctx context.Context, asset_id, proxy_id string) error {
+	args := m.Called(ctx, asset_id, proxy_id)
+	return args.Error(0)
+}
+
+// CreateKeyframe mocks the CreateKeyframe method
+func (m *MockClient) CreateKeyframe(ctx context.Context, asset_id string, keyframe *Keyframe) (*Keyframe, error) {
+	args := m.Called(ctx, asset_id, keyframe)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Keyframe), args.Error(1)
+}
+
+// CloseKeyframe mocks the CloseKeyframe method
+func (m *MockClient) CloseKeyframe(ctx context.Context, asset_id, keyframe_id string) error {
+	args := m.Called(ctx, asset_id, keyframe_id)
+	return args.Error(0)
+}
+
+// GetJob mocks the GetJob method
+func (m *MockClient) GetJob(ctx context.Context, id string) (*Job, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Job), args.Error(1)
+}
+
+// GetCurrentUser mocks the GetCurrentUser method
+func (m *MockClient) GetCurrentUser(ctx context.Context) (*User, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*User), args.Error(1)
+}
+
 // GetStorage mocks the GetStorage method
 func (m *MockClient) GetStorage(ctx context.Context, id string) (*Storage, error) {
 	args := m.Called(ctx, id)
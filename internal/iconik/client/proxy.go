@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+type Resolution struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type Proxy struct {
+	ID                string            `json:"id,omitempty"`
+	StorageID         string            `json:"storage_id"`
+	Name              string            `json:"name,omitempty"`
+	Filename          string            `json:"filename"`
+	Format            string            `json:"format,omitempty"`
+	Codec             string            `json:"codec,omitempty"`
+	Resolution        *Resolution       `json:"resolution,omitempty"`
+	Status            string            `json:"status,omitempty"`
+	UploadURL         string            `json:"upload_url,omitempty"`
+	UploadCredentials map[string]string `json:"upload_credentials,omitempty"`
+}
+
+type Keyframe struct {
+	ID                string            `json:"id,omitempty"`
+	StorageID         string            `json:"storage_id"`
+	Filename          string            `json:"filename"`
+	Type              string            `json:"type"`
+	Resolution        *Resolution       `json:"resolution,omitempty"`
+	Status            string            `json:"status,omitempty"`
+	UploadURL         string            `json:"upload_url,omitempty"`
+	UploadCredentials map[string]string `json:"upload_credentials,omitempty"`
+}
+
+func (c *APIClient) CreateProxy(ctx context.Context, asset_id string, proxy *Proxy) (*Proxy, error) {
+	req, err := c.NewRequest(
+		ctx, "POST", fmt.Sprintf("/API/files/v1/assets/%s/proxies/", asset_id), proxy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var newProxy Proxy
+	err = c.Do(req, &newProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newProxy, nil
+}
+
+func (c *APIClient) CloseProxy(ctx context.Context, asset_id, proxy_id string) error {
+	body := map[string]string{"status": "CLOSED"}
+
+	req, err := c.NewRequest(
+		ctx, "PATCH", fmt.Sprintf("/API/files/v1/assets/%s/proxies/%s/", asset_id, proxy_id), body,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = c.Do(req, &body)
+	if err != nil {
+		return fmt.Errorf("error closing the proxy: %w", err)
+	}
+
+	return nil
+}
+
+func (c *APIClient) CreateKeyframe(ctx context.Context, asset_id string, keyframe *Keyframe) (*Keyframe, error) {
+	req, err := c.NewRequest(
+		ctx, "POST", fmt.Sprintf("/API/files/v1/assets/%s/keyframes/", asset_id), keyframe,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var newKeyframe Keyframe
+	err = c.Do(req, &newKeyframe)
+	if err != nil {
+		return nil, err
+	}
+
+	return &newKeyframe, nil
+}
+
+func (c *APIClient) CloseKeyframe(ctx context.Context, asset_id, keyframe_id string) error {
+	body := map[string]string{"status": "CLOSED"}
+
+	req, err := c.NewRequest(
+		ctx, "PATCH", fmt.Sprintf("/API/files/v1/assets/%s/keyframes/%s/", asset_id, keyframe_id), body,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = c.Do(req, &body)
+	if err != nil {
+		return fmt.Errorf("error closing the keyframe: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAndCloseProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			assert.Equal(t, "/API/files/v1/assets/6ba7b810-9dad-11d1-80b4-00c04fd430c8/proxies/", r.URL.Path)
+
+			var proxy Proxy
+			err := json.NewDecoder(r.Body).Decode(&proxy)
+			assert.NoError(t, err)
+			assert.Equal(t, "5B4BAE0D-5E07-4B36-A2C2-0DF79F558F6F", proxy.StorageID)
+			assert.Equal(t, "image.jpg", proxy.Filename)
+			assert.Equal(t, 1024, proxy.Resolution.Width)
+
+			proxy.ID = "6ba7b811-9dad-11d1-80b4-00c04fd430c8"
+			proxy.UploadURL = "https://upload.example.com"
+			json.NewEncoder(w).Encode(proxy)
+		case "PATCH":
+			assert.Equal(
+				t,
+				"/API/files/v1/assets/6ba7b810-9dad-11d1-80b4-00c04fd430c8/proxies/6ba7b811-9dad-11d1-80b4-00c04fd430c8/",
+				r.URL.Path,
+			)
+
+			var body map[string]string
+			err := json.NewDecoder(r.Body).Decode(&body)
+			assert.NoError(t, err)
+			assert.Equal(t, "CLOSED", body["status"])
+
+			json.NewEncoder(w).Encode(body)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	proxy, err := client.CreateProxy(context.Background(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", &Proxy{
+		StorageID:  "5B4BAE0D-5E07-4B36-A2C2-0DF79F558F6F",
+		Filename:   "image.jpg",
+		Resolution: &Resolution{Width: 1024, Height: 768},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "6ba7b811-9dad-11d1-80b4-00c04fd430c8", proxy.ID)
+	assert.Equal(t, "https://upload.example.com", proxy.UploadURL)
+
+	err = client.CloseProxy(
+		context.Background(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b811-9dad-11d1-80b4-00c04fd430c8",
+	)
+	assert.NoError(t, err)
+}
+
+func TestCreateAndCloseKeyframe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			assert.Equal(t, "/API/files/v1/assets/6ba7b810-9dad-11d1-80b4-00c04fd430c8/keyframes/", r.URL.Path)
+
+			var keyframe Keyframe
+			err := json.NewDecoder(r.Body).Decode(&keyframe)
+			assert.NoError(t, err)
+			assert.Equal(t, "KEYFRAME", keyframe.Type)
+
+			keyframe.ID = "6ba7b812-9dad-11d1-80b4-00c04fd430c8"
+			json.NewEncoder(w).Encode(keyframe)
+		case "PATCH":
+			assert.Equal(
+				t,
+				"/API/files/v1/assets/6ba7b810-9dad-11d1-80b4-00c04fd430c8/keyframes/6ba7b812-9dad-11d1-80b4-00c04fd430c8/",
+				r.URL.Path,
+			)
+			w.Write([]byte(`{"status": "CLOSED"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	keyframe, err := client.CreateKeyframe(context.Background(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", &Keyframe{
+		StorageID: "5B4BAE0D-5E07-4B36-A2C2-0DF79F558F6F",
+		Filename:  "image_keyframe.jpg",
+		Type:      "KEYFRAME",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "6ba7b812-9dad-11d1-80b4-00c04fd430c8", keyframe.ID)
+
+	err = client.CloseKeyframe(
+		context.Background(), "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b812-9dad-11d1-80b4-00c04fd430c8",
+	)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+)
+
+type User struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// GetCurrentUser returns the user the token belongs to, it is a cheap way to
+// validate the credentials
+func (c *APIClient) GetCurrentUser(ctx context.Context) (*User, error) {
+	req, err := c.NewRequest(ctx, "GET", "/API/users/v1/users/current/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	err = c.Do(req, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCurrentUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/API/users/v1/users/current/", r.URL.Path)
+		assert.Equal(t, "token", r.Header.Get("Auth-Token"))
+
+		w.Write([]byte(`{
+			"id": "0a1ec6ba-0741-11f0-8c8f-8ab8b108bac0",
+			"email": "ingest@example.com",
+			"first_name": "Ingest",
+			"last_name": "Host"
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	user, err := client.GetCurrentUser(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "0a1ec6ba-0741-11f0-8c8f-8ab8b108bac0", user.ID)
+	assert.Equal(t, "ingest@example.com", user.Email)
+}
+
+func TestGetCurrentUser_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"errors": ["Invalid token"]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "app-id", "token")
+
+	_, err := client.GetCurrentUser(context.Background())
+	assert.Error(t, err)
+}
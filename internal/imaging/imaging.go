@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ErrTooLarge is returned by Decode for images of more pixels than allowed
+var ErrTooLarge = errors.New("image is too large")
+
+var supportedExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+}
+
+// IsSupported reports whether the file can be decoded by Decode
+func IsSupported(path string) bool {
+	return supportedExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Decode reads a JPEG, PNG or GIF image. The dimensions are read first and
+// images of more than maxPixels pixels are rejected with ErrTooLarge without
+// decoding them, a maxPixels <= 0 allows any size.
+func Decode(path string, maxPixels int) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if maxPixels > 0 && config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return img, nil
+}
+
+// Resize scales the image down so that its longest side is at most maxSize,
+// averaging the source pixels that fall into each destination pixel. Images
+// that already fit are returned as is.
+func Resize(img image.Image, maxSize int) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	if maxSize <= 0 || (srcWidth <= maxSize && srcHeight <= maxSize) {
+		return img
+	}
+
+	dstWidth, dstHeight := maxSize, maxSize
+	if srcWidth >= srcHeight {
+		dstHeight = max(1, srcHeight*maxSize/srcWidth)
+	} else {
+		dstWidth = max(1, srcWidth*maxSize/srcHeight)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcWidth, srcHeight))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		y0 := y * srcHeight / dstHeight
+		y1 := max(y0+1, (y+1)*srcHeight/dstHeight)
+
+		for x := 0; x < dstWidth; x++ {
+			x0 := x * srcWidth / dstWidth
+			x1 := max(x0+1, (x+1)*srcWidth/dstWidth)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[offset])
+					g += int(src.Pix[offset+1])
+					b += int(src.Pix[offset+2])
+					a += int(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// SaveJPEG encodes the image as a JPEG file
+func SaveJPEG(path string, img image.Image, quality int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	err = jpeg.Encode(f, img, &jpeg.Options{Quality: quality})
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+
+	return f.Close()
+}
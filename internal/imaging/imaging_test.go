@@ -0,0 +1,80 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	resized := Resize(img, 100)
+	assert.Equal(t, 100, resized.Bounds().Dx())
+	assert.Equal(t, 50, resized.Bounds().Dy())
+	assert.Equal(t, color.RGBA{R: 200, G: 100, B: 50, A: 255}, resized.At(10, 10))
+
+	tall := Resize(image.NewRGBA(image.Rect(0, 0, 100, 300)), 30)
+	assert.Equal(t, 10, tall.Bounds().Dx())
+	assert.Equal(t, 30, tall.Bounds().Dy())
+
+	assert.Equal(t, img, Resize(img, 1000))
+}
+
+func TestDecodeAndSaveJPEG(t *testing.T) {
+	dir := t.TempDir()
+
+	pngPath := filepath.Join(dir, "image.png")
+	f, err := os.Create(pngPath)
+	assert.NoError(t, err)
+	err = png.Encode(f, image.NewRGBA(image.Rect(0, 0, 64, 32)))
+	assert.NoError(t, err)
+	f.Close()
+
+	assert.True(t, IsSupported(pngPath))
+	assert.False(t, IsSupported(filepath.Join(dir, "clip.mov")))
+
+	img, err := Decode(pngPath, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, img.Bounds().Dx())
+
+	jpegPath := filepath.Join(dir, "image.jpg")
+	err = SaveJPEG(jpegPath, Resize(img, 16), 85)
+	assert.NoError(t, err)
+
+	img, err = Decode(jpegPath, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, img.Bounds().Dx())
+	assert.Equal(t, 8, img.Bounds().Dy())
+
+	_, err = Decode(filepath.Join(dir, "missing.png"), 0)
+	assert.Error(t, err)
+}
+
+func TestDecodeTooLarge(t *testing.T) {
+	pngPath := filepath.Join(t.TempDir(), "image.png")
+	f, err := os.Create(pngPath)
+	assert.NoError(t, err)
+	err = png.Encode(f, image.NewRGBA(image.Rect(0, 0, 64, 32)))
+	assert.NoError(t, err)
+	f.Close()
+
+	_, err = Decode(pngPath, 64*32-1)
+	assert.True(t, errors.Is(err, ErrTooLarge))
+	assert.EqualError(t, err, "image is too large: 64x32")
+
+	img, err := Decode(pngPath, 64*32)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, img.Bounds().Dx())
+}
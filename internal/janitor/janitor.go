@@ -0,0 +1,77 @@
+package janitor
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/rs/zerolog/log"
+)
+
+const interval = 5 * time.Minute
+
+// Janitor periodically deletes the local files of synced files once their
+// retention period is over
+type Janitor struct {
+	config *config.Config
+
+	postUploadUseCase *usecase.PostUploadUseCase
+
+	done    chan bool
+	running atomic.Bool
+}
+
+func NewJanitor(config *config.Config, store store.Store, client icnk_client.Client) *Janitor {
+	return &Janitor{
+		config: config,
+
+		postUploadUseCase: usecase.NewPostUploadUseCase(config, client, store),
+
+		done: make(chan bool),
+	}
+}
+
+func (j *Janitor) start() {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer j.running.Store(false)
+
+	for {
+		select {
+		case <-ticker.C:
+			j.Run()
+		case <-j.done:
+			log.Debug().Str("service", "janitor").Msg("Stopped the janitor")
+			return
+		}
+	}
+}
+
+func (j *Janitor) Start() {
+	log.Debug().Str("service", "janitor").Msg("Starting the janitor")
+
+	j.running.Store(true)
+	go j.start()
+}
+
+// Running reports whether the janitor loop is running
+func (j *Janitor) Running() bool {
+	return j.running.Load()
+}
+
+func (j *Janitor) Stop() {
+	log.Debug().Str("service", "janitor").Msg("Stopping the janitor")
+
+	close(j.done)
+}
+
+// Run deletes the expired files once
+func (j *Janitor) Run() {
+	err := j.postUploadUseCase.DeleteExpired(time.Now())
+	if err != nil {
+		log.Error().Err(err).Str("service", "janitor").Msg("Error deleting expired files")
+	}
+}
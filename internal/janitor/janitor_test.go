@@ -0,0 +1,57 @@
+package janitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJanitor(t *testing.T) {
+	tmpDbDir, err := os.MkdirTemp("", "janitor-test-db-*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDbDir)
+
+	store, err := store.NewBadgerStore(tmpDbDir)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	dir := t.TempDir() + "/"
+	err = os.WriteFile(filepath.Join(dir, "clip.mov"), []byte("data"), 0644)
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, "clip.mov"))
+	assert.NoError(t, err)
+
+	err = store.SaveFile("clip.mov", &entity.File{
+		Name:             "clip.mov",
+		Size:             4,
+		FileDateModified: info.ModTime().Format(time.RFC3339),
+		LocalAction:      entity.LocalActionDeleteScheduled,
+		DeleteAfter:      time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+	})
+	assert.NoError(t, err)
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir}}
+
+	janitor := NewJanitor(cfg, store, client.NewMockClient())
+	janitor.Start()
+
+	janitor.Run()
+
+	_, err = os.Stat(filepath.Join(dir, "clip.mov"))
+	assert.True(t, os.IsNotExist(err))
+
+	file, err := store.GetFile("clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.LocalActionDeleted, file.LocalAction)
+
+	janitor.Stop()
+	_, ok := <-janitor.done
+	assert.False(t, ok, "done channel should be closed")
+}
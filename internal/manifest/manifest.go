@@ -0,0 +1,105 @@
+package manifest
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kgantsov/synconik/internal/metadata"
+)
+
+// Columns of a CSV manifest, every other column is a metadata field
+const (
+	ColumnPath         = "path"
+	ColumnCollectionID = "collection_id"
+	ColumnTitle        = "title"
+)
+
+// Entry is a file to push with the optional overrides of its asset
+type Entry struct {
+	Path         string
+	CollectionID string
+	Title        string
+	Metadata     metadata.Values
+}
+
+// ReadList reads one path per line, blank lines and lines starting with # are
+// ignored
+func ReadList(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, Entry{Path: line})
+	}
+
+	return entries, scanner.Err()
+}
+
+// ReadCSV reads a manifest with a header row. The path column is required,
+// collection_id and title are optional and the values of the other columns
+// are added to the metadata of the asset under the column name.
+func ReadCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pathColumn := -1
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		if header[i] == ColumnPath {
+			pathColumn = i
+		}
+	}
+	if pathColumn < 0 {
+		return nil, fmt.Errorf("manifest has no %s column", ColumnPath)
+	}
+
+	entries := []Entry{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry := Entry{Metadata: metadata.Values{}}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+
+			switch header[i] {
+			case ColumnPath:
+				entry.Path = value
+			case ColumnCollectionID:
+				entry.CollectionID = value
+			case ColumnTitle:
+				entry.Title = value
+			default:
+				entry.Metadata.Add(header[i], value)
+			}
+		}
+
+		if entry.Path == "" {
+			line, _ := reader.FieldPos(pathColumn)
+			return nil, fmt.Errorf("line %d: empty %s", line, ColumnPath)
+		}
+
+		entries = append(entries, entry)
+	}
+}
@@ -0,0 +1,45 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadList(t *testing.T) {
+	entries, err := ReadList(strings.NewReader("/media/a.mov\n\n# comment\n  /media/b c.mov  \n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{{Path: "/media/a.mov"}, {Path: "/media/b c.mov"}}, entries)
+}
+
+func TestReadCSV(t *testing.T) {
+	entries, err := ReadCSV(strings.NewReader(
+		"path,collection_id,title,scene,take\n" +
+			"/media/a.mov,C1,Opening,1,2\n" +
+			"\"/media/b,c.mov\",,,,\n",
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{
+			Path:         "/media/a.mov",
+			CollectionID: "C1",
+			Title:        "Opening",
+			Metadata:     metadata.Values{"scene": {"1"}, "take": {"2"}},
+		},
+		{Path: "/media/b,c.mov", Metadata: metadata.Values{}},
+	}, entries)
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	_, err := ReadCSV(strings.NewReader("file,title\n/media/a.mov,A\n"))
+	assert.EqualError(t, err, "manifest has no path column")
+
+	_, err = ReadCSV(strings.NewReader("path,title\n/media/a.mov,A\n,B\n"))
+	assert.EqualError(t, err, "line 3: empty path")
+
+	entries, err := ReadCSV(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
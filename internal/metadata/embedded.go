@@ -0,0 +1,182 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	exifHeader      = []byte("Exif\x00\x00")
+	xmpHeader       = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	photoshopHeader = []byte("Photoshop 3.0\x00")
+
+	// ErrUnsupportedFormat is returned for files that are neither JPEG nor TIFF
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+)
+
+var iptcTags = map[byte]string{
+	5:   "IPTC:ObjectName",
+	25:  "IPTC:Keywords",
+	80:  "IPTC:Byline",
+	90:  "IPTC:City",
+	101: "IPTC:Country",
+	105: "IPTC:Headline",
+	116: "IPTC:CopyrightNotice",
+	120: "IPTC:Caption",
+}
+
+// ReadEmbedded extracts EXIF, IPTC and XMP metadata embedded in a JPEG or
+// TIFF file. EXIF tags are keyed by their name (Make, DateTimeOriginal, ...),
+// IPTC datasets as IPTC:Name and XMP properties as prefix:name.
+func ReadEmbedded(path string) (Values, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	values := Values{}
+
+	switch {
+	case magic[0] == 0xFF && magic[1] == 0xD8:
+		if _, err := f.Seek(2, io.SeekStart); err != nil {
+			return nil, err
+		}
+		err = readJPEG(bufio.NewReader(f), values)
+	case bytes.Equal(magic, []byte("II*\x00")) || bytes.Equal(magic, []byte("MM\x00*")):
+		err = parseTIFF(f, values)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// CaptureTime returns the time the image was taken
+func CaptureTime(values Values) (time.Time, bool) {
+	for _, name := range []string{"DateTimeOriginal", "photoshop:DateCreated", "xmp:CreateDate", "DateTime"} {
+		if t, err := time.Parse(time.RFC3339, values.Get(name)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func readJPEG(r *bufio.Reader, values Values) error {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil
+		}
+		if b != 0xFF {
+			continue
+		}
+
+		marker, err := r.ReadByte()
+		if err != nil {
+			return nil
+		}
+
+		switch {
+		case marker == 0xFF || marker == 0x00:
+			if marker == 0xFF {
+				r.UnreadByte()
+			}
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// metadata segments always precede the image data
+			return nil
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil || length < 2 {
+			return nil
+		}
+
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil
+		}
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(segment, exifHeader):
+			if err := parseTIFF(bytes.NewReader(segment[len(exifHeader):]), values); err != nil {
+				return err
+			}
+		case marker == 0xE1 && bytes.HasPrefix(segment, xmpHeader):
+			if err := parseXMP(segment[len(xmpHeader):], values); err != nil {
+				return err
+			}
+		case marker == 0xED && bytes.HasPrefix(segment, photoshopHeader):
+			parsePhotoshop(segment[len(photoshopHeader):], values)
+		}
+	}
+}
+
+// parsePhotoshop reads the IPTC record from Photoshop image resource blocks
+func parsePhotoshop(data []byte, values Values) {
+	for len(data) >= 12 && bytes.Equal(data[:4], []byte("8BIM")) {
+		id := binary.BigEndian.Uint16(data[4:])
+
+		// pascal string name padded to an even length
+		nameLength := int(data[6]) + 1
+		if nameLength%2 != 0 {
+			nameLength++
+		}
+		offset := 6 + nameLength
+		if len(data) < offset+4 {
+			return
+		}
+
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		offset += 4
+		if size < 0 || len(data) < offset+size {
+			return
+		}
+
+		if id == 0x0404 {
+			parseIPTC(data[offset:offset+size], values)
+		}
+
+		if size%2 != 0 {
+			size++
+		}
+		if len(data) < offset+size {
+			return
+		}
+		data = data[offset+size:]
+	}
+}
+
+func parseIPTC(data []byte, values Values) {
+	for len(data) >= 5 && data[0] == 0x1C {
+		record, dataset := data[1], data[2]
+		size := int(binary.BigEndian.Uint16(data[3:]))
+		if size&0x8000 != 0 || len(data) < 5+size {
+			return
+		}
+
+		if name, ok := iptcTags[dataset]; ok && record == 2 {
+			values.Add(name, string(bytes.TrimSpace(data[5:5+size])))
+		}
+
+		data = data[5+size:]
+	}
+}
@@ -0,0 +1,176 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiEntry(tag uint16, value string) testEntry {
+	return testEntry{tag: tag, typ: 2, count: uint32(len(value) + 1), data: append([]byte(value), 0)}
+}
+
+func longEntry(tag uint16, value uint32) testEntry {
+	return testEntry{tag: tag, typ: 4, count: 1, data: binary.LittleEndian.AppendUint32(nil, value)}
+}
+
+func rationalEntry(tag uint16, values ...uint32) testEntry {
+	data := []byte{}
+	for _, value := range values {
+		data = binary.LittleEndian.AppendUint32(data, value)
+	}
+	return testEntry{tag: tag, typ: 5, count: uint32(len(values) / 2), data: data}
+}
+
+func ifdSize(entries []testEntry) int {
+	size := 2 + 12*len(entries) + 4
+	for _, entry := range entries {
+		if len(entry.data) > 4 {
+			size += len(entry.data)
+		}
+	}
+	return size
+}
+
+func writeIFD(buf *bytes.Buffer, offset int, entries []testEntry) {
+	dataOffset := offset + 2 + 12*len(entries) + 4
+	data := []byte{}
+
+	binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(buf, binary.LittleEndian, entry.tag)
+		binary.Write(buf, binary.LittleEndian, entry.typ)
+		binary.Write(buf, binary.LittleEndian, entry.count)
+		if len(entry.data) > 4 {
+			binary.Write(buf, binary.LittleEndian, uint32(dataOffset+len(data)))
+			data = append(data, entry.data...)
+		} else {
+			value := make([]byte, 4)
+			copy(value, entry.data)
+			buf.Write(value)
+		}
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	buf.Write(data)
+}
+
+func buildTIFF(ifd0, exif, gps []testEntry) []byte {
+	exifOffset := 8 + ifdSize(ifd0) + 2*12
+	gpsOffset := exifOffset + ifdSize(exif)
+	ifd0 = append(ifd0, longEntry(tagExifIFD, uint32(exifOffset)), longEntry(tagGPSIFD, uint32(gpsOffset)))
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("II")
+	binary.Write(buf, binary.LittleEndian, uint16(42))
+	binary.Write(buf, binary.LittleEndian, uint32(8))
+	writeIFD(buf, 8, ifd0)
+	writeIFD(buf, exifOffset, exif)
+	writeIFD(buf, gpsOffset, gps)
+
+	return buf.Bytes()
+}
+
+func jpegSegment(marker byte, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	segment := []byte{0xFF, marker}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+func testTIFF() []byte {
+	return buildTIFF(
+		[]testEntry{asciiEntry(0x010F, "Canon"), asciiEntry(0x0110, "Canon EOS R5")},
+		[]testEntry{
+			rationalEntry(0x829A, 1, 250),
+			rationalEntry(0x829D, 28, 10),
+			asciiEntry(0x9003, "2024:05:01 14:30:00"),
+			asciiEntry(0x9011, "+02:00"),
+			asciiEntry(0xA434, "RF24-70mm F2.8 L IS USM"),
+		},
+		[]testEntry{
+			asciiEntry(0x0001, "N"),
+			rationalEntry(0x0002, 52, 1, 22, 1, 12, 1),
+			asciiEntry(0x0003, "W"),
+			rationalEntry(0x0004, 4, 1, 53, 1, 0, 1),
+		},
+	)
+}
+
+func TestReadEmbedded_JPEG(t *testing.T) {
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmp:Rating="4">` +
+		`<dc:subject><rdf:Bag><rdf:li>beach</rdf:li><rdf:li>sunset</rdf:li></rdf:Bag></dc:subject>` +
+		`<dc:title><rdf:Alt><rdf:li xml:lang="x-default">Golden hour</rdf:li></rdf:Alt></dc:title>` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>`
+
+	iptc := []byte{0x1C, 2, 25, 0, 4}
+	iptc = append(iptc, "surf"...)
+	irb := []byte("8BIM\x04\x04\x00\x00")
+	irb = binary.BigEndian.AppendUint32(irb, uint32(len(iptc)))
+	irb = append(irb, iptc...)
+	irb = append(irb, 0)
+
+	image := [][]byte{
+		{0xFF, 0xD8},
+		jpegSegment(0xE1, exifHeader, testTIFF()),
+		jpegSegment(0xE1, xmpHeader, []byte(xmp)),
+		jpegSegment(0xED, photoshopHeader, irb),
+		{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9},
+	}
+
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	err := os.WriteFile(path, bytes.Join(image, nil), 0644)
+	assert.NoError(t, err)
+
+	values, err := ReadEmbedded(path)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Canon", values.Get("Make"))
+	assert.Equal(t, "Canon EOS R5", values.Get("Model"))
+	assert.Equal(t, "RF24-70mm F2.8 L IS USM", values.Get("LensModel"))
+	assert.Equal(t, "1/250", values.Get("ExposureTime"))
+	assert.Equal(t, "2.8", values.Get("FNumber"))
+	assert.Equal(t, "2024-05-01T14:30:00+02:00", values.Get("DateTimeOriginal"))
+	assert.Equal(t, "52.370000", values.Get("GPSLatitude"))
+	assert.Equal(t, "-4.883333", values.Get("GPSLongitude"))
+	assert.Equal(t, []string{"beach", "sunset"}, values["dc:subject"])
+	assert.Equal(t, "Golden hour", values.Get("dc:title"))
+	assert.Equal(t, "4", values.Get("xmp:Rating"))
+	assert.Equal(t, "surf", values.Get("IPTC:Keywords"))
+
+	captureTime, ok := CaptureTime(values)
+	assert.True(t, ok)
+	assert.True(t, captureTime.Equal(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)))
+}
+
+func TestReadEmbedded_TIFF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.tif")
+	err := os.WriteFile(path, testTIFF(), 0644)
+	assert.NoError(t, err)
+
+	values, err := ReadEmbedded(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "Canon EOS R5", values.Get("Model"))
+	assert.Equal(t, "2024-05-01T14:30:00+02:00", values.Get("DateTimeOriginal"))
+}
+
+func TestReadEmbedded_Unsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	err := os.WriteFile(path, []byte("hello"), 0644)
+	assert.NoError(t, err)
+
+	_, err = ReadEmbedded(path)
+	assert.Equal(t, ErrUnsupportedFormat, err)
+}
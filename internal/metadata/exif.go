@@ -0,0 +1,295 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExifTimeLayout is the layout of EXIF date and time values
+const ExifTimeLayout = "2006:01:02 15:04:05"
+
+const (
+	tagExifIFD   = 0x8769
+	tagGPSIFD    = 0x8825
+	tagXMLPacket = 0x02BC
+
+	maxIFDEntries = 1024
+)
+
+var ifd0Tags = map[uint16]string{
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x8298: "Copyright",
+}
+
+var exifTags = map[uint16]string{
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8827: "ISO",
+	0x9003: "DateTimeOriginal",
+	0x9011: "OffsetTimeOriginal",
+	0x920A: "FocalLength",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA433: "LensMake",
+	0xA434: "LensModel",
+}
+
+var errInvalidTIFF = errors.New("invalid TIFF data")
+
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+type tiffReader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+// parseTIFF reads the EXIF tags of a TIFF structure, which is either a TIFF
+// file or the payload of a JPEG APP1 Exif segment
+func parseTIFF(r io.ReaderAt, values Values) error {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return errInvalidTIFF
+	}
+
+	t := &tiffReader{r: r}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return errInvalidTIFF
+	}
+
+	if t.order.Uint16(header[2:]) != 42 {
+		return errInvalidTIFF
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(header[4:]))
+	if err != nil {
+		return err
+	}
+	t.addTags(values, ifd0, ifd0Tags)
+
+	if entry, ok := ifd0[tagXMLPacket]; ok {
+		if err := parseXMP(entry.data, values); err != nil {
+			return err
+		}
+	}
+
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		exifIFD, err := t.readIFD(t.uint(entry, 0))
+		if err != nil {
+			return err
+		}
+		t.addTags(values, exifIFD, exifTags)
+	}
+
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		gpsIFD, err := t.readIFD(t.uint(entry, 0))
+		if err != nil {
+			return err
+		}
+		t.addGPS(values, gpsIFD)
+	}
+
+	normalizeExifTimes(values)
+
+	return nil
+}
+
+func (t *tiffReader) readIFD(offset uint32) (map[uint16]tiffEntry, error) {
+	buf := make([]byte, 2)
+	if _, err := t.r.ReadAt(buf, int64(offset)); err != nil {
+		return nil, errInvalidTIFF
+	}
+
+	count := int(t.order.Uint16(buf))
+	if count > maxIFDEntries {
+		return nil, errInvalidTIFF
+	}
+
+	raw := make([]byte, count*12)
+	if _, err := t.r.ReadAt(raw, int64(offset)+2); err != nil {
+		return nil, errInvalidTIFF
+	}
+
+	entries := map[uint16]tiffEntry{}
+	for i := 0; i < count; i++ {
+		field := raw[i*12 : i*12+12]
+		entry := tiffEntry{
+			tag:   t.order.Uint16(field[0:]),
+			typ:   t.order.Uint16(field[2:]),
+			count: t.order.Uint32(field[4:]),
+		}
+
+		size := int64(typeSize(entry.typ)) * int64(entry.count)
+		if size == 0 || size > 1<<20 {
+			continue
+		}
+
+		if size <= 4 {
+			entry.data = field[8 : 8+size]
+		} else {
+			entry.data = make([]byte, size)
+			if _, err := t.r.ReadAt(entry.data, int64(t.order.Uint32(field[8:]))); err != nil {
+				continue
+			}
+		}
+
+		entries[entry.tag] = entry
+	}
+
+	return entries, nil
+}
+
+func (t *tiffReader) addTags(values Values, entries map[uint16]tiffEntry, names map[uint16]string) {
+	for tag, name := range names {
+		entry, ok := entries[tag]
+		if !ok {
+			continue
+		}
+		values.Add(name, t.format(name, entry))
+	}
+}
+
+func (t *tiffReader) addGPS(values Values, entries map[uint16]tiffEntry) {
+	coordinate := func(refTag, valueTag uint16, negative string) string {
+		ref, okRef := entries[refTag]
+		value, okValue := entries[valueTag]
+		if !okRef || !okValue || value.count < 3 {
+			return ""
+		}
+
+		degrees := t.rational(value, 0) + t.rational(value, 1)/60 + t.rational(value, 2)/3600
+		if strings.HasPrefix(t.ascii(ref), negative) {
+			degrees = -degrees
+		}
+		return strconv.FormatFloat(degrees, 'f', 6, 64)
+	}
+
+	values.Add("GPSLatitude", coordinate(0x0001, 0x0002, "S"))
+	values.Add("GPSLongitude", coordinate(0x0003, 0x0004, "W"))
+
+	if altitude, ok := entries[0x0006]; ok {
+		meters := t.rational(altitude, 0)
+		if ref, ok := entries[0x0005]; ok && len(ref.data) > 0 && ref.data[0] == 1 {
+			meters = -meters
+		}
+		values.Add("GPSAltitude", strconv.FormatFloat(meters, 'f', 1, 64))
+	}
+}
+
+func (t *tiffReader) format(name string, entry tiffEntry) string {
+	switch entry.typ {
+	case 2:
+		return t.ascii(entry)
+	case 3, 4:
+		return strconv.FormatUint(uint64(t.uint(entry, 0)), 10)
+	case 5, 10:
+		if name == "ExposureTime" {
+			num, den := t.fraction(entry, 0)
+			if num == 1 || den == 0 {
+				return fmt.Sprintf("%d/%d", num, den)
+			}
+		}
+		return strconv.FormatFloat(t.rational(entry, 0), 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func (t *tiffReader) ascii(entry tiffEntry) string {
+	return strings.TrimSpace(strings.TrimRight(string(entry.data), "\x00"))
+}
+
+func (t *tiffReader) uint(entry tiffEntry, i int) uint32 {
+	switch entry.typ {
+	case 3:
+		if len(entry.data) >= 2*i+2 {
+			return uint32(t.order.Uint16(entry.data[2*i:]))
+		}
+	case 4, 9:
+		if len(entry.data) >= 4*i+4 {
+			return t.order.Uint32(entry.data[4*i:])
+		}
+	}
+	return 0
+}
+
+func (t *tiffReader) fraction(entry tiffEntry, i int) (int64, int64) {
+	if len(entry.data) < 8*i+8 {
+		return 0, 0
+	}
+
+	num := t.order.Uint32(entry.data[8*i:])
+	den := t.order.Uint32(entry.data[8*i+4:])
+	if entry.typ == 10 {
+		return int64(int32(num)), int64(int32(den))
+	}
+	return int64(num), int64(den)
+}
+
+func (t *tiffReader) rational(entry tiffEntry, i int) float64 {
+	num, den := t.fraction(entry, i)
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	default:
+		return 0
+	}
+}
+
+// normalizeExifTimes converts EXIF date and time values to RFC 3339, using
+// OffsetTimeOriginal for the time zone when present
+func normalizeExifTimes(values Values) {
+	location := time.UTC
+	if offset := values.Get("OffsetTimeOriginal"); offset != "" {
+		if t, err := time.Parse("-07:00", offset); err == nil {
+			location = t.Location()
+		}
+	}
+
+	for _, name := range []string{"DateTime", "DateTimeOriginal"} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.ParseInLocation(ExifTimeLayout, value, location)
+		if err != nil {
+			delete(values, name)
+			continue
+		}
+		values[name] = []string{t.Format(time.RFC3339)}
+	}
+}
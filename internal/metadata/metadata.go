@@ -0,0 +1,42 @@
+package metadata
+
+import "sort"
+
+// Values holds metadata field values keyed by field name. A field can carry
+// several values, e.g. keywords.
+type Values map[string][]string
+
+// Add appends values to the given field, ignoring empty strings
+func (v Values) Add(field string, values ...string) {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		v[field] = append(v[field], value)
+	}
+}
+
+// Get returns the first value of the given field
+func (v Values) Get(field string) string {
+	if len(v[field]) == 0 {
+		return ""
+	}
+	return v[field][0]
+}
+
+// Merge copies all fields from other, replacing existing values
+func (v Values) Merge(other Values) {
+	for field, values := range other {
+		v[field] = values
+	}
+}
+
+// Fields returns the field names in a stable order
+func (v Values) Fields() []string {
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
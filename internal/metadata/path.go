@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PathField describes how a metadata field is produced from a path match.
+// Value may reference named groups as $name or ${name}; when it is empty the
+// value of Group is used.
+type PathField struct {
+	Name      string
+	Group     string
+	Value     string
+	Transform string
+	Layout    string
+}
+
+// PathRule extracts metadata fields from a relative path using a regular
+// expression with named groups
+type PathRule struct {
+	Pattern *regexp.Regexp
+	Fields  []PathField
+}
+
+func NewPathRule(pattern string, fields []PathField) (*PathRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path rule pattern %q: %w", pattern, err)
+	}
+
+	for _, field := range fields {
+		if field.Group != "" && re.SubexpIndex(field.Group) < 0 {
+			return nil, fmt.Errorf("path rule %q has no group %q", pattern, field.Group)
+		}
+	}
+
+	return &PathRule{Pattern: re, Fields: fields}, nil
+}
+
+// Extract returns the field values for the path and whether the rule matched
+func (r *PathRule) Extract(path string) (Values, bool, error) {
+	match := r.Pattern.FindStringSubmatchIndex(path)
+	if match == nil {
+		return nil, false, nil
+	}
+
+	values := Values{}
+
+	for _, field := range r.Fields {
+		var value string
+		if field.Value != "" {
+			value = string(r.Pattern.ExpandString(nil, field.Value, path, match))
+		} else if field.Group != "" {
+			i := r.Pattern.SubexpIndex(field.Group)
+			if match[2*i] >= 0 {
+				value = path[match[2*i]:match[2*i+1]]
+			}
+		}
+
+		value, err := transform(value, field.Transform, field.Layout)
+		if err != nil {
+			return nil, true, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		values.Add(field.Name, value)
+	}
+
+	return values, true, nil
+}
+
+func transform(value, name, layout string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	switch name {
+	case "":
+		return value, nil
+	case "upper":
+		return strings.ToUpper(value), nil
+	case "lower":
+		return strings.ToLower(value), nil
+	case "int":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %q as integer: %w", value, err)
+		}
+		return strconv.Itoa(n), nil
+	case "date":
+		if layout == "" {
+			layout = time.DateOnly
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %q as date: %w", value, err)
+		}
+		return t.Format(time.RFC3339), nil
+	default:
+		return "", fmt.Errorf("unknown transform %q", name)
+	}
+}
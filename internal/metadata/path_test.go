@@ -0,0 +1,43 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathRule_Extract(t *testing.T) {
+	rule, err := NewPathRule(
+		`^(?P<show>[^/]+)/S(?P<season>\d+)/(?P<date>\d{8})/`,
+		[]PathField{
+			{Name: "Show", Group: "show", Transform: "lower"},
+			{Name: "Season", Group: "season", Transform: "int"},
+			{Name: "ShootDate", Group: "date", Transform: "date", Layout: "20060102"},
+			{Name: "Label", Value: "$show season $season"},
+		},
+	)
+	assert.NoError(t, err)
+
+	values, matched, err := rule.Extract("SHOW/S02/20240501/clip.mov")
+	assert.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, "show", values.Get("Show"))
+	assert.Equal(t, "2", values.Get("Season"))
+	assert.Equal(t, "2024-05-01T00:00:00Z", values.Get("ShootDate"))
+	assert.Equal(t, "SHOW season 02", values.Get("Label"))
+
+	_, matched, err = rule.Extract("other/clip.mov")
+	assert.NoError(t, err)
+	assert.False(t, matched)
+
+	_, _, err = rule.Extract("SHOW/S02/20241399/clip.mov")
+	assert.Error(t, err)
+}
+
+func TestNewPathRule_Invalid(t *testing.T) {
+	_, err := NewPathRule(`(?P<show`, nil)
+	assert.Error(t, err)
+
+	_, err = NewPathRule(`(?P<show>\w+)`, []PathField{{Name: "Season", Group: "season"}})
+	assert.Error(t, err)
+}
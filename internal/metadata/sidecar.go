@@ -0,0 +1,255 @@
+package metadata
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// sidecarDirs is the number of directories whose listing is kept for {stem}
+// patterns, the files of a directory are checked one after another
+const sidecarDirs = 64
+
+// Sidecar finds metadata files that live next to media files. A pattern is a
+// file name template where {name} is replaced by the full media file name and
+// {stem} by the media file name without its extension, e.g. "{name}.json"
+// matches clip.mov.json and "{stem}.xml" matches clip.xml.
+type Sidecar struct {
+	Patterns []string
+
+	// dirs are the stems of the files of the last listed directories
+	mu    sync.Mutex
+	dirs  map[string]*dirStems
+	order []string
+}
+
+// dirStems counts the files of a directory by stem as of its modification
+// time
+type dirStems struct {
+	modTime time.Time
+	stems   map[string]int
+}
+
+func NewSidecar(patterns []string) *Sidecar {
+	return &Sidecar{Patterns: patterns}
+}
+
+// Find returns the path of the first existing sidecar of the given media file
+func (s *Sidecar) Find(path string) (string, bool) {
+	dir, name := filepath.Split(path)
+
+	for _, pattern := range s.Patterns {
+		sidecarName := strings.NewReplacer("{name}", name, "{stem}", stem(name)).Replace(pattern)
+		if sidecarName == name {
+			continue
+		}
+
+		sidecarPath := filepath.Join(dir, sidecarName)
+		if info, err := os.Stat(sidecarPath); err == nil && info.Mode().IsRegular() {
+			return sidecarPath, true
+		}
+	}
+
+	return "", false
+}
+
+// IsSidecar reports whether the given file is a sidecar of another file in
+// the same directory
+func (s *Sidecar) IsSidecar(path string) bool {
+	dir, name := filepath.Split(path)
+
+	for _, pattern := range s.Patterns {
+		placeholder := "{name}"
+		if !strings.Contains(pattern, placeholder) {
+			placeholder = "{stem}"
+		}
+
+		prefix, suffix, found := strings.Cut(pattern, placeholder)
+		if !found || len(name) <= len(prefix)+len(suffix) {
+			continue
+		}
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		media := name[len(prefix) : len(name)-len(suffix)]
+
+		if placeholder == "{name}" {
+			if info, err := os.Stat(filepath.Join(dir, media)); err == nil && !info.IsDir() {
+				return true
+			}
+			continue
+		}
+
+		count := s.stems(filepath.Clean(dir))[media]
+		if stem(name) == media {
+			// the sidecar itself is not its media file
+			count--
+		}
+		if count > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stems returns the number of files by stem in the directory. The directory is
+// only listed again once it was modified, so checking all its files lists it
+// once.
+func (s *Sidecar) stems(dir string) map[string]int {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	cached, ok := s.dirs[dir]
+	s.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.stems
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	stems := map[string]int{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			stems[stem(entry.Name())]++
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dirs == nil {
+		s.dirs = map[string]*dirStems{}
+	}
+	if _, ok := s.dirs[dir]; !ok {
+		s.order = append(s.order, dir)
+		if len(s.order) > sidecarDirs {
+			delete(s.dirs, s.order[0])
+			s.order = s.order[1:]
+		}
+	}
+	s.dirs[dir] = &dirStems{modTime: info.ModTime(), stems: stems}
+
+	return stems
+}
+
+func stem(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// ParseSidecar reads a JSON, YAML or XML sidecar and flattens it into values
+// keyed by dotted paths, e.g. {"camera": {"model": "A"}} becomes camera.model.
+// XML keys are relative to the root element and attributes are keyed as
+// element@attribute.
+func ParseSidecar(path string) (Values, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sidecar: %w", err)
+	}
+	defer f.Close()
+
+	values := Values{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var data interface{}
+		if err := json.NewDecoder(f).Decode(&data); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON sidecar: %w", err)
+		}
+		flatten(values, "", data)
+	case ".yaml", ".yml":
+		var data interface{}
+		if err := yaml.NewDecoder(f).Decode(&data); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to parse YAML sidecar: %w", err)
+		}
+		flatten(values, "", data)
+	case ".xml":
+		if err := flattenXML(values, f); err != nil {
+			return nil, fmt.Errorf("failed to parse XML sidecar: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sidecar format: %s", path)
+	}
+
+	return values, nil
+}
+
+func flatten(values Values, key string, data interface{}) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			flatten(values, joinKey(key, k), child)
+		}
+	case map[interface{}]interface{}:
+		for k, child := range v {
+			flatten(values, joinKey(key, fmt.Sprint(k)), child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			flatten(values, key, child)
+		}
+	case nil:
+	default:
+		if key != "" {
+			values.Add(key, fmt.Sprint(v))
+		}
+	}
+}
+
+func flattenXML(values Values, r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+
+	var path []string
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+			text.Reset()
+
+			key := strings.Join(path[1:], ".")
+			for _, attr := range t.Attr {
+				values.Add(key+"@"+attr.Name.Local, attr.Value)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(path) > 1 {
+				values.Add(strings.Join(path[1:], "."), strings.TrimSpace(text.String()))
+			}
+			text.Reset()
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
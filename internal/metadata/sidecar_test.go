@@ -0,0 +1,106 @@
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSidecar_FindAndIsSidecar(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"clip.mov", "clip.mov.json", "shot.mxf", "shot.xml", "notes.txt"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644)
+		assert.NoError(t, err)
+	}
+
+	sidecar := NewSidecar([]string{"{name}.json", "{stem}.xml"})
+
+	path, ok := sidecar.Find(filepath.Join(dir, "clip.mov"))
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "clip.mov.json"), path)
+
+	path, ok = sidecar.Find(filepath.Join(dir, "shot.mxf"))
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "shot.xml"), path)
+
+	_, ok = sidecar.Find(filepath.Join(dir, "notes.txt"))
+	assert.False(t, ok)
+
+	assert.True(t, sidecar.IsSidecar(filepath.Join(dir, "clip.mov.json")))
+	assert.True(t, sidecar.IsSidecar(filepath.Join(dir, "shot.xml")))
+	assert.False(t, sidecar.IsSidecar(filepath.Join(dir, "clip.mov")))
+	assert.False(t, sidecar.IsSidecar(filepath.Join(dir, "notes.txt")))
+}
+
+func TestSidecar_IsSidecarListing(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "shot.xml"), []byte("<a/>"), 0644))
+
+	sidecar := NewSidecar([]string{"{stem}.xml"})
+	assert.False(t, sidecar.IsSidecar(filepath.Join(dir, "shot.xml")))
+
+	// the listing is kept until the directory changes
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "shot.mxf"), []byte("video"), 0644))
+	modified := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(dir, modified, modified))
+	assert.True(t, sidecar.IsSidecar(filepath.Join(dir, "shot.xml")))
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "shot.mxf")))
+	modified = modified.Add(time.Minute)
+	assert.NoError(t, os.Chtimes(dir, modified, modified))
+	assert.False(t, sidecar.IsSidecar(filepath.Join(dir, "shot.xml")))
+
+	// only the last listed directories are kept
+	for i := 0; i < sidecarDirs+1; i++ {
+		other := filepath.Join(dir, fmt.Sprintf("dir%d", i))
+		assert.NoError(t, os.Mkdir(other, 0755))
+		assert.False(t, sidecar.IsSidecar(filepath.Join(other, "shot.xml")))
+	}
+	assert.Len(t, sidecar.dirs, sidecarDirs)
+	assert.Len(t, sidecar.order, sidecarDirs)
+}
+
+func TestParseSidecar(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "clip.json",
+			content: `{"scene": 12, "camera": {"model": "ALEXA"}, "keywords": ["beach", "sunset"]}`,
+		},
+		{
+			name:    "clip.yaml",
+			content: "scene: 12\ncamera:\n  model: ALEXA\nkeywords:\n  - beach\n  - sunset\n",
+		},
+		{
+			name: "clip.xml",
+			content: `<clip><scene>12</scene><camera id="A"><model>ALEXA</model></camera>` +
+				`<keywords>beach</keywords><keywords>sunset</keywords></clip>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			err := os.WriteFile(path, []byte(tt.content), 0644)
+			assert.NoError(t, err)
+
+			values, err := ParseSidecar(path)
+			assert.NoError(t, err)
+			assert.Equal(t, "12", values.Get("scene"))
+			assert.Equal(t, "ALEXA", values.Get("camera.model"))
+			assert.Equal(t, []string{"beach", "sunset"}, values["keywords"])
+		})
+	}
+
+	_, err := ParseSidecar(filepath.Join(dir, "clip.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+var xmpPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":            "dc",
+	"http://ns.adobe.com/xap/1.0/":                "xmp",
+	"http://ns.adobe.com/photoshop/1.0/":          "photoshop",
+	"http://ns.adobe.com/exif/1.0/":               "exif",
+	"http://ns.adobe.com/exif/1.0/aux/":           "aux",
+	"http://ns.adobe.com/tiff/1.0/":               "tiff",
+	"http://ns.adobe.com/lightroom/1.0/":          "lr",
+	"http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/": "Iptc4xmpCore",
+}
+
+// parseXMP reads the properties of an XMP packet. Properties are keyed by
+// prefix:name, e.g. dc:subject, and both element and attribute forms are
+// supported. Array items become separate values.
+func parseXMP(data []byte, values Values) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var stack []string
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			text.Reset()
+
+			name := xmpName(t.Name)
+			if t.Name.Space == rdfNamespace {
+				name = ""
+			}
+			stack = append(stack, name)
+
+			for _, attr := range t.Attr {
+				if key := xmpName(attr.Name); key != "" {
+					values.Add(key, strings.TrimSpace(attr.Value))
+				}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+
+			// array items (rdf:li) belong to the closest property element
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] != "" {
+					values.Add(stack[i], strings.TrimSpace(text.String()))
+					break
+				}
+			}
+
+			text.Reset()
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+func xmpName(name xml.Name) string {
+	prefix, ok := xmpPrefixes[name.Space]
+	if !ok {
+		return ""
+	}
+	return prefix + ":" + name.Local
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "synconik"
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	WorkerBusy = "busy"
+	WorkerIdle = "idle"
+)
+
+var idPattern = regexp.MustCompile(
+	`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+)
+
+var (
+	FilesDiscovered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "files_discovered_total",
+		Help:      "Number of files found by the scanner.",
+	})
+
+	ScanDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "scanner",
+		Name:      "scan_duration_seconds",
+		Help:      "Duration of a full scan of the directory.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	})
+
+	JobsQueued = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "uploader",
+		Name:      "jobs_queued_total",
+		Help:      "Number of upload jobs queued by the scanner.",
+	})
+
+	Workers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "uploader",
+		Name:      "workers",
+		Help:      "Number of upload workers by state.",
+	}, []string{"state"})
+
+	Uploads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "uploader",
+		Name:      "uploads_total",
+		Help:      "Number of file uploads by storage method and result.",
+	}, []string{"method", "result"})
+
+	UploadedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "uploader",
+		Name:      "uploaded_bytes_total",
+		Help:      "Number of bytes uploaded by storage method.",
+	}, []string{"method"})
+
+	UploadBytesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "uploader",
+		Name:      "bytes_sent_total",
+		Help:      "Number of bytes sent to the storage while uploading, including failed attempts.",
+	}, []string{"method"})
+
+	UploadsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "uploader",
+		Name:      "uploads_in_flight",
+		Help:      "Number of uploads in progress.",
+	})
+
+	UploadBytesRemaining = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "uploader",
+		Name:      "bytes_remaining",
+		Help:      "Number of bytes left to send for the uploads in progress.",
+	})
+
+	IconikRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "iconik",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of Iconik API requests by method, endpoint and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "endpoint", "code"})
+)
+
+// Handler serves the registered metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveIconikRequest records the latency of an Iconik API request. A status
+// code of 0 means the request failed before a response was received.
+func ObserveIconikRequest(method, path string, code int, duration time.Duration) {
+	status := "error"
+	if code > 0 {
+		status = strconv.Itoa(code)
+	}
+
+	IconikRequestDuration.WithLabelValues(method, Endpoint(path), status).Observe(duration.Seconds())
+}
+
+// ObserveUpload records the outcome of a file upload
+func ObserveUpload(method string, size int64, err error) {
+	if err != nil {
+		Uploads.WithLabelValues(method, ResultFailure).Inc()
+		return
+	}
+
+	Uploads.WithLabelValues(method, ResultSuccess).Inc()
+	UploadedBytes.WithLabelValues(method).Add(float64(size))
+}
+
+// Endpoint replaces the IDs in an API path so that requests to the same
+// endpoint share a label value
+func Endpoint(path string) string {
+	return idPattern.ReplaceAllString(path, ":id")
+}
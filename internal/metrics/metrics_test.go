@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpoint(t *testing.T) {
+	assert.Equal(
+		t,
+		"/API/files/v1/assets/:id/files/:id/",
+		Endpoint("/API/files/v1/assets/47265105-BE2B-4C3F-8997-66BAB2893D0D/files/d025605f-cf64-4ee5-9f48-e6dd5d363473/"),
+	)
+	assert.Equal(t, "/API/assets/v1/assets/", Endpoint("/API/assets/v1/assets/"))
+}
+
+func TestObserveUpload(t *testing.T) {
+	ObserveUpload("S3", 1024, nil)
+	ObserveUpload("S3", 512, nil)
+	ObserveUpload("S3", 2048, errors.New("connection reset"))
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(Uploads.WithLabelValues("S3", ResultSuccess)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(Uploads.WithLabelValues("S3", ResultFailure)))
+	assert.Equal(t, float64(1536), testutil.ToFloat64(UploadedBytes.WithLabelValues("S3")))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveIconikRequest("POST", "/API/assets/v1/assets/", 201, 120*time.Millisecond)
+	ObserveIconikRequest("GET", "/API/jobs/v1/jobs/47265105-BE2B-4C3F-8997-66BAB2893D0D/", 0, time.Second)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+	assert.True(t, strings.Contains(
+		body,
+		`synconik_iconik_request_duration_seconds_count{code="201",endpoint="/API/assets/v1/assets/",method="POST"} 1`,
+	))
+	assert.True(t, strings.Contains(
+		body,
+		`synconik_iconik_request_duration_seconds_count{code="error",endpoint="/API/jobs/v1/jobs/:id/",method="GET"} 1`,
+	))
+}
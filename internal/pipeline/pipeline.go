@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/scanner"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/rs/zerolog/log"
+)
+
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type FailedFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Summary is the outcome of a single pass over the scanned directory
+type Summary struct {
+	Uploaded      int           `json:"uploaded"`
+	UploadedBytes int64         `json:"uploaded_bytes"`
+	Skipped       []SkippedFile `json:"skipped"`
+	Failed        []FailedFile  `json:"failed"`
+	Duration      float64       `json:"duration_seconds"`
+}
+
+// RunOnce scans the configured directory once and waits until every queued
+// file was processed by the uploader. Files that failed in an earlier run are
+// uploaded again unless readOnly is set. With readOnly nothing is written into
+// the scanned directory and files past their retention period are not
+// deleted.
+func RunOnce(
+	config *config.Config, store store.Store, client icnk_client.Client, readOnly bool,
+) (*Summary, error) {
+	start := time.Now()
+
+	uploadJobQueue := make(chan uploader.Job)
+
+	uploader := uploader.NewUploader(config, store, client, uploadJobQueue)
+	if err := uploader.Start(); err != nil {
+		return nil, err
+	}
+	defer uploader.Stop()
+
+	scanner, err := scanner.NewScanner(config, store, client, uploadJobQueue)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner.ReadOnly = readOnly
+	// a one-shot sync reports every file it could not upload, so files that
+	// failed before are tried again rather than skipped. A dry run shows what
+	// the daemon would do, which skips them.
+	scanner.RetryFailed = !readOnly
+
+	var mu sync.Mutex
+	summary := &Summary{Skipped: []SkippedFile{}, Failed: []FailedFile{}}
+
+	scanner.OnSkip = func(relativePath, reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		summary.Skipped = append(summary.Skipped, SkippedFile{Path: relativePath, Reason: reason})
+	}
+	scanner.OnDone = func(relativePath string, info os.FileInfo, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			summary.Failed = append(summary.Failed, FailedFile{Path: relativePath, Error: err.Error()})
+			return
+		}
+		summary.Uploaded++
+		summary.UploadedBytes += info.Size()
+	}
+
+	scanner.Scan()
+	// waits for the queued uploads
+	scanner.Stop()
+
+	if !readOnly {
+		err := usecase.NewPostUploadUseCase(config, client, store).DeleteExpired(time.Now())
+		if err != nil {
+			log.Error().Err(err).Str("service", "pipeline").Msg("Error deleting expired files")
+		}
+	}
+
+	sort.Slice(summary.Skipped, func(i, j int) bool {
+		return summary.Skipped[i].Path < summary.Skipped[j].Path
+	})
+	sort.Slice(summary.Failed, func(i, j int) bool {
+		return summary.Failed[i].Path < summary.Failed[j].Path
+	})
+	summary.Duration = time.Since(start).Seconds()
+
+	return summary, nil
+}
@@ -0,0 +1,99 @@
+package pipeline_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/dryrun"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/pipeline"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTree(t *testing.T) (*config.Config, store.Store) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	files := map[string]string{
+		"rushes/clip.mov":   "video",
+		"rushes/synced.mov": "video",
+		"stills/photo.jpg":  "photo!",
+	}
+	for path, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte(content), 0644))
+	}
+
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	t.Cleanup(func() { badgerStore.Close() })
+
+	assert.NoError(t, badgerStore.SaveFile(
+		"rushes/synced.mov", &entity.File{Name: "synced.mov", AssetID: "A1", ID: "F1"},
+	))
+
+	cfg := &config.Config{
+		Scanner:  config.ScannerConfig{Dir: dir, Interval: 10},
+		Uploader: config.UploaderConfig{Workers: 2},
+		Iconik:   config.Iconik{StorageID: "S1"},
+	}
+
+	return cfg, badgerStore
+}
+
+func TestRunOnce(t *testing.T) {
+	cfg, badgerStore := setupTree(t)
+
+	client := dryrun.NewClient(&icnk_client.Storage{ID: "S1", Method: "S3"})
+
+	summary, err := pipeline.RunOnce(cfg, badgerStore, client, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, summary.Uploaded)
+	assert.Equal(t, int64(11), summary.UploadedBytes)
+	assert.Equal(t, []pipeline.SkippedFile{{Path: "rushes/synced.mov", Reason: "synced"}}, summary.Skipped)
+	assert.Empty(t, summary.Failed)
+
+	file, err := badgerStore.GetFile("stills/photo.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+}
+
+func TestRunOnceFailed(t *testing.T) {
+	cfg, badgerStore := setupTree(t)
+
+	client := dryrun.NewClient(&icnk_client.Storage{ID: "S1", Method: "FTP"})
+
+	summary, err := pipeline.RunOnce(cfg, badgerStore, client, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, summary.Uploaded)
+	assert.Len(t, summary.Failed, 2)
+	assert.Equal(t, "rushes/clip.mov", summary.Failed[0].Path)
+	assert.Equal(t, "stills/photo.jpg", summary.Failed[1].Path)
+	assert.NotEmpty(t, summary.Failed[0].Error)
+}
+
+func TestRunOnceRetriesFailed(t *testing.T) {
+	cfg, badgerStore := setupTree(t)
+
+	failed := &entity.File{Name: "clip.mov"}
+	failed.SetState(entity.StateFailed, errors.New("request failed: forbidden"))
+	assert.NoError(t, badgerStore.SaveFile("rushes/clip.mov", failed))
+
+	client := dryrun.NewClient(&icnk_client.Storage{ID: "S1", Method: "S3"})
+
+	summary, err := pipeline.RunOnce(cfg, badgerStore, client, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, summary.Uploaded)
+	assert.Equal(t, []pipeline.SkippedFile{{Path: "rushes/synced.mov", Reason: "synced"}}, summary.Skipped)
+
+	file, err := badgerStore.GetFile("rushes/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "", file.Error)
+}
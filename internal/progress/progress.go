@@ -0,0 +1,170 @@
+package progress
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/metrics"
+)
+
+// Default is the tracker used by the storage backends
+var Default = NewTracker()
+
+// Transfer is the progress of a single upload
+type Transfer struct {
+	Method    string
+	Path      string
+	Size      int64
+	StartedAt time.Time
+
+	sent         atomic.Int64
+	lastProgress atomic.Int64
+}
+
+// Snapshot is the state of a transfer at a point in time
+type Snapshot struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Sent      int64     `json:"sent"`
+	Percent   float64   `json:"percent"`
+	Rate      float64   `json:"rate"`
+	ETA       float64   `json:"eta_seconds"`
+	Stalled   float64   `json:"stalled_seconds"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Tracker keeps the transfers in progress
+type Tracker struct {
+	mu        sync.Mutex
+	transfers map[*Transfer]struct{}
+	now       func() time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		transfers: map[*Transfer]struct{}{},
+		now:       time.Now,
+	}
+}
+
+// Start registers a transfer, it must be finished with Finish
+func (t *Tracker) Start(method, path string, size int64) *Transfer {
+	now := t.now()
+	transfer := &Transfer{Method: method, Path: path, Size: size, StartedAt: now}
+	transfer.lastProgress.Store(now.UnixNano())
+
+	t.mu.Lock()
+	t.transfers[transfer] = struct{}{}
+	t.mu.Unlock()
+
+	metrics.UploadsInFlight.Inc()
+	metrics.UploadBytesRemaining.Add(float64(size))
+
+	return transfer
+}
+
+// Finish removes a transfer whether it succeeded or not
+func (t *Tracker) Finish(transfer *Transfer) {
+	t.mu.Lock()
+	_, ok := t.transfers[transfer]
+	delete(t.transfers, transfer)
+	t.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	metrics.UploadsInFlight.Dec()
+	metrics.UploadBytesRemaining.Sub(float64(transfer.remaining()))
+}
+
+// Reader counts the bytes read from r as sent by the transfer
+func (t *Tracker) Reader(transfer *Transfer, r io.Reader) io.Reader {
+	return &reader{r: r, tracker: t, transfer: transfer}
+}
+
+// Snapshot returns the transfers in progress sorted by path
+func (t *Tracker) Snapshot() []Snapshot {
+	now := t.now()
+
+	t.mu.Lock()
+	snapshots := make([]Snapshot, 0, len(t.transfers))
+	for transfer := range t.transfers {
+		snapshots = append(snapshots, transfer.snapshot(now))
+	}
+	t.mu.Unlock()
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Path < snapshots[j].Path
+	})
+
+	return snapshots
+}
+
+func (t *Tracker) add(transfer *Transfer, n int) {
+	if n <= 0 {
+		return
+	}
+
+	before := transfer.remaining()
+	transfer.sent.Add(int64(n))
+	transfer.lastProgress.Store(t.now().UnixNano())
+
+	metrics.UploadBytesSent.WithLabelValues(transfer.Method).Add(float64(n))
+	metrics.UploadBytesRemaining.Sub(float64(before - transfer.remaining()))
+}
+
+// Sent returns the number of bytes sent so far
+func (tr *Transfer) Sent() int64 {
+	return tr.sent.Load()
+}
+
+func (tr *Transfer) remaining() int64 {
+	remaining := tr.Size - tr.sent.Load()
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func (tr *Transfer) snapshot(now time.Time) Snapshot {
+	sent := tr.sent.Load()
+	elapsed := now.Sub(tr.StartedAt).Seconds()
+
+	snapshot := Snapshot{
+		Method:    tr.Method,
+		Path:      tr.Path,
+		Size:      tr.Size,
+		Sent:      sent,
+		StartedAt: tr.StartedAt,
+		Stalled:   now.Sub(time.Unix(0, tr.lastProgress.Load())).Seconds(),
+	}
+
+	if tr.Size > 0 {
+		snapshot.Percent = float64(sent) / float64(tr.Size) * 100
+	}
+	if elapsed > 0 {
+		snapshot.Rate = float64(sent) / elapsed
+	}
+	if snapshot.Rate > 0 {
+		snapshot.ETA = float64(tr.remaining()) / snapshot.Rate
+	}
+
+	return snapshot
+}
+
+type reader struct {
+	r        io.Reader
+	tracker  *Tracker
+	transfer *Transfer
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.tracker.add(r.transfer, n)
+	return n, err
+}
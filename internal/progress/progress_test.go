@@ -0,0 +1,65 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kgantsov/synconik/internal/metrics"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+
+	inFlight := testutil.ToFloat64(metrics.UploadsInFlight)
+	remaining := testutil.ToFloat64(metrics.UploadBytesRemaining)
+
+	transfer := tracker.Start("S3", "/data/clip.mov", 1000)
+	other := tracker.Start("S3", "/data/audio.wav", 10)
+
+	assert.Equal(t, inFlight+2, testutil.ToFloat64(metrics.UploadsInFlight))
+	assert.Equal(t, remaining+1010, testutil.ToFloat64(metrics.UploadBytesRemaining))
+
+	now = now.Add(10 * time.Second)
+	reader := tracker.Reader(transfer, bytes.NewReader(make([]byte, 250)))
+	n, err := io.Copy(io.Discard, reader)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), n)
+	assert.Equal(t, int64(250), transfer.Sent())
+
+	now = now.Add(5 * time.Second)
+	snapshots := tracker.Snapshot()
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, "/data/audio.wav", snapshots[0].Path)
+	assert.Equal(t, float64(15), snapshots[0].Stalled)
+	assert.Equal(t, float64(0), snapshots[0].ETA)
+
+	snapshot := snapshots[1]
+	assert.Equal(t, "/data/clip.mov", snapshot.Path)
+	assert.Equal(t, int64(250), snapshot.Sent)
+	assert.Equal(t, float64(25), snapshot.Percent)
+	assert.InDelta(t, 16.67, snapshot.Rate, 0.01)
+	assert.InDelta(t, 45, snapshot.ETA, 0.01)
+	assert.Equal(t, float64(5), snapshot.Stalled)
+
+	assert.Equal(t, remaining+760, testutil.ToFloat64(metrics.UploadBytesRemaining))
+
+	tracker.Finish(transfer)
+	tracker.Finish(other)
+	tracker.Finish(other)
+
+	assert.Empty(t, tracker.Snapshot())
+	assert.Equal(t, inFlight, testutil.ToFloat64(metrics.UploadsInFlight))
+	assert.Equal(t, remaining, testutil.ToFloat64(metrics.UploadBytesRemaining))
+}
+
+func TestFormatETA(t *testing.T) {
+	assert.Equal(t, "unknown", FormatETA(0))
+	assert.Equal(t, "1m30s", FormatETA(90.4))
+}
@@ -0,0 +1,89 @@
+package progress
+
+import (
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog/log"
+)
+
+// Reporter periodically logs the transfers in progress
+type Reporter struct {
+	tracker  *Tracker
+	interval time.Duration
+
+	done chan bool
+}
+
+func NewReporter(tracker *Tracker, interval time.Duration) *Reporter {
+	return &Reporter{
+		tracker:  tracker,
+		interval: interval,
+		done:     make(chan bool),
+	}
+}
+
+func (r *Reporter) start() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Report()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Start logs the progress every interval, a non positive interval disables
+// the reporter
+func (r *Reporter) Start() {
+	if r.interval <= 0 {
+		return
+	}
+
+	go r.start()
+}
+
+func (r *Reporter) Stop() {
+	if r.interval <= 0 {
+		return
+	}
+
+	close(r.done)
+}
+
+// Report logs the progress of every transfer once
+func (r *Reporter) Report() {
+	for _, snapshot := range r.tracker.Snapshot() {
+		event := log.Info().
+			Str("service", "progress").
+			Str("path", snapshot.Path).
+			Int64("sent", snapshot.Sent).
+			Int64("size", snapshot.Size)
+
+		if snapshot.Stalled >= r.interval.Seconds() {
+			event = event.Float64("stalled_seconds", snapshot.Stalled)
+		}
+
+		event.Msgf(
+			"Uploading %.1f%% (%s of %s) at %s/s, ETA %s",
+			snapshot.Percent,
+			humanize.Bytes(uint64(snapshot.Sent)),
+			humanize.Bytes(uint64(snapshot.Size)),
+			humanize.Bytes(uint64(snapshot.Rate)),
+			FormatETA(snapshot.ETA),
+		)
+	}
+}
+
+// FormatETA formats the remaining seconds of a transfer, unknown when the
+// rate is not known yet
+func FormatETA(seconds float64) string {
+	if seconds <= 0 {
+		return "unknown"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
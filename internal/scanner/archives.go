@@ -0,0 +1,11 @@
+package scanner
+
+import (
+	"github.com/kgantsov/synconik/internal/archive"
+)
+
+// isArchive reports whether the members of the file are uploaded rather than
+// the file itself
+func (s *Scanner) isArchive(relativePath string) bool {
+	return s.config.Scanner.Archives.Enabled && archive.IsArchive(relativePath)
+}
@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScanner_Archives(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	for _, path := range []string{"in/delivery.zip", "in/delivery.tar.gz", "in/clip.mov"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("data"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{
+			Dir:      dir,
+			Interval: 10,
+			Archives: config.ArchivesConfig{Enabled: true},
+		},
+	}
+
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	defer badgerStore.Close()
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateCollection", mock.Anything, mock.Anything).Return(&client.Collection{ID: "C1"}, nil)
+
+	uploadQueue := make(chan uploader.Job, 10)
+	scanner, err := NewScanner(cfg, badgerStore, mockClient, uploadQueue)
+	assert.NoError(t, err)
+
+	scanner.Scan()
+	assert.Len(t, uploadQueue, 3)
+
+	archives := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		job := <-uploadQueue
+		archives[job.Payload.Path] = job.Payload.Archive
+		job.Payload.WG.Done()
+	}
+	assert.Equal(t, map[string]bool{"in/delivery.zip": true, "in/delivery.tar.gz": true, "in/clip.mov": false}, archives)
+
+	assert.NoError(t, scanner.Enqueue("in/delivery.zip"))
+	job := <-uploadQueue
+	assert.True(t, job.Payload.Archive)
+	job.Payload.WG.Done()
+
+	// archives are uploaded as files when the mode is off
+	cfg.Scanner.Archives.Enabled = false
+	assert.NoError(t, scanner.Enqueue("in/delivery.zip"))
+	job = <-uploadQueue
+	assert.False(t, job.Payload.Archive)
+	job.Payload.WG.Done()
+
+	scanner.Stop()
+}
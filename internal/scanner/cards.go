@@ -0,0 +1,23 @@
+package scanner
+
+import (
+	"os"
+
+	"github.com/kgantsov/synconik/internal/card"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+// detectCard returns the camera card in a directory, or nil when it is not
+// the root of a card
+func (s *Scanner) detectCard(relativePath string, entries []os.DirEntry) *card.Card {
+	if !s.config.Scanner.Cards.Enabled {
+		return nil
+	}
+
+	dir := ""
+	if relativePath != "" {
+		dir = relativePath + "/"
+	}
+
+	return usecase.DetectCard(s.config, dir, entries)
+}
@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScanner_Cards(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	files := []string{
+		"cards/A001/LASTCLIP.TXT",
+		"cards/A001/CONTENTS/CLIP/0001AB.XML",
+		"cards/A001/CONTENTS/VIDEO/0001AB.MXF",
+		"cards/A001/CONTENTS/AUDIO/0001AB00.MXF",
+		"cards/A001/CONTENTS/CLIP/0002CD.XML",
+		"cards/A001/CONTENTS/VIDEO/0002CD.MXF",
+		"cards/notes.txt",
+	}
+	for _, path := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("clip"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{
+			Dir:      dir,
+			Interval: 10,
+			Cards:    config.CardsConfig{Enabled: true},
+		},
+	}
+
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	defer badgerStore.Close()
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateCollection", mock.Anything, mock.Anything).Return(&client.Collection{ID: "C1"}, nil)
+
+	uploadQueue := make(chan uploader.Job, 10)
+	scanner, err := NewScanner(cfg, badgerStore, mockClient, uploadQueue)
+	assert.NoError(t, err)
+
+	scanner.Scan()
+	assert.Len(t, uploadQueue, 3)
+
+	jobs := map[string]uploader.Payload{}
+	for i := 0; i < 3; i++ {
+		job := <-uploadQueue
+		jobs[job.Payload.Path] = job.Payload
+		job.Payload.WG.Done()
+	}
+
+	assert.Nil(t, jobs["cards/notes.txt"].Clip)
+
+	payload := jobs["cards/A001/CONTENTS/VIDEO/0001AB.MXF"]
+	assert.NotNil(t, payload.Clip)
+	assert.Equal(t, []string{"cards/A001/CONTENTS/AUDIO/0001AB00.MXF", "cards/A001/CONTENTS/CLIP/0001AB.XML"}, payload.Clip.Keys())
+	assert.Equal(t, int64(12), payload.Info.Size())
+	assert.NotNil(t, jobs["cards/A001/CONTENTS/VIDEO/0002CD.MXF"].Clip)
+
+	// the card is a collection, its folders are not
+	_, err = badgerStore.GetFile("cards/A001")
+	assert.NoError(t, err)
+	_, err = badgerStore.GetFile("cards/A001/CONTENTS")
+	assert.Equal(t, store.ErrFileNotFound, err)
+
+	// requeueing a clip detects its card again
+	assert.NoError(t, scanner.Enqueue("cards/A001/CONTENTS/VIDEO/0001AB.MXF"))
+	job := <-uploadQueue
+	assert.Len(t, job.Payload.Clip.Files, 3)
+	job.Payload.WG.Done()
+
+	scanner.Stop()
+}
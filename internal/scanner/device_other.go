@@ -0,0 +1,13 @@
+//go:build !unix
+
+package scanner
+
+import (
+	"os"
+)
+
+// deviceOf is not supported on this platform, so every directory is on the
+// filesystem of the scanned directory
+func deviceOf(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
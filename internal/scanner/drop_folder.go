@@ -0,0 +1,179 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/rs/zerolog/log"
+)
+
+// Result markers written into a delivery folder once it was processed, the
+// folder is not scanned again until they are removed
+const (
+	ResultMarkerOK     = ".synconik-ok"
+	ResultMarkerFailed = ".synconik-failed"
+)
+
+// delivery is a complete folder of the drop folder. Its uploads are counted so
+// that the result marker is written once the last of them is done.
+type delivery struct {
+	path string
+
+	mu       sync.Mutex
+	pending  int
+	uploaded int
+	errors   map[string]string
+	finish   func(d *delivery)
+}
+
+func newDelivery(path string, finish func(d *delivery)) *delivery {
+	// the walk holds a reference until all the files of the folder are queued
+	return &delivery{path: path, pending: 1, errors: map[string]string{}, finish: finish}
+}
+
+func (d *delivery) add() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending++
+}
+
+// done releases a reference with the result of an upload, or of the walk
+// when relativePath is empty
+func (d *delivery) done(relativePath string, err error) {
+	d.mu.Lock()
+	if relativePath != "" {
+		if err != nil {
+			d.errors[relativePath] = err.Error()
+		} else {
+			d.uploaded++
+		}
+	}
+	d.pending--
+	last := d.pending == 0
+	d.mu.Unlock()
+
+	if last {
+		d.finish(d)
+	}
+}
+
+// dropFolder reports whether only complete delivery folders are ingested
+func (s *Scanner) dropFolder() bool {
+	return len(s.config.Scanner.DropFolder.Markers) > 0
+}
+
+// isMarker reports whether the file is a completion or result marker
+func (s *Scanner) isMarker(name string) bool {
+	if name == ResultMarkerOK || name == ResultMarkerFailed {
+		return true
+	}
+
+	for _, marker := range s.config.Scanner.DropFolder.Markers {
+		if name == marker {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliveryOf returns the delivery folder of a path, the top level directory
+// below the scanned directory
+func deliveryOf(relativePath string) string {
+	i := strings.Index(relativePath, "/")
+	if i < 0 {
+		return ""
+	}
+	return relativePath[:i]
+}
+
+// startDelivery checks whether a top level folder is ready to be ingested and
+// returns its delivery, or an empty reason when the folder is skipped
+// silently
+func (s *Scanner) startDelivery(relativePath, path string) (*delivery, string) {
+	if hasFile(path, ResultMarkerOK, ResultMarkerFailed) {
+		return nil, ""
+	}
+	if !hasFile(path, s.config.Scanner.DropFolder.Markers...) {
+		return nil, usecase.SkipIncomplete
+	}
+
+	s.deliveriesMu.Lock()
+	defer s.deliveriesMu.Unlock()
+
+	// uploads queued by a previous scan are still running
+	if s.deliveries[relativePath] {
+		return nil, ""
+	}
+	s.deliveries[relativePath] = true
+
+	log.Info().Str("service", "scanner").Str("path", relativePath).Msg("Ingesting a complete delivery")
+
+	return newDelivery(relativePath, s.finishDelivery), ""
+}
+
+// finishDelivery writes the result marker of a delivery whose uploads are all
+// done
+func (s *Scanner) finishDelivery(d *delivery) {
+	defer func() {
+		s.deliveriesMu.Lock()
+		delete(s.deliveries, d.path)
+		s.deliveriesMu.Unlock()
+	}()
+
+	name, content := resultMarker(d)
+
+	log.Info().
+		Str("service", "scanner").
+		Str("path", d.path).
+		Msgf("Processed the delivery with %d uploads and %d errors", d.uploaded, len(d.errors))
+
+	if s.ReadOnly {
+		return
+	}
+
+	err := os.WriteFile(filepath.Join(s.config.Scanner.Dir+d.path, name), []byte(content), 0644)
+	if err != nil {
+		log.Error().Err(err).Str("service", "scanner").Msgf("Error writing the result marker of %s", d.path)
+	}
+}
+
+// resultMarker returns the name and the content of the result marker
+func resultMarker(d *delivery) (string, string) {
+	now := time.Now().Format(time.RFC3339)
+
+	if len(d.errors) == 0 {
+		return ResultMarkerOK, fmt.Sprintf("%s: uploaded %d files\n", now, d.uploaded)
+	}
+
+	paths := make([]string, 0, len(d.errors))
+	for path := range d.errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: uploaded %d files, %d failed\n", now, d.uploaded, len(d.errors))
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s: %s\n", path, d.errors[path])
+	}
+
+	return ResultMarkerFailed, b.String()
+}
+
+// hasFile reports whether any of the files exists in the directory
+func hasFile(dir string, names ...string) bool {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupDropFolder(t *testing.T) (*Scanner, string, *[]string) {
+	dir := filepath.Join(t.TempDir(), "drop") + "/"
+	files := []string{
+		"loose.mov",
+		"ready/.done",
+		"ready/clip.mov",
+		"ready/day1/bad.mov",
+		"partial/clip.mov",
+		"processed/READY",
+		"processed/clip.mov",
+		"processed/" + ResultMarkerOK,
+	}
+	for _, path := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("data"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{
+			Dir:        dir,
+			Interval:   10,
+			DropFolder: config.DropFolderConfig{Markers: []string{".done", "READY"}},
+		},
+	}
+
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	t.Cleanup(func() { badgerStore.Close() })
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateCollection", mock.Anything, mock.Anything).Return(&client.Collection{ID: "C1"}, nil)
+
+	uploadQueue := make(chan uploader.Job)
+	scanner, err := NewScanner(cfg, badgerStore, mockClient, uploadQueue)
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	uploaded := []string{}
+
+	go func() {
+		for job := range uploadQueue {
+			var err error
+			if strings.HasSuffix(job.Payload.Path, "bad.mov") {
+				err = errors.New("upload failed")
+			}
+
+			mu.Lock()
+			uploaded = append(uploaded, job.Payload.Path)
+			mu.Unlock()
+
+			job.Payload.OnDone(err)
+			job.Payload.WG.Done()
+		}
+	}()
+	t.Cleanup(func() { close(uploadQueue) })
+
+	return scanner, dir, &uploaded
+}
+
+func TestScanner_DropFolder(t *testing.T) {
+	scanner, dir, uploaded := setupDropFolder(t)
+
+	skipped := map[string]string{}
+	scanner.OnSkip = func(relativePath, reason string) {
+		skipped[relativePath] = reason
+	}
+
+	scanner.Scan()
+	scanner.Stop()
+
+	sort.Strings(*uploaded)
+	assert.Equal(t, []string{"ready/clip.mov", "ready/day1/bad.mov"}, *uploaded)
+	assert.Equal(t, map[string]string{
+		"loose.mov":   "incomplete",
+		"partial":     "incomplete",
+		"ready/.done": "marker",
+	}, skipped)
+
+	content, err := os.ReadFile(dir + "ready/" + ResultMarkerFailed)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "uploaded 1 files, 1 failed\n")
+	assert.Contains(t, string(content), "ready/day1/bad.mov: upload failed\n")
+
+	assertNoFile(t, dir+"ready/"+ResultMarkerOK)
+	assertNoFile(t, dir+"partial/"+ResultMarkerOK)
+	assertNoFile(t, dir+"partial/"+ResultMarkerFailed)
+}
+
+func TestScanner_DropFolderReadOnly(t *testing.T) {
+	scanner, dir, uploaded := setupDropFolder(t)
+	scanner.ReadOnly = true
+
+	scanner.Scan()
+	scanner.Stop()
+
+	assert.Len(t, *uploaded, 2)
+	assertNoFile(t, dir+"ready/"+ResultMarkerOK)
+	assertNoFile(t, dir+"ready/"+ResultMarkerFailed)
+}
+
+func TestResultMarker(t *testing.T) {
+	name, content := resultMarker(&delivery{path: "ready", uploaded: 3, errors: map[string]string{}})
+	assert.Equal(t, ResultMarkerOK, name)
+	assert.Contains(t, content, "uploaded 3 files\n")
+
+	name, content = resultMarker(&delivery{
+		path:     "ready",
+		uploaded: 1,
+		errors:   map[string]string{"ready/b.mov": "forbidden", "ready/a.mov": "timeout"},
+	})
+	assert.Equal(t, ResultMarkerFailed, name)
+	assert.True(t, strings.HasSuffix(content, "uploaded 1 files, 2 failed\nready/a.mov: timeout\nready/b.mov: forbidden\n"))
+}
+
+func assertNoFile(t *testing.T, path string) {
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "%s should not exist", path)
+}
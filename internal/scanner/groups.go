@@ -0,0 +1,28 @@
+package scanner
+
+import (
+	"os"
+
+	"github.com/kgantsov/synconik/internal/group"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+// detectGroups returns the groups of a directory by the store keys of their
+// primary and secondary files
+func (s *Scanner) detectGroups(relativePath string, entries []os.DirEntry) map[string]*group.Group {
+	groups := map[string]*group.Group{}
+
+	dir := ""
+	if relativePath != "" {
+		dir = relativePath + "/"
+	}
+
+	for _, g := range usecase.DetectGroups(s.config, dir, entries) {
+		groups[g.Key()] = g
+		for _, member := range g.Members {
+			groups[dir+member.Info.Name()] = g
+		}
+	}
+
+	return groups
+}
@@ -0,0 +1,79 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScanner_Groups(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	files := []string{"card/A001.mov", "card/A001.wav", "card/A002.wav"}
+	for _, path := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("media"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{
+			Dir:      dir,
+			Interval: 10,
+			Groups: []config.GroupRuleConfig{{
+				Primary:   []string{".mov"},
+				Secondary: []config.GroupFormatConfig{{Extension: ".wav", Format: "AUDIO"}},
+			}},
+		},
+	}
+
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	defer badgerStore.Close()
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateCollection", mock.Anything, mock.Anything).Return(&client.Collection{ID: "C1"}, nil)
+
+	uploadQueue := make(chan uploader.Job, 10)
+	scanner, err := NewScanner(cfg, badgerStore, mockClient, uploadQueue)
+	assert.NoError(t, err)
+
+	scanner.Scan()
+	assert.Len(t, uploadQueue, 2)
+
+	jobs := map[string]uploader.Payload{}
+	for i := 0; i < 2; i++ {
+		job := <-uploadQueue
+		jobs[job.Payload.Path] = job.Payload
+		job.Payload.WG.Done()
+	}
+
+	// a secondary file without its primary file is uploaded on its own
+	assert.Nil(t, jobs["card/A002.wav"].Group)
+
+	payload := jobs["card/A001.mov"]
+	assert.NotNil(t, payload.Group)
+	assert.Equal(t, []string{"A001.wav"}, payload.Group.Names())
+	assert.Equal(t, int64(10), payload.Info.Size())
+
+	file, err := badgerStore.GetFile("card/A001.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatePending, file.State)
+
+	_, err = badgerStore.GetFile("card/A001.wav")
+	assert.Equal(t, store.ErrFileNotFound, err)
+
+	// requeueing a group detects its files again
+	assert.NoError(t, scanner.Enqueue("card/A001.mov"))
+	job := <-uploadQueue
+	assert.Equal(t, []string{"A001.wav"}, job.Payload.Group.Names())
+	job.Payload.WG.Done()
+
+	scanner.Stop()
+}
@@ -2,14 +2,21 @@ package scanner
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/kgantsov/synconik/internal/card"
 	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/group"
 	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/metrics"
+	"github.com/kgantsov/synconik/internal/sequence"
 	"github.com/kgantsov/synconik/internal/store"
 	"github.com/kgantsov/synconik/internal/uploader"
 	"github.com/kgantsov/synconik/internal/usecase"
@@ -23,9 +30,27 @@ type Scanner struct {
 	store             store.Store
 	client            icnk_client.Client
 	collectionUseCase *usecase.CollectionUseCase
+	filterUseCase     *usecase.FilterUseCase
 
-	wg   *sync.WaitGroup
-	done chan bool
+	// OnSkip is called for every file that is found but not queued
+	OnSkip func(relativePath, reason string)
+	// OnDone is called with the result of every queued upload
+	OnDone func(relativePath string, info os.FileInfo, err error)
+	// ReadOnly leaves the scanned directory untouched, no result markers are
+	// written into delivery folders
+	ReadOnly bool
+	// RetryFailed queues files that failed in an earlier run instead of
+	// skipping them
+	RetryFailed bool
+
+	// deliveries are the delivery folders with uploads in progress
+	deliveries   map[string]bool
+	deliveriesMu sync.Mutex
+
+	wg      *sync.WaitGroup
+	done    chan bool
+	trigger chan struct{}
+	running atomic.Bool
 }
 
 func NewScanner(
@@ -48,20 +73,28 @@ func NewScanner(
 		store:  store,
 
 		collectionUseCase: usecase.NewCollectionUseCase(config, client, store),
+		filterUseCase:     usecase.NewFilterUseCase(config, client, store),
 
 		UploadJobQueue: uploadJobQueue,
 
-		wg:   &wg,
-		done: make(chan bool),
+		deliveries: map[string]bool{},
+
+		wg:      &wg,
+		done:    make(chan bool),
+		trigger: make(chan struct{}, 1),
 	}, nil
 }
 
 func (s *Scanner) start() {
+	defer s.running.Store(false)
+
 	ticker := time.NewTicker(time.Duration(s.config.Scanner.Interval) * time.Second)
 	for {
 		select {
 		case <-ticker.C:
 			s.Scan()
+		case <-s.trigger:
+			s.Scan()
 		case <-s.done:
 			log.Debug().Str("service", "scanner").Msg("Stopped the scanner")
 			return
@@ -73,6 +106,7 @@ func (s *Scanner) Start() {
 	// Start the scanner
 	log.Debug().Str("service", "scanner").Msg("Starting the scanner")
 
+	s.running.Store(true)
 	s.Scan()
 	go s.start()
 }
@@ -85,12 +119,224 @@ func (s *Scanner) Stop() {
 	close(s.done)
 }
 
+// Running reports whether the scan loop is running
+func (s *Scanner) Running() bool {
+	return s.running.Load()
+}
+
+// TriggerScan requests a scan without waiting for the next tick, it returns
+// false when a requested scan is already pending
+func (s *Scanner) TriggerScan() bool {
+	select {
+	case s.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Enqueue queues an upload job for a single file given by its path relative
+// to the scanned directory
+func (s *Scanner) Enqueue(relativePath string) error {
+	var seq *sequence.Sequence
+	var g *group.Group
+	var clip *card.Clip
+
+	info, err := os.Stat(usecase.LocalPath(s.config, relativePath))
+	if err != nil && s.config.Scanner.Sequences.Enabled {
+		if seq = usecase.FindSequence(s.config, relativePath); seq != nil {
+			info, err = seq.Info(), nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if g = usecase.FindGroup(s.config, relativePath); g != nil {
+		info = g.Info()
+	}
+	if clip = usecase.FindClip(s.config, relativePath); clip != nil {
+		info = clip.Info()
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", relativePath)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", relativePath)
+	}
+
+	s.markPending(relativePath, info)
+	s.wg.Add(1)
+
+	payload := s.payload(relativePath, info, nil)
+	payload.Sequence = seq
+	payload.Group = g
+	payload.Clip = clip
+	payload.Archive = s.isArchive(relativePath)
+
+	select {
+	case s.UploadJobQueue <- uploader.Job{
+		Payload: payload,
+	}:
+		metrics.JobsQueued.Inc()
+		return nil
+	case <-s.done:
+		s.wg.Done()
+		return errors.New("scanner is stopped")
+	}
+}
+
+// markPending records a file that is not in the store yet as pending
+func (s *Scanner) markPending(relativePath string, info os.FileInfo) {
+	_, err := s.store.GetFile(relativePath)
+	if !errors.Is(err, store.ErrFileNotFound) {
+		return
+	}
+
+	file := usecase.NewFileRecord(relativePath, info)
+	file.SetState(entity.StatePending, nil)
+
+	if err := s.store.SaveFile(relativePath, file); err != nil {
+		log.Error().Err(err).Str("service", "scanner").Msgf("Error saving pending file: %s", relativePath)
+	}
+}
+
+// markRetry marks a failed file as pending, so the uploader does not skip it.
+// The record keeps its asset, the file is uploaded as a new version of it.
+func (s *Scanner) markRetry(relativePath string) error {
+	return s.store.UpdateFile(relativePath, func(file *entity.File) error {
+		file.SetState(entity.StatePending, nil)
+		return nil
+	})
+}
+
+func (s *Scanner) payload(relativePath string, info os.FileInfo, d *delivery) uploader.Payload {
+	payload := uploader.Payload{Path: relativePath, Info: info, WG: s.wg}
+
+	if s.OnDone != nil || d != nil {
+		payload.OnDone = func(err error) {
+			if s.OnDone != nil {
+				s.OnDone(relativePath, info, err)
+			}
+			if d != nil {
+				d.done(relativePath, err)
+			}
+		}
+	}
+
+	return payload
+}
+
+func (s *Scanner) skip(relativePath, reason string) {
+	log.Debug().Str("service", "scanner").Str("path", relativePath).Msgf("Skipping %s file", reason)
+
+	if s.OnSkip != nil {
+		s.OnSkip(relativePath, reason)
+	}
+}
+
 func (s *Scanner) Scan() {
 	// Scan the folder
 	fileCount := 0
 	dirCount := 0
 
-	err := filepath.Walk(s.config.Scanner.Dir, func(path string, info os.FileInfo, err error) error {
+	start := time.Now()
+	defer func() {
+		metrics.ScanDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// mu guards the state shared by the goroutines of the walk
+	var mu sync.Mutex
+
+	// complete delivery folders in the drop folder mode
+	deliveries := map[string]*delivery{}
+	stopped := false
+	// image sequences by the keys of their frames
+	frames := map[string]*sequence.Sequence{}
+	// groups by the keys of their files
+	groups := map[string]*group.Group{}
+
+	// queue uploads a file found by the walk unless it is skipped, it stops
+	// the walk when the scanner is stopped. Synced files are uploaded again
+	// when they changed since their upload, which is only checked for files
+	// uploaded on their own.
+	queue := func(
+		relativePath string, info os.FileInfo, single bool, set func(payload *uploader.Payload),
+	) error {
+		var d *delivery
+		if s.dropFolder() {
+			if s.isMarker(info.Name()) {
+				s.skip(relativePath, usecase.SkipMarker)
+				return nil
+			}
+
+			// files outside of delivery folders are never complete
+			mu.Lock()
+			d = deliveries[deliveryOf(relativePath)]
+			mu.Unlock()
+			if d == nil {
+				s.skip(relativePath, usecase.SkipIncomplete)
+				return nil
+			}
+		}
+
+		var compared os.FileInfo
+		if single {
+			compared = info
+		}
+
+		reason, err := s.filterUseCase.Skip(relativePath, compared)
+		if err != nil {
+			log.Error().Err(err).Str("service", "scanner").Msgf("Error filtering file: %s", relativePath)
+			return nil
+		}
+
+		// removing the result marker of a delivery retries its failed files
+		if reason == usecase.SkipFailed && (d != nil || s.RetryFailed) {
+			if err := s.markRetry(relativePath); err != nil {
+				log.Error().Err(err).Str("service", "scanner").Msgf("Error retrying failed file: %s", relativePath)
+				return nil
+			}
+			reason = ""
+		}
+
+		if reason != usecase.SkipSidecar {
+			mu.Lock()
+			fileCount++
+			mu.Unlock()
+			metrics.FilesDiscovered.Inc()
+			log.Info().Str("service", "scanner").Str("path", relativePath).Msgf("Found a file")
+		}
+
+		if reason != "" {
+			s.skip(relativePath, reason)
+			return nil
+		}
+
+		s.markPending(relativePath, info)
+		s.wg.Add(1)
+		if d != nil {
+			d.add()
+		}
+
+		payload := s.payload(relativePath, info, d)
+		set(&payload)
+
+		select {
+		case s.UploadJobQueue <- uploader.Job{
+			Payload: payload,
+		}:
+			metrics.JobsQueued.Inc()
+		case <-s.done:
+			// the deliveries are not finished, they are ingested again by the next run
+			mu.Lock()
+			stopped = true
+			mu.Unlock()
+			return filepath.SkipAll
+		}
+		return nil
+	}
+
+	err := s.walk(func(path string, info os.FileInfo, entries []os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -100,31 +346,105 @@ func (s *Scanner) Scan() {
 		log.Info().Str("service", "scanner").Str("path", path).Msg("Found a file or directory")
 
 		if info.IsDir() {
+			if s.dropFolder() && relativePath != "" && !strings.Contains(relativePath, "/") {
+				d, reason := s.startDelivery(relativePath, path)
+				if d == nil {
+					if reason != "" {
+						s.skip(relativePath, reason)
+					}
+					return filepath.SkipDir
+				}
+				mu.Lock()
+				deliveries[relativePath] = d
+				mu.Unlock()
+			}
+
+			mu.Lock()
 			dirCount++
+			mu.Unlock()
 
 			log.Info().Str("service", "scanner").Str("path", path).Msgf("Found a directory")
 
+			var sequences map[string]*sequence.Sequence
+			if s.config.Scanner.Sequences.Enabled {
+				sequences = s.detectSequences(relativePath, entries)
+			}
+			detected := s.detectGroups(relativePath, entries)
+
+			mu.Lock()
+			for key, seq := range sequences {
+				frames[key] = seq
+			}
+			for key, g := range detected {
+				groups[key] = g
+			}
+			mu.Unlock()
+
 			err := s.collectionUseCase.CreateCollectionIfNotExists(relativePath, info)
 			if err != nil {
 				log.Error().Err(err).Str("service", "scanner").Msgf("Error creating collection")
 			}
-		} else {
-			fileCount++
-			log.Info().Str("service", "scanner").Str("path", relativePath).Msgf("Found a file")
 
-			s.wg.Add(1)
+			if c := s.detectCard(relativePath, entries); c != nil {
+				for _, clip := range c.Clips {
+					clip := clip
+					err := queue(clip.Key(), clip.Info(), false, func(payload *uploader.Payload) {
+						payload.Clip = clip
+					})
+					if err != nil {
+						return err
+					}
+				}
+				// the folders of a card are not collections and its other
+				// files are not uploaded on their own
+				return filepath.SkipDir
+			}
+		} else {
+			mu.Lock()
+			seq := frames[relativePath]
+			mu.Unlock()
+			if seq != nil {
+				// the other frames are uploaded with the first one
+				if info.Name() != seq.First() {
+					return nil
+				}
+				relativePath, info = seq.Key(), seq.Info()
+			}
 
-			select {
-			case s.UploadJobQueue <- uploader.Job{
-				Payload: uploader.Payload{Path: relativePath, Info: info, WG: s.wg},
-			}:
-			case <-s.done:
-				return filepath.SkipAll
+			mu.Lock()
+			g := groups[relativePath]
+			mu.Unlock()
+			if g != nil {
+				// the secondary files are uploaded with the primary one
+				if relativePath != g.Key() {
+					return nil
+				}
+				info = g.Info()
 			}
+
+			archive := s.isArchive(relativePath)
+			single := seq == nil && g == nil && !archive
+
+			return queue(relativePath, info, single, func(payload *uploader.Payload) {
+				payload.Sequence = seq
+				payload.Group = g
+				payload.Archive = archive
+			})
 		}
 		return nil
 	})
 
+	for _, d := range deliveries {
+		if err != nil || stopped {
+			// the delivery is ingested again by the next scan
+			s.deliveriesMu.Lock()
+			delete(s.deliveries, d.path)
+			s.deliveriesMu.Unlock()
+			continue
+		}
+		d.done("", nil)
+	}
+
 	if err != nil {
 		log.Info().
 			Str("service", "scanner").
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
 	"github.com/kgantsov/synconik/internal/iconik/client"
 	"github.com/kgantsov/synconik/internal/store"
 	"github.com/kgantsov/synconik/internal/uploader"
@@ -99,3 +100,36 @@ func TestScanner_StartStop(t *testing.T) {
 	_, ok := <-scanner.done
 	assert.False(t, ok, "done channel should be closed")
 }
+
+func TestScanner_Enqueue(t *testing.T) {
+	scanner, tmpDir, cleanup := setupTestScanner(t)
+	defer cleanup()
+
+	err := os.WriteFile(filepath.Join(tmpDir, "testdir", "clip.mov"), []byte("data"), 0644)
+	assert.NoError(t, err)
+
+	err = scanner.Enqueue("/clip.mov")
+	assert.NoError(t, err)
+
+	job := <-scanner.UploadJobQueue
+	assert.Equal(t, "/clip.mov", job.Payload.Path)
+	assert.Equal(t, int64(4), job.Payload.Info.Size())
+	job.Payload.WG.Done()
+
+	file, err := scanner.store.GetFile("/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatePending, file.State)
+	assert.Equal(t, "clip.mov", file.Name)
+	assert.Equal(t, 4, file.Size)
+
+	assert.Error(t, scanner.Enqueue("/missing.mov"))
+	assert.Error(t, scanner.Enqueue(""))
+}
+
+func TestScanner_TriggerScan(t *testing.T) {
+	scanner, _, cleanup := setupTestScanner(t)
+	defer cleanup()
+
+	assert.True(t, scanner.TriggerScan())
+	assert.False(t, scanner.TriggerScan(), "a scan is already pending")
+}
@@ -0,0 +1,27 @@
+package scanner
+
+import (
+	"os"
+
+	"github.com/kgantsov/synconik/internal/sequence"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+// detectSequences returns the image sequences of a directory by the store
+// keys of their frames
+func (s *Scanner) detectSequences(relativePath string, entries []os.DirEntry) map[string]*sequence.Sequence {
+	frames := map[string]*sequence.Sequence{}
+
+	dir := ""
+	if relativePath != "" {
+		dir = relativePath + "/"
+	}
+
+	for _, seq := range usecase.DetectSequences(s.config, dir, entries) {
+		for _, frame := range seq.Frames {
+			frames[dir+frame.Info.Name()] = seq
+		}
+	}
+
+	return frames
+}
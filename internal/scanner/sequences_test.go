@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScanner_Sequences(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	files := []string{"shots/shot_0001.exr", "shots/shot_0002.exr", "shots/shot_0003.exr", "shots/clip.mov"}
+	for _, path := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("frame"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{
+			Dir:       dir,
+			Interval:  10,
+			Sequences: config.SequencesConfig{Enabled: true},
+		},
+	}
+
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	defer badgerStore.Close()
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateCollection", mock.Anything, mock.Anything).Return(&client.Collection{ID: "C1"}, nil)
+
+	uploadQueue := make(chan uploader.Job, 10)
+	scanner, err := NewScanner(cfg, badgerStore, mockClient, uploadQueue)
+	assert.NoError(t, err)
+
+	scanner.Scan()
+	assert.Len(t, uploadQueue, 2)
+
+	jobs := map[string]uploader.Payload{}
+	for i := 0; i < 2; i++ {
+		job := <-uploadQueue
+		jobs[job.Payload.Path] = job.Payload
+		job.Payload.WG.Done()
+	}
+
+	assert.Nil(t, jobs["shots/clip.mov"].Sequence)
+
+	payload := jobs["shots/shot_####.exr"]
+	assert.NotNil(t, payload.Sequence)
+	assert.Len(t, payload.Sequence.Frames, 3)
+	assert.Equal(t, int64(15), payload.Info.Size())
+
+	file, err := badgerStore.GetFile("shots/shot_####.exr")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StatePending, file.State)
+	assert.Equal(t, 15, file.Size)
+
+	// requeueing a sequence detects its frames again
+	assert.NoError(t, scanner.Enqueue("shots/shot_####.exr"))
+	job := <-uploadQueue
+	assert.Len(t, job.Payload.Sequence.Frames, 3)
+	job.Payload.WG.Done()
+
+	scanner.Stop()
+}
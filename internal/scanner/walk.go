@@ -0,0 +1,364 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultWalkers is the number of directories read at once when
+// scanner.walkers is not set
+const defaultWalkers = 4
+
+// visitFunc is called by the walk for every directory with its entries and
+// for every regular file, like filepath.WalkFunc
+type visitFunc func(path string, info os.FileInfo, entries []os.DirEntry, err error) error
+
+// walker walks the scanned directory with several goroutines reading
+// directories at once. A directory is read and visited with its entries
+// before they are visited, and the entries of a directory are visited in
+// lexical order by a single goroutine, but directories are read in no
+// particular order, so visit must be safe for concurrent use.
+//
+// Entries are listed with os.ReadDir and only stat when visit needs more than
+// their name and type. The policies for symbolic links, special files and
+// other filesystems are applied before visiting a path, and the files of a
+// followed linked directory are visited under the path of the link.
+type walker struct {
+	scanner *Scanner
+	visit   visitFunc
+
+	// device is the filesystem of the scanned directory
+	device    uint64
+	hasDevice bool
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	dirs []*walkDir
+	// pending is the number of directories queued or being read
+	pending int
+	stopped bool
+	err     error
+}
+
+// walkDir is a directory to read and visit
+type walkDir struct {
+	path string
+	info os.FileInfo
+	// roots are the real paths of the scanned directory and of the linked
+	// directories followed to reach the directory
+	roots []string
+}
+
+// walk walks the scanned directory calling visit for the directories and
+// regular files to scan
+func (s *Scanner) walk(visit visitFunc) error {
+	root := s.config.Scanner.Dir
+
+	real, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+
+	w := &walker{scanner: s, visit: visit}
+	w.cond = sync.NewCond(&w.mu)
+	w.device, w.hasDevice = deviceOf(info)
+
+	w.push(&walkDir{path: root, info: info, roots: []string{real}})
+
+	workers := s.config.Scanner.Walkers
+	if workers <= 0 {
+		workers = defaultWalkers
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for d := w.pop(); d != nil; d = w.pop() {
+				w.read(d)
+				w.finish()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return w.err
+}
+
+// push queues a directory to read
+func (w *walker) push(d *walkDir) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped {
+		return
+	}
+
+	// the last queued directory is read first, which keeps the queue short
+	w.dirs = append(w.dirs, d)
+	w.pending++
+	w.cond.Signal()
+}
+
+// pop waits for a directory to read, it returns nil once all directories
+// were read or the walk was stopped
+func (w *walker) pop() *walkDir {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.dirs) == 0 && w.pending > 0 && !w.stopped {
+		w.cond.Wait()
+	}
+	if w.stopped || len(w.dirs) == 0 {
+		return nil
+	}
+
+	d := w.dirs[len(w.dirs)-1]
+	w.dirs = w.dirs[:len(w.dirs)-1]
+	return d
+}
+
+// finish marks a popped directory as read
+func (w *walker) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending--
+	if w.pending == 0 {
+		w.cond.Broadcast()
+	}
+}
+
+// stop ends the walk with the error returned by visit, filepath.SkipAll ends
+// it without an error
+func (w *walker) stop(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.stopped {
+		w.stopped = true
+		w.err = w.result(err)
+	}
+	w.dirs = nil
+	w.cond.Broadcast()
+}
+
+func (w *walker) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.stopped
+}
+
+func (w *walker) result(err error) error {
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// read visits a directory and then its entries, a directory that cannot be
+// read is visited with the error and the entries read so far
+func (w *walker) read(d *walkDir) {
+	start := time.Now()
+
+	entries, err := os.ReadDir(d.path)
+	if err := w.visit(d.path, d.info, entries, err); err != nil {
+		if err != filepath.SkipDir {
+			w.stop(err)
+		}
+		return
+	}
+
+	log.Debug().
+		Str("service", "scanner").
+		Str("path", d.path).
+		Int("entries", len(entries)).
+		Dur("duration", time.Since(start)).
+		Msg("Read a directory")
+
+	for _, entry := range entries {
+		if w.isStopped() {
+			return
+		}
+
+		err := w.entry(d, entry)
+		if err == filepath.SkipDir {
+			// skips the remaining files of the directory
+			return
+		}
+		if err != nil {
+			w.stop(err)
+			return
+		}
+	}
+}
+
+func (w *walker) entry(d *walkDir, entry os.DirEntry) error {
+	path := filepath.Join(d.path, entry.Name())
+	relativePath := strings.TrimPrefix(path, w.scanner.config.Scanner.Dir)
+
+	switch {
+	case entry.Type()&os.ModeSymlink != 0:
+		return w.symlink(d, path, relativePath)
+	case entry.IsDir():
+		info := &entryInfo{entry: entry}
+		if !w.sameFilesystem(info) {
+			w.scanner.skip(relativePath, usecase.SkipMount)
+			return nil
+		}
+		w.push(&walkDir{path: path, info: info, roots: d.roots})
+		return nil
+	case !entry.Type().IsRegular():
+		w.scanner.skip(relativePath, usecase.SkipSpecial)
+		return nil
+	}
+
+	return w.visit(path, &entryInfo{entry: entry}, nil, nil)
+}
+
+// symlink visits the target of a link under the path of the link
+func (w *walker) symlink(d *walkDir, path, relativePath string) error {
+	policy := w.scanner.config.Scanner.Symlinks
+	if policy == config.SymlinksSkip {
+		w.scanner.skip(relativePath, usecase.SkipSymlink)
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Warn().Err(err).Str("service", "scanner").Str("path", relativePath).Msg("Broken symbolic link")
+		w.scanner.skip(relativePath, usecase.SkipSymlink)
+		return nil
+	}
+	info = &linkInfo{FileInfo: info, name: filepath.Base(path)}
+
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() {
+			w.scanner.skip(relativePath, usecase.SkipSpecial)
+			return nil
+		}
+		return w.visit(path, info, nil, nil)
+	}
+
+	if policy != config.SymlinksFollow {
+		w.scanner.skip(relativePath, usecase.SkipSymlink)
+		return nil
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return w.visit(path, info, nil, err)
+	}
+
+	if w.cycle(path, real, d.roots) {
+		w.scanner.skip(relativePath, usecase.SkipCycle)
+		return nil
+	}
+
+	// the directory is read through the link, its entries keep its path
+	roots := append(append([]string{}, d.roots...), real)
+	w.push(&walkDir{path: path, info: info, roots: roots})
+	return nil
+}
+
+// cycle reports whether the linked directory real holds the link or one of
+// the directories being walked, following it would walk them again
+func (w *walker) cycle(path, real string, roots []string) bool {
+	locations := roots
+	if parent, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		locations = append([]string{parent}, locations...)
+	}
+
+	for _, location := range locations {
+		rel, err := filepath.Rel(real, location)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameFilesystem reports whether the directory is on the filesystem of the
+// scanned directory, it is always the case unless one_filesystem is set
+func (w *walker) sameFilesystem(info os.FileInfo) bool {
+	if !w.scanner.config.Scanner.OneFilesystem || !w.hasDevice {
+		return true
+	}
+
+	device, ok := deviceOf(info)
+	return !ok || device == w.device
+}
+
+// linkInfo is the file info of the target of a link with the name of the link
+type linkInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (i *linkInfo) Name() string { return i.name }
+
+// entryInfo is the file info of a directory entry. The name and the type come
+// from the directory listing, the entry is only stat for the other fields,
+// which stay empty when it was removed in the meantime.
+type entryInfo struct {
+	entry os.DirEntry
+
+	once sync.Once
+	info os.FileInfo
+}
+
+func (i *entryInfo) load() os.FileInfo {
+	i.once.Do(func() {
+		info, err := i.entry.Info()
+		if err == nil {
+			i.info = info
+		}
+	})
+	return i.info
+}
+
+func (i *entryInfo) Name() string { return i.entry.Name() }
+func (i *entryInfo) IsDir() bool  { return i.entry.IsDir() }
+
+func (i *entryInfo) Size() int64 {
+	if info := i.load(); info != nil {
+		return info.Size()
+	}
+	return 0
+}
+
+func (i *entryInfo) Mode() os.FileMode {
+	if info := i.load(); info != nil {
+		return info.Mode()
+	}
+	return i.entry.Type()
+}
+
+func (i *entryInfo) ModTime() time.Time {
+	if info := i.load(); info != nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func (i *entryInfo) Sys() any {
+	if info := i.load(); info != nil {
+		return info.Sys()
+	}
+	return nil
+}
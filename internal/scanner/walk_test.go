@@ -0,0 +1,236 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/uploader"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// setupLinks creates media/real.mov with a link to it, a link to a directory
+// outside of media holding a link back to it, and a link to media itself
+func setupLinks(t *testing.T) string {
+	base := t.TempDir()
+	dir := filepath.Join(base, "media") + "/"
+	outside := filepath.Join(base, "outside")
+
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	assert.NoError(t, os.MkdirAll(outside, 0755))
+	assert.NoError(t, os.WriteFile(dir+"real.mov", []byte("real"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "a.mov"), []byte("linked"), 0644))
+
+	assert.NoError(t, os.Symlink(dir+"real.mov", dir+"link.mov"))
+	assert.NoError(t, os.Symlink(outside, dir+"linked"))
+	assert.NoError(t, os.Symlink(dir, filepath.Join(outside, "back")))
+	assert.NoError(t, os.Symlink(dir, dir+"self"))
+	assert.NoError(t, os.Symlink(filepath.Join(base, "missing.mov"), dir+"broken.mov"))
+
+	return dir
+}
+
+// scanPaths scans the directory and returns the queued jobs and the skipped
+// paths with their reasons
+func scanPaths(t *testing.T, cfg *config.Config) (map[string]uploader.Payload, map[string]string) {
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	defer badgerStore.Close()
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateCollection", mock.Anything, mock.Anything).Return(&client.Collection{ID: "C1"}, nil)
+
+	uploadQueue := make(chan uploader.Job, 200)
+	scanner, err := NewScanner(cfg, badgerStore, mockClient, uploadQueue)
+	assert.NoError(t, err)
+
+	skipped := map[string]string{}
+	var mu sync.Mutex
+	scanner.OnSkip = func(relativePath, reason string) {
+		mu.Lock()
+		defer mu.Unlock()
+		skipped[relativePath] = reason
+	}
+
+	scanner.Scan()
+
+	jobs := map[string]uploader.Payload{}
+	for len(uploadQueue) > 0 {
+		job := <-uploadQueue
+		jobs[job.Payload.Path] = job.Payload
+		job.Payload.WG.Done()
+	}
+
+	scanner.Stop()
+
+	return jobs, skipped
+}
+
+func TestScanner_SymlinksFiles(t *testing.T) {
+	dir := setupLinks(t)
+
+	jobs, skipped := scanPaths(t, &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10}})
+
+	assert.Len(t, jobs, 2)
+	assert.Contains(t, jobs, "real.mov")
+
+	// links to files are uploaded with the content of their target
+	link := jobs["link.mov"].Info
+	assert.Equal(t, "link.mov", link.Name())
+	assert.Equal(t, int64(len("real")), link.Size())
+
+	assert.Equal(t, map[string]string{
+		"linked":     usecase.SkipSymlink,
+		"self":       usecase.SkipSymlink,
+		"broken.mov": usecase.SkipSymlink,
+	}, skipped)
+}
+
+func TestScanner_SymlinksFollow(t *testing.T) {
+	dir := setupLinks(t)
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10, Symlinks: config.SymlinksFollow}}
+	jobs, skipped := scanPaths(t, cfg)
+
+	assert.Len(t, jobs, 3)
+	assert.Contains(t, jobs, "real.mov")
+	assert.Contains(t, jobs, "link.mov")
+	assert.Equal(t, int64(len("linked")), jobs["linked/a.mov"].Info.Size())
+
+	assert.Equal(t, map[string]string{
+		"linked/back": usecase.SkipCycle,
+		"self":        usecase.SkipCycle,
+		"broken.mov":  usecase.SkipSymlink,
+	}, skipped)
+}
+
+func TestScanner_SymlinksSkip(t *testing.T) {
+	dir := setupLinks(t)
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10, Symlinks: config.SymlinksSkip}}
+	jobs, skipped := scanPaths(t, cfg)
+
+	assert.Len(t, jobs, 1)
+	assert.Contains(t, jobs, "real.mov")
+	assert.Len(t, skipped, 4)
+	for _, reason := range skipped {
+		assert.Equal(t, usecase.SkipSymlink, reason)
+	}
+}
+
+func TestScanner_Walkers(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	paths := []string{}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 4; k++ {
+				paths = append(paths, fmt.Sprintf("day%d/cam%d/clip%d.mov", i, j, k))
+			}
+		}
+	}
+	for _, path := range paths {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("clip"), 0644))
+	}
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10, Walkers: 8}}
+	jobs, skipped := scanPaths(t, cfg)
+
+	assert.Len(t, jobs, len(paths))
+	assert.Empty(t, skipped)
+	for _, path := range paths {
+		assert.Equal(t, int64(len("clip")), jobs[path].Info.Size(), path)
+	}
+}
+
+func TestScanner_WalkComparesStoredFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	assert.NoError(t, os.MkdirAll(dir+"rushes", 0755))
+	for _, name := range []string{"new.mov", "synced.mov", "failed.mov", "edited.mov"} {
+		assert.NoError(t, os.WriteFile(dir+"rushes/"+name, []byte("clip"), 0644))
+	}
+
+	badgerStore, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+	defer badgerStore.Close()
+
+	synced := &entity.File{Name: "synced.mov", DirectoryPath: "rushes/"}
+	synced.SetState(entity.StateSynced, nil)
+	assert.NoError(t, badgerStore.SaveFile("rushes/synced.mov", synced))
+	failed := &entity.File{Name: "failed.mov", DirectoryPath: "rushes/"}
+	failed.SetState(entity.StateFailed, nil)
+	assert.NoError(t, badgerStore.SaveFile("rushes/failed.mov", failed))
+
+	// synced before the file was modified in place
+	info, err := os.Stat(dir + "rushes/edited.mov")
+	assert.NoError(t, err)
+	edited := usecase.NewFileRecord("rushes/edited.mov", info)
+	edited.Size = 2
+	edited.SetState(entity.StateSynced, nil)
+	assert.NoError(t, badgerStore.SaveFile("rushes/edited.mov", edited))
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateCollection", mock.Anything, mock.Anything).Return(&client.Collection{ID: "C1"}, nil)
+
+	uploadQueue := make(chan uploader.Job, 10)
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10}}
+	scanner, err := NewScanner(cfg, badgerStore, mockClient, uploadQueue)
+	assert.NoError(t, err)
+
+	skipped := map[string]string{}
+	scanner.OnSkip = func(relativePath, reason string) {
+		skipped[relativePath] = reason
+	}
+
+	scanner.Scan()
+	assert.Len(t, uploadQueue, 2)
+
+	queued := []string{}
+	for len(uploadQueue) > 0 {
+		job := <-uploadQueue
+		queued = append(queued, job.Payload.Path)
+		job.Payload.WG.Done()
+	}
+	assert.ElementsMatch(t, []string{"rushes/new.mov", "rushes/edited.mov"}, queued)
+
+	assert.Equal(t, map[string]string{
+		"rushes/synced.mov": usecase.SkipSynced,
+		"rushes/failed.mov": usecase.SkipFailed,
+	}, skipped)
+
+	scanner.Stop()
+}
+
+func TestEntryInfo(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "clip.mov"), []byte("clip"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "gone.mov"), []byte("gone"), 0644))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// the entry is only stat when its size is needed
+	assert.NoError(t, os.Remove(filepath.Join(dir, "gone.mov")))
+
+	clip := &entryInfo{entry: entries[0]}
+	assert.Equal(t, "clip.mov", clip.Name())
+	assert.False(t, clip.IsDir())
+	assert.Equal(t, int64(4), clip.Size())
+	assert.True(t, clip.Mode().IsRegular())
+	assert.False(t, clip.ModTime().IsZero())
+
+	gone := &entryInfo{entry: entries[1]}
+	assert.Equal(t, "gone.mov", gone.Name())
+	assert.Equal(t, int64(0), gone.Size())
+	assert.True(t, gone.Mode().IsRegular())
+	assert.True(t, gone.ModTime().IsZero())
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanner_SpecialFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	assert.NoError(t, os.MkdirAll(dir, 0755))
+	assert.NoError(t, os.WriteFile(dir+"clip.mov", []byte("clip"), 0644))
+	assert.NoError(t, syscall.Mkfifo(dir+"pipe", 0644))
+	assert.NoError(t, os.Symlink(dir+"pipe", dir+"pipe-link"))
+
+	jobs, skipped := scanPaths(t, &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10}})
+
+	assert.Len(t, jobs, 1)
+	assert.Contains(t, jobs, "clip.mov")
+	assert.Equal(t, map[string]string{"pipe": usecase.SkipSpecial, "pipe-link": usecase.SkipSpecial}, skipped)
+}
+
+func TestWalker_SameFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	info, err := os.Stat(dir)
+	assert.NoError(t, err)
+
+	device, ok := deviceOf(info)
+	assert.True(t, ok)
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir + "/", OneFilesystem: true}}
+	w := &walker{scanner: &Scanner{config: cfg}, device: device, hasDevice: true}
+	assert.True(t, w.sameFilesystem(info))
+
+	// a directory on another device is a mount point
+	w.device = device + 1
+	assert.False(t, w.sameFilesystem(info))
+
+	cfg.Scanner.OneFilesystem = false
+	assert.True(t, w.sameFilesystem(info))
+}
@@ -0,0 +1,216 @@
+package sequence
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults used when the configuration leaves them unset
+var (
+	DefaultExtensions = []string{".dpx", ".exr"}
+	DefaultMinFrames  = 2
+)
+
+var framePattern = regexp.MustCompile(`^(.*?)(\d+)(\.[^.]+)$`)
+
+// Frame is one numbered file of a sequence
+type Frame struct {
+	Number int
+	Info   os.FileInfo
+}
+
+// Sequence is a set of numbered frames with the same name around the frame
+// number in one directory, e.g. shot_0001.exr, shot_0002.exr...
+type Sequence struct {
+	// Dir is the store key of the directory with a trailing slash, empty for
+	// the scanned directory
+	Dir     string
+	Prefix  string
+	Suffix  string
+	Padding int
+	Frames  []Frame
+}
+
+// Name returns the name of the sequence with the frame number replaced by
+// hashes, e.g. shot_####.exr
+func (s *Sequence) Name() string {
+	return s.Prefix + strings.Repeat("#", s.Padding) + s.Suffix
+}
+
+// Key returns the store key of the sequence
+func (s *Sequence) Key() string {
+	return s.Dir + s.Name()
+}
+
+// First returns the name of the first frame
+func (s *Sequence) First() string {
+	return s.Frames[0].Info.Name()
+}
+
+// Size returns the size of all frames
+func (s *Sequence) Size() int64 {
+	var size int64
+	for _, frame := range s.Frames {
+		size += frame.Info.Size()
+	}
+	return size
+}
+
+// Match reports whether the file name is a frame of the sequence
+func (s *Sequence) Match(name string) bool {
+	prefix, number, suffix, ok := parse(name)
+	return ok && prefix == s.Prefix && suffix == s.Suffix && len(number) >= s.Padding
+}
+
+// Info returns the file info of the whole sequence, the size of all frames
+// and the time of the last modified one
+func (s *Sequence) Info() os.FileInfo {
+	info := &sequenceInfo{name: s.Name(), size: s.Size()}
+	for _, frame := range s.Frames {
+		if frame.Info.ModTime().After(info.modTime) {
+			info.modTime = frame.Info.ModTime()
+		}
+	}
+	return info
+}
+
+// FromKey returns the sequence stored under a key, without its frames
+func FromKey(key string) (*Sequence, bool) {
+	dir, name := "", key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		dir, name = key[:i+1], key[i+1:]
+	}
+
+	start := strings.Index(name, "#")
+	if start < 0 {
+		return nil, false
+	}
+	end := start
+	for end < len(name) && name[end] == '#' {
+		end++
+	}
+
+	return &Sequence{Dir: dir, Prefix: name[:start], Suffix: name[end:], Padding: end - start}, true
+}
+
+// Detect groups the files of a directory into sequences. Only files with
+// one of the extensions are considered and a sequence needs at least
+// minFrames frames.
+func Detect(dir string, files []os.FileInfo, extensions []string, minFrames int) []*Sequence {
+	if len(extensions) == 0 {
+		extensions = DefaultExtensions
+	}
+	if minFrames <= 0 {
+		minFrames = DefaultMinFrames
+	}
+
+	groups := map[string]*Sequence{}
+	keys := []string{}
+
+	for _, info := range files {
+		if !info.Mode().IsRegular() || !hasExtension(info.Name(), extensions) {
+			continue
+		}
+
+		prefix, number, suffix, ok := parse(info.Name())
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			continue
+		}
+
+		key := prefix + "\x00" + suffix
+		sequence, ok := groups[key]
+		if !ok {
+			sequence = &Sequence{Dir: dir, Prefix: prefix, Suffix: suffix, Padding: len(number)}
+			groups[key] = sequence
+			keys = append(keys, key)
+		}
+		// unpadded frame numbers have different lengths
+		if len(number) < sequence.Padding {
+			sequence.Padding = len(number)
+		}
+		sequence.Frames = append(sequence.Frames, Frame{Number: n, Info: info})
+	}
+
+	sort.Strings(keys)
+
+	sequences := []*Sequence{}
+	for _, key := range keys {
+		sequence := groups[key]
+		if len(sequence.Frames) < minFrames {
+			continue
+		}
+
+		sort.Slice(sequence.Frames, func(i, j int) bool {
+			return sequence.Frames[i].Number < sequence.Frames[j].Number
+		})
+		sequences = append(sequences, sequence)
+	}
+
+	return sequences
+}
+
+// DetectEntries detects the sequences of a directory listing like Detect,
+// only the entries named like frames with one of the extensions are stat
+func DetectEntries(dir string, entries []os.DirEntry, extensions []string, minFrames int) []*Sequence {
+	if len(extensions) == 0 {
+		extensions = DefaultExtensions
+	}
+
+	files := []os.FileInfo{}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !hasExtension(entry.Name(), extensions) {
+			continue
+		}
+		if _, _, _, ok := parse(entry.Name()); !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+
+	return Detect(dir, files, extensions, minFrames)
+}
+
+func parse(name string) (string, string, string, bool) {
+	match := framePattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", "", false
+	}
+	return match[1], match[2], match[3], true
+}
+
+func hasExtension(name string, extensions []string) bool {
+	ext := filepath.Ext(name)
+	for _, extension := range extensions {
+		if strings.EqualFold(ext, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+type sequenceInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i *sequenceInfo) Name() string       { return i.name }
+func (i *sequenceInfo) Size() int64        { return i.size }
+func (i *sequenceInfo) Mode() os.FileMode  { return 0644 }
+func (i *sequenceInfo) ModTime() time.Time { return i.modTime }
+func (i *sequenceInfo) IsDir() bool        { return false }
+func (i *sequenceInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,117 @@
+package sequence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readDir(t *testing.T, dir string, names ...string) []os.FileInfo {
+	infos := []os.FileInfo{}
+	for _, name := range names {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("frame"), 0644))
+		info, err := os.Stat(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func TestDetect(t *testing.T) {
+	files := readDir(
+		t, t.TempDir(),
+		"shot_0003.exr", "shot_0001.exr", "shot_0002.exr",
+		"plate.1.dpx", "plate.2.dpx", "plate.10.dpx",
+		"single_0001.exr",
+		"notes_0001.txt", "notes_0002.txt",
+		"shot_v2_0001.EXR", "shot_v2_0002.EXR",
+	)
+
+	sequences := Detect("rushes/", files, nil, 0)
+	assert.Len(t, sequences, 3)
+
+	plate := sequences[0]
+	assert.Equal(t, "plate.#.dpx", plate.Name())
+	assert.Equal(t, "rushes/plate.#.dpx", plate.Key())
+	assert.Equal(t, "plate.1.dpx", plate.First())
+	assert.Equal(t, []int{1, 2, 10}, frameNumbers(plate))
+
+	shot := sequences[1]
+	assert.Equal(t, "shot_####.exr", shot.Name())
+	assert.Equal(t, []int{1, 2, 3}, frameNumbers(shot))
+	assert.Equal(t, int64(15), shot.Size())
+
+	info := shot.Info()
+	assert.Equal(t, "shot_####.exr", info.Name())
+	assert.Equal(t, int64(15), info.Size())
+	assert.False(t, info.IsDir())
+
+	assert.Equal(t, "shot_v2_####.EXR", sequences[2].Name())
+}
+
+func TestDetectOptions(t *testing.T) {
+	files := readDir(t, t.TempDir(), "a_01.tif", "a_02.tif", "b_01.exr", "b_02.exr")
+
+	sequences := Detect("", files, []string{".tif"}, 0)
+	assert.Len(t, sequences, 1)
+	assert.Equal(t, "a_##.tif", sequences[0].Key())
+
+	assert.Empty(t, Detect("", files, nil, 3))
+}
+
+func TestFromKey(t *testing.T) {
+	sequence, ok := FromKey("rushes/shot_####.exr")
+	assert.True(t, ok)
+	assert.Equal(t, &Sequence{Dir: "rushes/", Prefix: "shot_", Suffix: ".exr", Padding: 4}, sequence)
+
+	assert.True(t, sequence.Match("shot_0001.exr"))
+	assert.True(t, sequence.Match("shot_10001.exr"))
+	assert.False(t, sequence.Match("shot_001.exr"))
+	assert.False(t, sequence.Match("shot_0001.dpx"))
+
+	_, ok = FromKey("rushes/clip.mov")
+	assert.False(t, ok)
+}
+
+func frameNumbers(sequence *Sequence) []int {
+	numbers := []int{}
+	for _, frame := range sequence.Frames {
+		numbers = append(numbers, frame.Number)
+	}
+	return numbers
+}
+
+// statEntry records the entries whose info was read
+type statEntry struct {
+	os.DirEntry
+	stat map[string]bool
+}
+
+func (e *statEntry) Info() (os.FileInfo, error) {
+	e.stat[e.Name()] = true
+	return e.DirEntry.Info()
+}
+
+func TestDetectEntries(t *testing.T) {
+	dir := t.TempDir()
+	readDir(t, dir, "shot_0001.exr", "shot_0002.exr", "clip.mov", "notes_0001.txt", "cover.exr")
+
+	listed, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+
+	stat := map[string]bool{}
+	entries := []os.DirEntry{}
+	for _, entry := range listed {
+		entries = append(entries, &statEntry{DirEntry: entry, stat: stat})
+	}
+
+	sequences := DetectEntries("rushes/", entries, nil, 0)
+	assert.Len(t, sequences, 1)
+	assert.Equal(t, "rushes/shot_####.exr", sequences[0].Key())
+	assert.Equal(t, int64(10), sequences[0].Info().Size())
+
+	// only the entries named like frames are stat
+	assert.Equal(t, map[string]bool{"shot_0001.exr": true, "shot_0002.exr": true}, stat)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/health"
+	"github.com/kgantsov/synconik/internal/metrics"
+	"github.com/rs/zerolog/log"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server exposes the operational HTTP endpoints of synconik
+type Server struct {
+	config *config.Config
+
+	mux      *http.ServeMux
+	server   *http.Server
+	listener net.Listener
+}
+
+func NewServer(config *config.Config, checker *health.Checker) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", checker.LivenessHandler())
+	mux.Handle("/readyz", checker.ReadinessHandler())
+
+	return &Server{
+		config: config,
+		mux:    mux,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Handler returns the handler serving all endpoints
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Start listens on the configured address and serves requests in the
+// background. An empty address disables the server.
+func (s *Server) Start() error {
+	if s.config.HTTP.Addr == "" {
+		log.Debug().Str("service", "server").Msg("HTTP server is disabled")
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.config.HTTP.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	log.Info().Str("service", "server").Msgf("Listening on %s", listener.Addr())
+
+	go func() {
+		err := s.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Str("service", "server").Msg("HTTP server failed")
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
+
+	log.Debug().Str("service", "server").Msg("Stopping the HTTP server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Str("service", "server").Msg("Error stopping the HTTP server")
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/health"
+	"github.com/kgantsov/synconik/internal/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerMetrics(t *testing.T) {
+	metrics.FilesDiscovered.Inc()
+
+	server := NewServer(&config.Config{HTTP: config.HTTPConfig{Addr: "127.0.0.1:0"}}, health.NewChecker())
+	err := server.Start()
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	resp, err := http.Get("http://" + server.Addr() + "/metrics")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.Contains(string(body), "synconik_scanner_files_discovered_total"))
+}
+
+func TestServerDisabled(t *testing.T) {
+	server := NewServer(&config.Config{}, health.NewChecker())
+	err := server.Start()
+	assert.NoError(t, err)
+	assert.Equal(t, "", server.Addr())
+	server.Stop()
+}
+
+func TestServerHealth(t *testing.T) {
+	running := true
+	checker := health.NewChecker()
+	checker.AddLiveness("scanner", health.RunningCheck("scanner", func() bool { return running }))
+	checker.AddReadiness("scanner_dir", health.DirCheck(t.TempDir()))
+
+	server := NewServer(&config.Config{}, checker)
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	running = false
+	recorder = httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/progress"
 )
 
 type B2Storage struct {
@@ -32,7 +33,10 @@ func (s *B2Storage) Upload(filePath string, file *entity.UploadFile) error {
 	}
 
 	// Create request
-	req, err := http.NewRequest("POST", file.UploadURL, f)
+	transfer := progress.Default.Start("B2", filePath, file.Size)
+	defer progress.Default.Finish(transfer)
+
+	req, err := http.NewRequest("POST", file.UploadURL, progress.Default.Reader(transfer, f))
 	if err != nil {
 		return fmt.Errorf("failed to create upload request: %w", err)
 	}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/progress"
 )
 
 type GCSStorage struct {
@@ -57,7 +58,10 @@ func (s *GCSStorage) Upload(filePath string, file *entity.UploadFile) error {
 
 	full_url := file.UploadURL + "&upload_id=" + uploadID
 
-	req, err := http.NewRequest("PUT", full_url, f)
+	transfer := progress.Default.Start("GCS", filePath, file.Size)
+	defer progress.Default.Finish(transfer)
+
+	req, err := http.NewRequest("PUT", full_url, progress.Default.Reader(transfer, f))
 	if err != nil {
 		return fmt.Errorf("error creating request: %w", err)
 	}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/progress"
 )
 
 type S3Storage struct {
@@ -25,7 +26,10 @@ func (s *S3Storage) Upload(filePath string, file *entity.UploadFile) error {
 	}
 	defer f.Close()
 
-	req, err := http.NewRequest("PUT", file.UploadURL, f)
+	transfer := progress.Default.Start("S3", filePath, file.Size)
+	defer progress.Default.Finish(transfer)
+
+	req, err := http.NewRequest("PUT", file.UploadURL, progress.Default.Reader(transfer, f))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
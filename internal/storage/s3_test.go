@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/progress"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,6 +36,12 @@ func TestS3Storage_Upload(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, testContent, body)
 
+		// the transfer is tracked while the request is in progress
+		snapshots := progress.Default.Snapshot()
+		assert.Len(t, snapshots, 1)
+		assert.Equal(t, testFile, snapshots[0].Path)
+		assert.Equal(t, int64(len(testContent)), snapshots[0].Sent)
+
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer server.Close()
@@ -52,4 +59,5 @@ func TestS3Storage_Upload(t *testing.T) {
 
 	err = storage.Upload(testFile, uploadFile)
 	assert.NoError(t, err)
+	assert.Empty(t, progress.Default.Snapshot())
 }
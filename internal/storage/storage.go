@@ -1,9 +1,26 @@
 package storage
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/kgantsov/synconik/internal/entity"
 )
 
 type Storage interface {
 	Upload(filePath string, file *entity.UploadFile) error
 }
+
+// NewStorage returns the storage backend for an Iconik storage method
+func NewStorage(method string, httpClient *http.Client) (Storage, error) {
+	switch method {
+	case "GCS":
+		return NewGCSStorage(httpClient), nil
+	case "S3":
+		return NewS3Storage(httpClient), nil
+	case "B2":
+		return NewB2Storage(httpClient), nil
+	default:
+		return nil, fmt.Errorf("Unknown storage method: %s", method)
+	}
+}
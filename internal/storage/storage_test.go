@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStorage(t *testing.T) {
+	for method, expected := range map[string]Storage{
+		"GCS": &GCSStorage{},
+		"S3":  &S3Storage{},
+		"B2":  &B2Storage{},
+	} {
+		storage, err := NewStorage(method, http.DefaultClient)
+		assert.NoError(t, err)
+		assert.IsType(t, expected, storage)
+	}
+
+	_, err := NewStorage("FTP", http.DefaultClient)
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/kgantsov/synconik/internal/entity"
@@ -28,11 +30,39 @@ func NewBadgerStore(dir string) (*BadgerStore, error) {
 	return &BadgerStore{db: db}, nil
 }
 
+// NewReadOnlyBadgerStore opens the database for reading, it fails while
+// another process has the database open
+func NewReadOnlyBadgerStore(dir string) (*BadgerStore, error) {
+	opts := badger.DefaultOptions(dir).WithReadOnly(true).WithLogger(nil)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{db: db}, nil
+}
+
+// BadgerStoreExists reports whether a database was created in dir
+func BadgerStoreExists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, badger.ManifestFilename))
+	return err == nil
+}
+
 // Close closes the BadgerStore instance
 func (s *BadgerStore) Close() error {
 	return s.db.Close()
 }
 
+// Ping checks that the database is open and readable
+func (s *BadgerStore) Ping() error {
+	if s.db.IsClosed() {
+		return fmt.Errorf("store is closed")
+	}
+
+	return s.db.View(func(txn *badger.Txn) error {
+		return nil
+	})
+}
+
 // Set sets a key-value pair in the specified bucket
 func (s *BadgerStore) Set(bucket, key string, value []byte) error {
 	return s.db.Update(func(txn *badger.Txn) error {
@@ -100,10 +130,83 @@ func (s *BadgerStore) SaveFile(path string, file *entity.File) error {
 	return s.Set(FILES_BUCKET, path, data)
 }
 
+func (s *BadgerStore) UpdateFile(path string, fn func(file *entity.File) error) error {
+	key := getKey(FILES_BUCKET, path)
+
+	update := func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return ErrFileNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		data, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		file := &entity.File{}
+		if err := file.Unmarshal(data); err != nil {
+			return err
+		}
+		if err := fn(file); err != nil {
+			return err
+		}
+
+		data, err = file.Marshal()
+		if err != nil {
+			return err
+		}
+		return txn.Set(key, data)
+	}
+
+	// the transaction conflicts when the record was written since it was read
+	for attempt := 0; ; attempt++ {
+		err := s.db.Update(update)
+		if err != badger.ErrConflict || attempt == 2 {
+			return err
+		}
+	}
+}
+
 func (s *BadgerStore) DeleteFile(path string) error {
 	return s.Delete(FILES_BUCKET, path)
 }
 
+func (s *BadgerStore) WalkFiles(prefix string, fn func(path string, file *entity.File) error) error {
+	bucketPrefix := getKey(FILES_BUCKET, "")
+
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = getKey(FILES_BUCKET, prefix)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+
+			data, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			file := &entity.File{}
+			if err := file.Unmarshal(data); err != nil {
+				return err
+			}
+
+			if err := fn(string(item.Key()[len(bucketPrefix):]), file); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 // getKey generates the key with the bucket prefix
 func getKey(bucket, key string) []byte {
 	return []byte(fmt.Sprintf("%s:%s", bucket, key))
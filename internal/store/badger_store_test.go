@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"os"
 	"testing"
 
@@ -65,14 +66,106 @@ func TestBadgerStore_FileOperations(t *testing.T) {
 	assert.False(t, exists)
 }
 
+func TestBadgerStore_WalkFiles(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, path := range []string{"photos/a.jpg", "photos/b.jpg", "videos/c.mov"} {
+		err := store.SaveFile(path, &entity.File{Name: path})
+		assert.NoError(t, err)
+	}
+
+	paths := []string{}
+	err := store.WalkFiles("photos/", func(path string, file *entity.File) error {
+		assert.Equal(t, path, file.Name)
+		paths = append(paths, path)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"photos/a.jpg", "photos/b.jpg"}, paths)
+
+	count := 0
+	err = store.WalkFiles("", func(path string, file *entity.File) error {
+		count++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestBadgerStore_UpdateFile(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	assert.NoError(t, store.SaveFile("clip.mov", &entity.File{Name: "clip.mov", State: entity.StateSynced}))
+
+	err := store.UpdateFile("clip.mov", func(file *entity.File) error {
+		file.TranscodeStatus = entity.TranscodeStatusFinished
+		return nil
+	})
+	assert.NoError(t, err)
+
+	file, err := store.GetFile("clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, entity.TranscodeStatusFinished, file.TranscodeStatus)
+
+	// the record is unchanged when fn fails
+	err = store.UpdateFile("clip.mov", func(file *entity.File) error {
+		file.State = entity.StateFailed
+		return errors.New("stale")
+	})
+	assert.EqualError(t, err, "stale")
+
+	file, err = store.GetFile("clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+
+	err = store.UpdateFile("missing.mov", func(file *entity.File) error {
+		t.Fatal("called for a missing record")
+		return nil
+	})
+	assert.Equal(t, ErrFileNotFound, err)
+}
+
 func TestBadgerStore_Close(t *testing.T) {
 	store, _, cleanup := setupTestDB(t)
 	defer cleanup()
 
+	assert.NoError(t, store.Ping())
+
 	err := store.Close()
 	assert.NoError(t, err)
+	assert.Error(t, store.Ping())
 
 	// Verify store is closed by attempting an operation
 	_, err = store.GetFile("/test.txt")
 	assert.Error(t, err)
 }
+
+func TestBadgerStore_ReadOnly(t *testing.T) {
+	store, tmpDir, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	err := store.SaveFile("rushes/clip.mov", &entity.File{Name: "clip.mov"})
+	assert.NoError(t, err)
+
+	assert.True(t, BadgerStoreExists(tmpDir))
+	assert.False(t, BadgerStoreExists(t.TempDir()))
+
+	// the database is locked while it is open for writing
+	_, err = NewReadOnlyBadgerStore(tmpDir)
+	assert.Error(t, err)
+
+	assert.NoError(t, store.Close())
+
+	readOnly, err := NewReadOnlyBadgerStore(tmpDir)
+	assert.NoError(t, err)
+	defer readOnly.Close()
+
+	file, err := readOnly.GetFile("rushes/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "clip.mov", file.Name)
+
+	assert.Error(t, readOnly.SaveFile("rushes/other.mov", &entity.File{Name: "other.mov"}))
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kgantsov/synconik/internal/entity"
+)
+
+// OverlayStore keeps writes in memory on top of a base store that is only
+// read, e.g. to run the pipeline without changing the database. The base may
+// be nil for an empty store.
+type OverlayStore struct {
+	base Store
+
+	mu      sync.RWMutex
+	files   map[string]*entity.File
+	deleted map[string]bool
+}
+
+func NewOverlayStore(base Store) *OverlayStore {
+	return &OverlayStore{
+		base:    base,
+		files:   map[string]*entity.File{},
+		deleted: map[string]bool{},
+	}
+}
+
+func (s *OverlayStore) GetFile(path string) (*entity.File, error) {
+	s.mu.RLock()
+	file, ok := s.files[path]
+	deleted := s.deleted[path]
+	s.mu.RUnlock()
+
+	if ok {
+		return copyFile(file), nil
+	}
+	if deleted || s.base == nil {
+		return nil, ErrFileNotFound
+	}
+
+	return s.base.GetFile(path)
+}
+
+func (s *OverlayStore) ExistsFile(path string) (bool, error) {
+	_, err := s.GetFile(path)
+	if err == ErrFileNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *OverlayStore) SaveFile(path string, file *entity.File) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[path] = copyFile(file)
+	delete(s.deleted, path)
+	return nil
+}
+
+func (s *OverlayStore) UpdateFile(path string, fn func(file *entity.File) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, ok := s.files[path]
+	if ok {
+		file = copyFile(file)
+	} else {
+		if s.deleted[path] || s.base == nil {
+			return ErrFileNotFound
+		}
+
+		var err error
+		if file, err = s.base.GetFile(path); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(file); err != nil {
+		return err
+	}
+
+	s.files[path] = file
+	return nil
+}
+
+func (s *OverlayStore) DeleteFile(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.files, path)
+	s.deleted[path] = true
+	return nil
+}
+
+func (s *OverlayStore) WalkFiles(prefix string, fn func(path string, file *entity.File) error) error {
+	files := map[string]*entity.File{}
+
+	if s.base != nil {
+		err := s.base.WalkFiles(prefix, func(path string, file *entity.File) error {
+			files[path] = file
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mu.RLock()
+	for path := range s.deleted {
+		delete(files, path)
+	}
+	for path, file := range s.files {
+		if strings.HasPrefix(path, prefix) {
+			files[path] = copyFile(file)
+		}
+	}
+	s.mu.RUnlock()
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := fn(path, files[path]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Changes returns the records saved in the overlay by path
+func (s *OverlayStore) Changes() map[string]*entity.File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := make(map[string]*entity.File, len(s.files))
+	for path, file := range s.files {
+		changes[path] = copyFile(file)
+	}
+	return changes
+}
+
+// copyFile keeps callers from changing the stored records, like the
+// serialised records of the other stores
+func copyFile(file *entity.File) *entity.File {
+	c := *file
+	return &c
+}
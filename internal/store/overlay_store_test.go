@@ -0,0 +1,78 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlayStore(t *testing.T) {
+	base, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, path := range []string{"rushes/a.mov", "rushes/b.mov", "stills/c.jpg"} {
+		assert.NoError(t, base.SaveFile(path, &entity.File{Name: path}))
+	}
+
+	overlay := NewOverlayStore(base)
+
+	file, err := overlay.GetFile("rushes/a.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "rushes/a.mov", file.Name)
+
+	assert.NoError(t, overlay.SaveFile("rushes/a.mov", &entity.File{Name: "changed"}))
+	assert.NoError(t, overlay.SaveFile("rushes/new.mov", &entity.File{Name: "new"}))
+	assert.NoError(t, overlay.DeleteFile("rushes/b.mov"))
+
+	file, err = overlay.GetFile("rushes/a.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", file.Name)
+
+	_, err = overlay.GetFile("rushes/b.mov")
+	assert.Equal(t, ErrFileNotFound, err)
+
+	exists, err := overlay.ExistsFile("rushes/new.mov")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	paths := []string{}
+	err = overlay.WalkFiles("rushes/", func(path string, file *entity.File) error {
+		paths = append(paths, path)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rushes/a.mov", "rushes/new.mov"}, paths)
+
+	// the base store is unchanged
+	file, err = base.GetFile("rushes/a.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "rushes/a.mov", file.Name)
+
+	exists, err = base.ExistsFile("rushes/b.mov")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.Len(t, overlay.Changes(), 2)
+
+	err = overlay.UpdateFile("stills/c.jpg", func(file *entity.File) error {
+		file.TranscodeStatus = entity.TranscodeStatusFinished
+		return nil
+	})
+	assert.NoError(t, err)
+	file, err = overlay.GetFile("stills/c.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "stills/c.jpg", file.Name)
+	assert.Equal(t, entity.TranscodeStatusFinished, file.TranscodeStatus)
+
+	file, err = base.GetFile("stills/c.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "", file.TranscodeStatus)
+
+	err = overlay.UpdateFile("rushes/b.mov", func(file *entity.File) error { return nil })
+	assert.Equal(t, ErrFileNotFound, err)
+
+	empty := NewOverlayStore(nil)
+	_, err = empty.GetFile("rushes/a.mov")
+	assert.Equal(t, ErrFileNotFound, err)
+}
@@ -7,5 +7,10 @@ type Store interface {
 	GetFile(path string) (*entity.File, error)
 	ExistsFile(path string) (bool, error)
 	SaveFile(path string, file *entity.File) error
+	// UpdateFile changes the stored record with fn at once, fn is not called
+	// and ErrFileNotFound returned when there is none
+	UpdateFile(path string, fn func(file *entity.File) error) error
 	DeleteFile(path string) error
+	// WalkFiles calls fn for every file whose path starts with prefix
+	WalkFiles(prefix string, fn func(path string, file *entity.File) error) error
 }
@@ -0,0 +1,90 @@
+package transcoder
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultInterval = 60
+
+// Transcoder periodically triggers transcoding of files that were deferred
+// to the batch window and follows the status of transcode jobs
+type Transcoder struct {
+	config *config.Config
+
+	transcodeUseCase *usecase.TranscodeUseCase
+
+	done    chan bool
+	running atomic.Bool
+}
+
+func NewTranscoder(config *config.Config, store store.Store, client icnk_client.Client) *Transcoder {
+	return &Transcoder{
+		config: config,
+
+		transcodeUseCase: usecase.NewTranscodeUseCase(config, client, store),
+
+		done: make(chan bool),
+	}
+}
+
+func (t *Transcoder) start() {
+	interval := t.config.Transcoding.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	defer t.running.Store(false)
+
+	for {
+		select {
+		case <-ticker.C:
+			t.Run()
+		case <-t.done:
+			log.Debug().Str("service", "transcoder").Msg("Stopped the transcoder")
+			return
+		}
+	}
+}
+
+func (t *Transcoder) Start() {
+	log.Debug().Str("service", "transcoder").Msg("Starting the transcoder")
+
+	t.running.Store(true)
+	go t.start()
+}
+
+// Running reports whether the transcoder loop is running
+func (t *Transcoder) Running() bool {
+	return t.running.Load()
+}
+
+func (t *Transcoder) Stop() {
+	log.Debug().Str("service", "transcoder").Msg("Stopping the transcoder")
+
+	close(t.done)
+}
+
+// Run triggers the deferred transcodes and polls the transcode jobs once
+func (t *Transcoder) Run() {
+	ctx := context.Background()
+
+	err := t.transcodeUseCase.TriggerDeferred(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("service", "transcoder").Msg("Error triggering deferred transcoding")
+	}
+
+	err = t.transcodeUseCase.PollJobs(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("service", "transcoder").Msg("Error polling transcode jobs")
+	}
+}
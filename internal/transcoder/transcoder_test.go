@@ -0,0 +1,59 @@
+package transcoder
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTranscoder(t *testing.T) {
+	tmpDbDir, err := os.MkdirTemp("", "transcoder-test-db-*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDbDir)
+
+	store, err := store.NewBadgerStore(tmpDbDir)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	err = store.SaveFile("clip.mov", &entity.File{
+		AssetID:           "47265105-BE2B-4C3F-8997-66BAB2893D0D",
+		ID:                "D025605F-CF64-4EE5-9F48-E6DD5D363473",
+		TranscodeStatus:   entity.TranscodeStatusDeferred,
+		TranscodePriority: 5,
+	})
+	assert.NoError(t, err)
+
+	mockClient := client.NewMockClient()
+	mockClient.On(
+		"TriggerTranscoding",
+		mock.Anything,
+		"47265105-BE2B-4C3F-8997-66BAB2893D0D",
+		"D025605F-CF64-4EE5-9F48-E6DD5D363473",
+		mock.Anything,
+	).Return("C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8", nil)
+	mockClient.On(
+		"GetJob",
+		mock.Anything,
+		"C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8",
+	).Return(&client.Job{ID: "C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8", Status: client.JobStatusFinished}, nil)
+
+	transcoder := NewTranscoder(&config.Config{}, store, mockClient)
+	transcoder.Start()
+
+	transcoder.Run()
+
+	file, err := store.GetFile("clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusFinished, file.TranscodeStatus)
+	assert.Equal(t, "C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8", file.TranscodeJobID)
+
+	transcoder.Stop()
+	_, ok := <-transcoder.done
+	assert.False(t, ok, "done channel should be closed")
+}
@@ -2,7 +2,10 @@ package uploader
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/kgantsov/synconik/internal/config"
 	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
@@ -18,8 +21,18 @@ type Uploader struct {
 	quit       chan bool
 	Workers    []*Worker
 
-	store  store.Store
-	client icnk_client.Client
+	store   store.Store
+	client  icnk_client.Client
+	storage *icnk_client.Storage
+
+	// mu guards Workers, MaxWorkers and the pause state
+	mu           sync.Mutex
+	scaleMu      sync.Mutex
+	nextWorkerID int
+	paused       bool
+	resumed      chan struct{}
+
+	running atomic.Bool
 }
 
 func NewUploader(
@@ -30,7 +43,9 @@ func NewUploader(
 	if numberOfWorkers <= 0 {
 		numberOfWorkers = 1
 	}
-	WorkerPool := make(chan chan Job, numberOfWorkers)
+	// the pool is unbuffered so that a worker only leaves it by being handed
+	// a job or by being retired
+	WorkerPool := make(chan chan Job)
 
 	return &Uploader{
 		Config:     config,
@@ -52,33 +67,185 @@ func (u *Uploader) Start() error {
 		log.Error().Err(err).Str("service", "uploader").Msgf("Error getting storage")
 		return err
 	}
+	u.storage = storage
 
 	// Start the uploader
+	u.mu.Lock()
 	for i := 0; i < u.MaxWorkers; i++ {
-		worker := NewWorker(
-			u.Config,
-			u.store,
-			u.client,
-			fmt.Sprintf("worker-%d", i),
-			u.WorkerPool,
-			storage,
-		)
-		worker.Start()
-
-		u.Workers = append(u.Workers, worker)
+		u.startWorker()
 	}
+	u.mu.Unlock()
 
+	u.running.Store(true)
 	go u.dispatch()
 
 	return nil
 }
 
+// Running reports whether the uploader dispatches jobs to the workers
+func (u *Uploader) Running() bool {
+	return u.running.Load()
+}
+
+// Pause stops handing new jobs to the workers, jobs in progress finish
+func (u *Uploader) Pause() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.paused {
+		return
+	}
+	u.paused = true
+	u.resumed = make(chan struct{})
+
+	log.Info().Str("service", "uploader").Msg("Paused uploader")
+}
+
+// Resume continues handing jobs to the workers
+func (u *Uploader) Resume() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.paused {
+		return
+	}
+	u.paused = false
+	close(u.resumed)
+
+	log.Info().Str("service", "uploader").Msg("Resumed uploader")
+}
+
+func (u *Uploader) Paused() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.paused
+}
+
+// WorkerCount returns the number of running workers
+func (u *Uploader) WorkerCount() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return len(u.Workers)
+}
+
+// SetWorkers starts or retires workers until n are running. Workers are only
+// retired once they are idle, so scaling down waits for jobs in progress.
+func (u *Uploader) SetWorkers(ctx context.Context, n int) error {
+	if n <= 0 {
+		return errors.New("number of workers must be positive")
+	}
+	if !u.Running() {
+		return errors.New("uploader is not running")
+	}
+
+	u.scaleMu.Lock()
+	defer u.scaleMu.Unlock()
+
+	u.mu.Lock()
+	for len(u.Workers) < n {
+		u.startWorker()
+	}
+	u.MaxWorkers = n
+	u.mu.Unlock()
+
+	for u.WorkerCount() > n {
+		select {
+		case jobChannel := <-u.WorkerPool:
+			u.retireWorker(jobChannel)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-u.quit:
+			return errors.New("uploader is stopped")
+		}
+	}
+
+	log.Info().Str("service", "uploader").Msgf("Running %d workers", n)
+
+	return nil
+}
+
+// InFlight returns the jobs the workers are processing
+func (u *Uploader) InFlight() []InFlightJob {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	jobs := []InFlightJob{}
+	for _, worker := range u.Workers {
+		if job, ok := worker.Current(); ok {
+			jobs = append(jobs, job)
+		}
+	}
+
+	return jobs
+}
+
+// startWorker must be called with mu held
+func (u *Uploader) startWorker() {
+	worker := NewWorker(
+		u.Config,
+		u.store,
+		u.client,
+		fmt.Sprintf("worker-%d", u.nextWorkerID),
+		u.WorkerPool,
+		u.storage,
+	)
+	u.nextWorkerID++
+	worker.Start()
+
+	u.Workers = append(u.Workers, worker)
+}
+
+// retireWorker stops the idle worker that owns the job channel
+func (u *Uploader) retireWorker(jobChannel chan Job) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for i, worker := range u.Workers {
+		if worker.JobChannel == jobChannel {
+			worker.Stop()
+			u.Workers = append(u.Workers[:i], u.Workers[i+1:]...)
+			return
+		}
+	}
+}
+
+// waitResumed blocks while the uploader is paused, it returns false when the
+// uploader is stopped
+func (u *Uploader) waitResumed() bool {
+	for {
+		u.mu.Lock()
+		paused, resumed := u.paused, u.resumed
+		u.mu.Unlock()
+
+		if !paused {
+			return true
+		}
+
+		select {
+		case <-resumed:
+		case <-u.quit:
+			return false
+		}
+	}
+}
+
 func (u *Uploader) dispatch() {
+	defer u.running.Store(false)
+
 	for {
 		select {
+		case <-u.quit:
+			return
 		case job := <-u.JobQueue:
 			// a job request has been received
 			go func(job Job) {
+				if !u.waitResumed() {
+					job.Payload.WG.Done()
+					return
+				}
+
 				// try to obtain a worker job channel that is available.
 				// this will block until a worker is idle
 				jobChannel := <-u.WorkerPool
@@ -91,9 +258,12 @@ func (u *Uploader) dispatch() {
 }
 
 func (u *Uploader) Stop() {
+	u.mu.Lock()
 	for _, worker := range u.Workers {
 		worker.Stop()
 	}
+	u.mu.Unlock()
+
 	log.Debug().Str("service", "uploader").Msg("Stopped uploader")
 	close(u.quit)
 }
@@ -1,8 +1,12 @@
 package uploader
 
 import (
+	"context"
+	"errors"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kgantsov/synconik/internal/config"
 	"github.com/kgantsov/synconik/internal/iconik/client"
@@ -42,3 +46,103 @@ func TestUploader(t *testing.T) {
 
 	uploader.Stop()
 }
+
+func setupTestUploader(t *testing.T, workers int) (*Uploader, *client.MockClient, func()) {
+	cfg := &config.Config{}
+	cfg.Scanner.Dir = t.TempDir()
+	cfg.Uploader.Workers = workers
+	cfg.Iconik.StorageID = "5B4BAE0D-5E07-4B36-A2C2-0DF79F558F6F"
+
+	store, err := store.NewBadgerStore(t.TempDir())
+	assert.NoError(t, err)
+
+	mockClient := client.NewMockClient()
+	mockClient.On(
+		"GetStorage", mock.Anything, "5B4BAE0D-5E07-4B36-A2C2-0DF79F558F6F",
+	).Return(&client.Storage{ID: "5B4BAE0D-5E07-4B36-A2C2-0DF79F558F6F", Method: "S3"}, nil)
+
+	uploader := NewUploader(cfg, store, mockClient, make(chan Job, 100))
+	err = uploader.Start()
+	assert.NoError(t, err)
+
+	cleanup := func() {
+		uploader.Stop()
+		store.Close()
+	}
+
+	return uploader, mockClient, cleanup
+}
+
+func TestUploader_SetWorkers(t *testing.T) {
+	uploader, _, cleanup := setupTestUploader(t, 2)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	assert.NoError(t, uploader.SetWorkers(ctx, 4))
+	assert.Equal(t, 4, uploader.WorkerCount())
+	assert.Equal(t, "worker-3", uploader.Workers[3].Name)
+
+	assert.NoError(t, uploader.SetWorkers(ctx, 1))
+	assert.Equal(t, 1, uploader.WorkerCount())
+
+	assert.Error(t, uploader.SetWorkers(ctx, 0))
+}
+
+func TestUploader_PauseResume(t *testing.T) {
+	uploader, mockClient, cleanup := setupTestUploader(t, 1)
+	defer cleanup()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockClient.On("CreateAsset", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		started <- struct{}{}
+		<-release
+	}).Return(nil, errors.New("asset creation failed"))
+
+	path := "/clip.mov"
+	err := os.WriteFile(uploader.Config.Scanner.Dir+path, []byte("data"), 0644)
+	assert.NoError(t, err)
+	info, err := os.Stat(uploader.Config.Scanner.Dir + path)
+	assert.NoError(t, err)
+
+	uploader.Pause()
+	assert.True(t, uploader.Paused())
+
+	var wg sync.WaitGroup
+	var uploadErr error
+	wg.Add(1)
+	uploader.JobQueue <- Job{Payload: Payload{
+		Path:   path,
+		Info:   info,
+		WG:     &wg,
+		OnDone: func(err error) { uploadErr = err },
+	}}
+
+	select {
+	case <-started:
+		t.Fatal("job started while the uploader is paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Empty(t, uploader.InFlight())
+
+	uploader.Resume()
+	assert.False(t, uploader.Paused())
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job did not start after resuming the uploader")
+	}
+
+	jobs := uploader.InFlight()
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, "worker-0", jobs[0].Worker)
+	assert.Equal(t, path, jobs[0].Path)
+	assert.Equal(t, int64(4), jobs[0].Size)
+
+	close(release)
+	wg.Wait()
+	assert.Empty(t, uploader.InFlight())
+	assert.EqualError(t, uploadErr, "asset creation failed")
+}
@@ -3,9 +3,14 @@ package uploader
 import (
 	"os"
 	"sync"
+	"time"
 
+	"github.com/kgantsov/synconik/internal/card"
 	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/group"
 	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/metrics"
+	"github.com/kgantsov/synconik/internal/sequence"
 	"github.com/kgantsov/synconik/internal/store"
 	"github.com/kgantsov/synconik/internal/usecase"
 	"github.com/rs/zerolog/log"
@@ -14,13 +19,31 @@ import (
 type Payload struct {
 	Path string
 	Info os.FileInfo
-	WG   *sync.WaitGroup
+	// Sequence is set for image sequences uploaded as one asset
+	Sequence *sequence.Sequence
+	// Group is set for files uploaded as the formats of one asset
+	Group *group.Group
+	// Clip is set for camera card clips uploaded as one asset
+	Clip *card.Clip
+	// Archive is set for archives whose members are uploaded
+	Archive bool
+	WG      *sync.WaitGroup
+	// OnDone is called with the result of the upload before WG is done
+	OnDone func(err error)
 }
 
 type Job struct {
 	Payload Payload
 }
 
+// InFlightJob describes a job a worker is processing
+type InFlightJob struct {
+	Worker    string    `json:"worker"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	StartedAt time.Time `json:"started_at"`
+}
+
 type Worker struct {
 	Config *config.Config
 
@@ -35,6 +58,9 @@ type Worker struct {
 	client icnk_client.Client
 
 	assetUseCase *usecase.AssetUseCase
+
+	mu      sync.Mutex
+	current *InFlightJob
 }
 
 func NewWorker(
@@ -65,38 +91,23 @@ func NewWorker(
 func (w *Worker) Start() {
 	log.Info().Str("service", "uploader").Str("worker", w.Name).Msg("Starting worker")
 
+	metrics.Workers.WithLabelValues(metrics.WorkerIdle).Inc()
+
 	go func() {
+		defer metrics.Workers.WithLabelValues(metrics.WorkerIdle).Dec()
+
 		for {
 			// register the current worker into the worker queue.
-			w.WorkerPool <- w.JobChannel
+			select {
+			case w.WorkerPool <- w.JobChannel:
+			case <-w.quit:
+				log.Debug().Str("service", "uploader").Str("worker", w.Name).Msg("Stopped worker")
+				return
+			}
 
 			select {
 			case job := <-w.JobChannel:
-				log.Info().
-					Str("service", "uploader").
-					Str("worker", w.Name).
-					Str("path", job.Payload.Path).
-					Msgf("Got a job")
-
-				err := w.assetUseCase.UploadIfNotExists(job.Payload.Path, job.Payload.Info)
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("service", "uploader").
-						Str("worker", w.Name).
-						Str("path", job.Payload.Path).
-						Msg(
-							"Error creating asset",
-						)
-				}
-
-				log.Info().
-					Str("service", "uploader").
-					Str("worker", w.Name).
-					Str("path", job.Payload.Path).
-					Msg("Job done")
-
-				job.Payload.WG.Done()
+				w.process(job)
 
 			case <-w.quit:
 				// we have received a signal to stop
@@ -107,6 +118,77 @@ func (w *Worker) Start() {
 	}()
 }
 
+// Current returns the job the worker is processing
+func (w *Worker) Current() (InFlightJob, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.current == nil {
+		return InFlightJob{}, false
+	}
+	return *w.current, true
+}
+
+func (w *Worker) process(job Job) {
+	metrics.Workers.WithLabelValues(metrics.WorkerIdle).Dec()
+	metrics.Workers.WithLabelValues(metrics.WorkerBusy).Inc()
+
+	inFlight := &InFlightJob{Worker: w.Name, Path: job.Payload.Path, StartedAt: time.Now()}
+	if job.Payload.Info != nil {
+		inFlight.Size = job.Payload.Info.Size()
+	}
+	w.mu.Lock()
+	w.current = inFlight
+	w.mu.Unlock()
+
+	log.Info().
+		Str("service", "uploader").
+		Str("worker", w.Name).
+		Str("path", job.Payload.Path).
+		Msgf("Got a job")
+
+	var err error
+	if job.Payload.Sequence != nil {
+		err = w.assetUseCase.UploadSequenceIfNotExists(job.Payload.Path, job.Payload.Sequence)
+	} else if job.Payload.Group != nil {
+		err = w.assetUseCase.UploadGroupIfNotExists(job.Payload.Path, job.Payload.Group)
+	} else if job.Payload.Clip != nil {
+		err = w.assetUseCase.UploadClipIfNotExists(job.Payload.Path, job.Payload.Clip)
+	} else if job.Payload.Archive {
+		err = w.assetUseCase.IngestArchiveIfNotExists(job.Payload.Path, job.Payload.Info)
+	} else {
+		err = w.assetUseCase.UploadIfNotExists(job.Payload.Path, job.Payload.Info)
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("service", "uploader").
+			Str("worker", w.Name).
+			Str("path", job.Payload.Path).
+			Msg(
+				"Error creating asset",
+			)
+	}
+
+	log.Info().
+		Str("service", "uploader").
+		Str("worker", w.Name).
+		Str("path", job.Payload.Path).
+		Msg("Job done")
+
+	w.mu.Lock()
+	w.current = nil
+	w.mu.Unlock()
+
+	if job.Payload.OnDone != nil {
+		job.Payload.OnDone(err)
+	}
+	job.Payload.WG.Done()
+
+	metrics.Workers.WithLabelValues(metrics.WorkerBusy).Dec()
+	metrics.Workers.WithLabelValues(metrics.WorkerIdle).Inc()
+}
+
 // Stop signals the worker to stop listening for work requests.
 func (w *Worker) Stop() {
 	log.Debug().Str("service", "uploader").Str("worker", w.Name).Msg("Stopping worker")
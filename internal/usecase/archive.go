@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kgantsov/synconik/internal/archive"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/rs/zerolog/log"
+)
+
+// IngestArchiveIfNotExists uploads the members of an archive found by the
+// scanner unless the archive was already ingested
+func (uc *AssetUseCase) IngestArchiveIfNotExists(path string, info os.FileInfo) error {
+	reason, err := uc.filterUseCase.Skip(path, nil)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		log.Debug().Str("service", "asset_usecase").Msgf("Skipping %s archive: %s", reason, path)
+		return nil
+	}
+
+	return uc.IngestArchive(path, info)
+}
+
+// IngestArchive uploads each member of the archive as an asset in a
+// collection named after the archive, the directories of the archive become
+// nested collections and the members are stored under the archive key, e.g.
+// delivery.zip/shots/a001.mov. Members are extracted one at a time into a
+// temporary file removed after its upload, and members that are already
+// synced are skipped. The archive record holds the state of the ingestion.
+func (uc *AssetUseCase) IngestArchive(path string, info os.FileInfo) error {
+	record, err := uc.archiveCollection(path, info)
+	if err != nil {
+		return uc.saveResult(path, NewFileRecord(path, info), err)
+	}
+
+	record.Size = int(info.Size())
+	record.SetState(entity.StateUploading, nil)
+	if err := uc.store.SaveFile(path, record); err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msg("Error saving file")
+	}
+
+	tempDir, err := os.MkdirTemp(uc.config.Scanner.Archives.TempDir, "synconik-archive-")
+	if err != nil {
+		return uc.saveArchive(path, record, err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	total, failed := 0, 0
+	var firstErr error
+
+	failure := func(name string, err error) {
+		failed++
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	err = archive.Walk(LocalPath(uc.config, path), func(member archive.Member, r io.Reader) error {
+		total++
+		if member.Err != nil {
+			log.Warn().Err(member.Err).Str("service", "asset_usecase").Msgf("Skipping archive member: %s", path)
+			failure(member.Path, member.Err)
+			return nil
+		}
+		if err := uc.ingestMember(path, member, r, tempDir); err != nil {
+			failure(member.Path, err)
+		}
+		return nil
+	})
+
+	if err == nil && uc.config.Scanner.Archives.UploadOriginal {
+		total++
+		if err := uc.ingestOriginal(path, info); err != nil {
+			failure(info.Name(), err)
+		}
+	}
+
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d of %d members failed, %w", failed, total, firstErr)
+	}
+
+	return uc.saveArchive(path, record, err)
+}
+
+// archiveCollection returns the record of the collection of the archive,
+// which is created on the first ingestion
+func (uc *AssetUseCase) archiveCollection(path string, info os.FileInfo) (*entity.File, error) {
+	record, err := uc.store.GetFile(path)
+	if err == nil && record.IsDir() {
+		return record, nil
+	}
+	if err != nil && !errors.Is(err, store.ErrFileNotFound) {
+		return nil, err
+	}
+
+	return uc.collectionUseCase.createCollection(path, info.Name())
+}
+
+// saveArchive records the result of the ingestion of an archive
+func (uc *AssetUseCase) saveArchive(path string, record *entity.File, ingestErr error) error {
+	if ingestErr != nil {
+		record.SetState(entity.StateFailed, ingestErr)
+	} else {
+		record.SetState(entity.StateSynced, nil)
+	}
+
+	if err := uc.store.SaveFile(path, record); err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msg("Error saving file")
+	}
+
+	return ingestErr
+}
+
+// ingestMember extracts a member of the archive and uploads it
+func (uc *AssetUseCase) ingestMember(archivePath string, member archive.Member, r io.Reader, tempDir string) error {
+	key := archivePath + "/" + member.Path
+
+	reason, err := uc.filterUseCase.Skip(key, nil)
+	if err != nil {
+		return err
+	}
+	if reason == SkipSynced {
+		return nil
+	}
+
+	if err := uc.archiveDirs(archivePath, member.Path); err != nil {
+		return err
+	}
+
+	localPath := filepath.Join(tempDir, member.Info.Name())
+	if err := extract(localPath, r); err != nil {
+		return err
+	}
+	defer os.Remove(localPath)
+
+	return uc.upload(key, member.Info, UploadOptions{LocalPath: localPath, Archive: archivePath})
+}
+
+// ingestOriginal uploads the archive itself into its collection
+func (uc *AssetUseCase) ingestOriginal(archivePath string, info os.FileInfo) error {
+	key := archivePath + "/" + info.Name()
+
+	reason, err := uc.filterUseCase.Skip(key, nil)
+	if err != nil {
+		return err
+	}
+	if reason == SkipSynced {
+		return nil
+	}
+
+	return uc.upload(key, info, UploadOptions{LocalPath: LocalPath(uc.config, archivePath), Archive: archivePath})
+}
+
+// archiveDirs creates the collections of the directories of a member
+func (uc *AssetUseCase) archiveDirs(archivePath, memberPath string) error {
+	dirs := strings.Split(memberPath, "/")
+	key := archivePath
+
+	for _, dir := range dirs[:len(dirs)-1] {
+		key += "/" + dir
+
+		exists, err := uc.store.ExistsFile(key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		file, err := uc.collectionUseCase.createCollection(key, dir)
+		if err != nil {
+			return err
+		}
+
+		file.Archive = archivePath
+		if err := uc.store.SaveFile(key, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extract(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
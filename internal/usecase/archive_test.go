@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupArchive(t *testing.T, uploadOriginal bool) (*config.Config, os.FileInfo) {
+	dir := t.TempDir() + "/"
+
+	f, err := os.Create(dir + "delivery.zip")
+	assert.NoError(t, err)
+	w := zip.NewWriter(f)
+	for name, content := range map[string]string{"readme.txt": "hello", "shots/a001.mov": "video"} {
+		fw, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.NoError(t, f.Close())
+
+	info, err := os.Stat(dir + "delivery.zip")
+	assert.NoError(t, err)
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{
+			Dir: dir,
+			Archives: config.ArchivesConfig{
+				Enabled: true, UploadOriginal: uploadOriginal, TempDir: t.TempDir(),
+			},
+		},
+	}
+
+	return cfg, info
+}
+
+func mockArchiveUploads(mockClient *client.MockClient) {
+	mockClient.On("CreateCollection", mock.Anything, &icnk_client.Collection{Title: "delivery.zip"}).
+		Return(&icnk_client.Collection{ID: "C1"}, nil).Once()
+	mockClient.On("CreateCollection", mock.Anything, &icnk_client.Collection{Title: "shots", ParentID: "C1"}).
+		Return(&icnk_client.Collection{ID: "C2"}, nil).Once()
+	mockClient.On("CreateAssetFormat", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.Format{ID: "FMT"}, nil)
+	mockClient.On("CreateFileSet", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.FileSet{ID: "FS"}, nil)
+	mockClient.On("CreateFile", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.File{ID: "F"}, nil)
+	mockClient.On("CloseFile", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("TriggerTranscoding", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("J1", nil)
+}
+
+func TestIngestArchive(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, info := setupArchive(t, true)
+
+	mockClient := client.NewMockClient()
+	mockArchiveUploads(mockClient)
+	mockClient.On("CreateAsset", mock.Anything, mock.Anything).Return(&icnk_client.Asset{ID: "A1"}, nil)
+
+	// members are extracted for their upload
+	uploaded := map[string]string{}
+	paths := []string{}
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.MatchedBy(func(path string) bool {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		uploaded[filepath.Base(path)] = string(data)
+		paths = append(paths, path)
+		return true
+	}), mock.Anything).Return(nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+	assert.NoError(t, assetUseCase.IngestArchiveIfNotExists("delivery.zip", info))
+
+	mockClient.AssertCalled(t, "CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "a001.mov", Status: "ACTIVE", Type: "ASSET", CollectionID: "C2",
+	})
+	mockClient.AssertCalled(t, "CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "readme.txt", Status: "ACTIVE", Type: "ASSET", CollectionID: "C1",
+	})
+	mockClient.AssertCalled(t, "CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "delivery.zip", Status: "ACTIVE", Type: "ASSET", CollectionID: "C1",
+	})
+	assert.Equal(t, "hello", uploaded["readme.txt"])
+	assert.Equal(t, "video", uploaded["a001.mov"])
+	assert.Contains(t, paths, cfg.Scanner.Dir+"delivery.zip")
+
+	record, err := store.GetFile("delivery.zip")
+	assert.NoError(t, err)
+	assert.True(t, record.IsDir())
+	assert.Equal(t, "C1", record.ID)
+	assert.Equal(t, entity.StateSynced, record.State)
+
+	dir, err := store.GetFile("delivery.zip/shots")
+	assert.NoError(t, err)
+	assert.Equal(t, "C2", dir.ID)
+	assert.Equal(t, "delivery.zip", dir.Archive)
+
+	for _, key := range []string{"delivery.zip/readme.txt", "delivery.zip/shots/a001.mov", "delivery.zip/delivery.zip"} {
+		member, err := store.GetFile(key)
+		assert.NoError(t, err, key)
+		assert.Equal(t, entity.StateSynced, member.State, key)
+		assert.Equal(t, "delivery.zip", member.Archive, key)
+	}
+
+	// extracted members are removed
+	entries, err := os.ReadDir(cfg.Scanner.Archives.TempDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// ingested archives are skipped
+	assert.NoError(t, assetUseCase.IngestArchiveIfNotExists("delivery.zip", info))
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 3)
+}
+
+func TestIngestArchiveFailedMember(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, info := setupArchive(t, false)
+
+	mockClient := client.NewMockClient()
+	mockArchiveUploads(mockClient)
+	mockClient.On("CreateAsset", mock.Anything, mock.MatchedBy(func(asset *icnk_client.Asset) bool {
+		return asset.Title == "a001.mov"
+	})).Return(nil, errors.New("request failed: forbidden")).Once()
+	mockClient.On("CreateAsset", mock.Anything, mock.Anything).Return(&icnk_client.Asset{ID: "A1"}, nil)
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+	err := assetUseCase.IngestArchiveIfNotExists("delivery.zip", info)
+	assert.EqualError(t, err, "1 of 2 members failed, shots/a001.mov: request failed: forbidden")
+
+	record, err := store.GetFile("delivery.zip")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, record.State)
+
+	// failed archives wait to be requeued
+	assert.NoError(t, assetUseCase.IngestArchiveIfNotExists("delivery.zip", info))
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 2)
+
+	// ingesting the archive again only uploads the failed member
+	assert.NoError(t, assetUseCase.IngestArchive("delivery.zip", info))
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 3)
+	mockClient.AssertNumberOfCalls(t, "CreateCollection", 2)
+
+	record, err = store.GetFile("delivery.zip")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, record.State)
+	assert.Equal(t, "C1", record.ID)
+}
+
+func TestIngestArchiveUnsafeMember(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupArchive(t, false)
+
+	// the unsafe member comes first, the members after it are still ingested
+	f, err := os.Create(cfg.Scanner.Dir + "delivery.zip")
+	assert.NoError(t, err)
+	w := zip.NewWriter(f)
+	for _, name := range []string{"../escape.txt", "readme.txt", "shots/a001.mov"} {
+		fw, err := w.Create(name)
+		assert.NoError(t, err)
+		_, err = fw.Write([]byte(name))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.NoError(t, f.Close())
+
+	info, err := os.Stat(cfg.Scanner.Dir + "delivery.zip")
+	assert.NoError(t, err)
+
+	mockClient := client.NewMockClient()
+	mockArchiveUploads(mockClient)
+	mockClient.On("CreateAsset", mock.Anything, mock.Anything).Return(&icnk_client.Asset{ID: "A1"}, nil)
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+	err = assetUseCase.IngestArchiveIfNotExists("delivery.zip", info)
+	assert.EqualError(t, err, `1 of 3 members failed, ../escape.txt: unsafe member path "../escape.txt"`)
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 2)
+
+	for _, key := range []string{"delivery.zip/readme.txt", "delivery.zip/shots/a001.mov"} {
+		member, err := store.GetFile(key)
+		assert.NoError(t, err, key)
+		assert.Equal(t, entity.StateSynced, member.State, key)
+	}
+
+	record, err := store.GetFile("delivery.zip")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, record.State)
+}
@@ -2,10 +2,13 @@ package usecase
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,16 +16,27 @@ import (
 	"github.com/kgantsov/synconik/internal/config"
 	"github.com/kgantsov/synconik/internal/entity"
 	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/metadata"
+	"github.com/kgantsov/synconik/internal/metrics"
 	"github.com/kgantsov/synconik/internal/storage"
 	"github.com/kgantsov/synconik/internal/store"
 	"github.com/rs/zerolog/log"
 )
 
+const defaultMimeType = "application/octet-stream"
+
 type AssetUseCase struct {
 	config  *config.Config
 	client  icnk_client.Client
 	store   store.Store
 	storage *icnk_client.Storage
+
+	filterUseCase     *FilterUseCase
+	collectionUseCase *CollectionUseCase
+	metadataUseCase   *MetadataUseCase
+	proxyUseCase      *ProxyUseCase
+	transcodeUseCase  *TranscodeUseCase
+	postUploadUseCase *PostUploadUseCase
 }
 
 func NewAssetUseCase(
@@ -33,68 +47,102 @@ func NewAssetUseCase(
 		client:  client,
 		store:   store,
 		storage: storage,
+
+		filterUseCase:     NewFilterUseCase(config, client, store),
+		collectionUseCase: NewCollectionUseCase(config, client, store),
+		metadataUseCase:   NewMetadataUseCase(config, client),
+		proxyUseCase:      NewProxyUseCase(config, client),
+		transcodeUseCase:  NewTranscodeUseCase(config, client, store),
+		postUploadUseCase: NewPostUploadUseCase(config, client, store),
 	}
 }
 
+// UploadOptions override what UploadAsset derives from the file and the store
+type UploadOptions struct {
+	CollectionID string
+	Title        string
+	// Metadata is written to the view of push manifests
+	Metadata metadata.Values
+	// LocalPath is the file uploaded for a path that is not on disk, e.g. a
+	// member extracted from the archive given by Archive
+	LocalPath string
+	Archive   string
+	// AssetID uploads the file as a new version of an existing asset
+	AssetID string
+}
+
 func (uc *AssetUseCase) UploadIfNotExists(path string, info os.FileInfo) error {
-	exists, err := uc.store.ExistsFile(path)
+	// files queued by the scanner are recorded as pending
+	reason, err := uc.filterUseCase.Skip(path, info)
 	if err != nil {
-		log.Debug().Str("service", "asset_usecase").Msgf("File exists: %s", path)
 		return err
 	}
-
-	if exists {
+	if reason != "" {
+		log.Debug().Str("service", "asset_usecase").Msgf("Skipping %s file: %s", reason, path)
 		return nil
 	}
 
-	file, err := uc.UploadAsset(path, info)
-	if err != nil {
-		return err
-	}
+	return uc.upload(path, info, UploadOptions{})
+}
 
-	err = uc.store.SaveFile(path, file)
+// Push uploads a file given explicitly rather than found by the scanner. Files
+// that are synced or being uploaded are skipped and the reason returned,
+// failed files are uploaded again.
+func (uc *AssetUseCase) Push(path string, info os.FileInfo, options UploadOptions) (string, error) {
+	reason, err := uc.filterUseCase.Skip(path, info)
 	if err != nil {
-		log.Error().Err(err).Str("service", "asset_usecase").Msg("Error saving file")
+		return "", err
+	}
+	if reason == SkipSynced || reason == SkipUploading {
+		return reason, nil
 	}
 
-	return nil
+	return "", uc.upload(path, info, options)
 }
 
-func (uc *AssetUseCase) UploadAsset(path string, info os.FileInfo) (*entity.File, error) {
-	var netTransport = &http.Transport{
-		Dial: (&net.Dialer{
-			Timeout: 5 * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+// upload uploads the file and records the result in the store. A file that
+// was uploaded before, e.g. changed since its upload or requeued, becomes a
+// new version of its asset.
+func (uc *AssetUseCase) upload(path string, info os.FileInfo, options UploadOptions) error {
+	if options.AssetID == "" {
+		options.AssetID = uc.storedAssetID(path)
 	}
 
-	var netClient = &http.Client{
-		Timeout:   time.Minute * 10,
-		Transport: netTransport,
+	file, uploadErr := uc.uploadAsset(path, info, options)
+	return uc.saveResult(path, file, uploadErr)
+}
+
+// saveResult records the state of an upload in the store. The post-upload
+// action of a synced file only runs once its record was saved.
+func (uc *AssetUseCase) saveResult(path string, file *entity.File, uploadErr error) error {
+	if file == nil {
+		return uploadErr
 	}
 
-	var iconikStorage storage.Storage
-	if uc.storage.Method == "GCS" {
-		iconikStorage = storage.NewGCSStorage(netClient)
-	} else if uc.storage.Method == "S3" {
-		iconikStorage = storage.NewS3Storage(netClient)
-	} else if uc.storage.Method == "B2" {
-		iconikStorage = storage.NewB2Storage(netClient)
-	} else {
-		return nil, fmt.Errorf("Unknown storage method: %s", uc.storage.Method)
+	if uploadErr != nil {
+		file.SetState(entity.StateFailed, uploadErr)
+
+		err := uc.store.SaveFile(path, file)
+		if err != nil {
+			log.Error().Err(err).Str("service", "asset_usecase").Msg("Error saving file")
+		}
+
+		return uploadErr
 	}
 
+	file.SetState(entity.StateSynced, nil)
+	return uc.postUploadUseCase.Apply(context.Background(), path, file)
+}
+
+// NewFileRecord returns the store record of a file found by the scanner
+func NewFileRecord(path string, info os.FileInfo) *entity.File {
 	// get directory path from path
 	dirPath := ""
 	if len(path) > 1 {
 		dirPath = path[:len(path)-len(info.Name())]
 	}
 
-	log.Debug().Str("service", "asset_usecase").Msgf("Uploading file: %s directory: %s", path, dirPath)
-
-	f := &entity.File{
+	return &entity.File{
 		DirectoryPath:    dirPath,
 		Name:             info.Name(),
 		Type:             "FILE",
@@ -102,27 +150,88 @@ func (uc *AssetUseCase) UploadAsset(path string, info os.FileInfo) (*entity.File
 		FileDateCreated:  info.ModTime().Format(time.RFC3339),
 		FileDateModified: info.ModTime().Format(time.RFC3339),
 	}
+}
+
+// LocalPath returns the path on disk of a store key. Files pushed from outside
+// the scanned directory are stored by their absolute path, while the keys of
+// scanned files never start with a slash since the directory ends with one.
+func LocalPath(config *config.Config, path string) string {
+	if strings.HasPrefix(path, "/") && strings.HasSuffix(config.Scanner.Dir, "/") {
+		return path
+	}
+
+	return config.Scanner.Dir + path
+}
+
+// UploadAsset creates the asset of the file in Iconik and uploads it. When the
+// upload fails after the record was created, the record is returned with the
+// IDs assigned so far along with the error.
+func (uc *AssetUseCase) UploadAsset(path string, info os.FileInfo) (*entity.File, error) {
+	return uc.uploadAsset(path, info, UploadOptions{})
+}
+
+func (uc *AssetUseCase) uploadAsset(path string, info os.FileInfo, options UploadOptions) (*entity.File, error) {
+	iconikStorage, err := storage.NewStorage(uc.storage.Method, newUploadHTTPClient())
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewFileRecord(path, info)
+	f.Archive = options.Archive
+	// the record keeps its asset until the upload replaces it, so a file that
+	// fails before its version is created is still uploaded to it next time
+	f.AssetID = options.AssetID
+	f.SetState(entity.StateUploading, nil)
+	dirPath := f.DirectoryPath
+
+	log.Debug().Str("service", "asset_usecase").Msgf("Uploading file: %s directory: %s", path, dirPath)
 
-	err := uc.store.SaveFile(path, f)
+	err = uc.store.SaveFile(path, f)
 	if err != nil {
 		log.Error().Err(err).Str("service", "asset_usecase").Msg("Error saving file")
 	}
 
+	absolutePath := LocalPath(uc.config, path)
+	if options.LocalPath != "" {
+		absolutePath = options.LocalPath
+	}
+	f.MimeType = DetectMimeType(absolutePath)
+
+	assetMetadata, err := uc.metadataUseCase.Collect(path, absolutePath)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error collecting metadata: %s", path)
+	}
+
+	assetMetadata.addValues(uc.config.Metadata.Manifest.ViewID, options.Metadata)
+
 	asset := &icnk_client.Asset{Title: info.Name(), Status: "ACTIVE", Type: "ASSET"}
+	uc.metadataUseCase.PrepareAsset(asset, assetMetadata)
+	if options.Title != "" {
+		asset.Title = options.Title
+	}
 
 	parentDir, err := uc.store.GetFile(strings.TrimRight(dirPath, "/"))
 	if err == nil {
 		asset.CollectionID = parentDir.ID
 	}
+	if options.CollectionID != "" {
+		asset.CollectionID = options.CollectionID
+	}
 
 	ctx := context.Background()
 
-	asset, err = uc.client.CreateAsset(ctx, asset)
+	asset, versionID, err := uc.createAsset(ctx, options.AssetID, asset)
 	if err != nil {
-		return nil, err
+		return f, err
 	}
 
 	f.AssetID = asset.ID
+	f.VersionID = versionID
+
+	err = uc.metadataUseCase.ApplyMetadata(ctx, asset.ID, assetMetadata)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error applying metadata: %s", path)
+	}
 
 	format, err := uc.client.CreateAssetFormat(
 		ctx,
@@ -130,13 +239,14 @@ func (uc *AssetUseCase) UploadAsset(path string, info os.FileInfo) (*entity.File
 		&icnk_client.Format{
 			Name:           "ORIGINAL",
 			Status:         "ACTIVE",
-			Metadata:       []map[string]string{{"internet_media_type": "image/jpeg"}},
+			Metadata:       []map[string]string{{"internet_media_type": f.MimeType}},
 			StorageMethods: []string{uc.storage.Method},
+			VersionID:      versionID,
 		},
 	)
 
 	if err != nil {
-		return nil, err
+		return f, err
 	}
 
 	f.FormatID = format.ID
@@ -149,42 +259,119 @@ func (uc *AssetUseCase) UploadAsset(path string, info os.FileInfo) (*entity.File
 			BaseDir:      dirPath,
 			Name:         info.Name(),
 			ComponentIds: []string{},
+			VersionID:    versionID,
 		},
 	)
 
 	if err != nil {
-		return nil, err
+		return f, err
 	}
 
 	f.FileSetID = fileSet.ID
 
-	file, err := uc.client.CreateFile(
-		ctx,
-		asset.ID,
-		&icnk_client.File{
-			StorageID:        uc.storage.ID,
-			FormatID:         format.ID,
-			FileSetID:        fileSet.ID,
-			Type:             f.Type,
-			DirectoryPath:    dirPath,
-			OriginalName:     info.Name(),
-			Size:             info.Size(),
-			FileDateCreated:  info.ModTime().Format(time.RFC3339),
-			FileDateModified: info.ModTime().Format(time.RFC3339),
-		},
-	)
+	file, err := uc.uploadFile(ctx, iconikStorage, asset.ID, &icnk_client.File{
+		StorageID:        uc.storage.ID,
+		FormatID:         format.ID,
+		FileSetID:        fileSet.ID,
+		Type:             f.Type,
+		DirectoryPath:    dirPath,
+		OriginalName:     info.Name(),
+		Size:             info.Size(),
+		FileDateCreated:  info.ModTime().Format(time.RFC3339),
+		FileDateModified: info.ModTime().Format(time.RFC3339),
+	}, absolutePath)
+	if file != nil {
+		f.ID = file.ID
+	}
+	if err != nil {
+		return f, err
+	}
+
+	if uc.proxyUseCase.Supports(absolutePath) {
+		err = uc.proxyUseCase.CreateProxies(ctx, asset.ID, absolutePath)
+		if err == nil {
+			f.TranscodeStatus = entity.TranscodeStatusLocal
+			return f, nil
+		}
+
+		log.Error().
+			Err(err).
+			Str("service", "asset_usecase").
+			Msgf("Error creating local proxies, falling back to transcoding: %s", path)
+	}
 
+	err = uc.transcodeUseCase.Transcode(ctx, path, f)
 	if err != nil {
-		return nil, err
+		return f, err
+	}
+
+	return f, nil
+}
+
+// storedAssetID returns the asset a file was uploaded to before, a file
+// changed or requeued since is uploaded as a new version of it
+func (uc *AssetUseCase) storedAssetID(path string) string {
+	stored, err := uc.store.GetFile(path)
+	if err != nil {
+		return ""
+	}
+	return stored.AssetID
+}
+
+// createAsset creates a new version of the asset assetID when it is set and
+// the asset still exists, and otherwise creates the asset. The ID of the
+// version is empty for a new asset.
+func (uc *AssetUseCase) createAsset(
+	ctx context.Context, assetID string, asset *icnk_client.Asset,
+) (*icnk_client.Asset, string, error) {
+	if assetID != "" {
+		versionID, err := uc.createVersion(ctx, assetID)
+		if err != nil {
+			return nil, "", err
+		}
+		if versionID != "" {
+			asset.ID = assetID
+			return asset, versionID, nil
+		}
+	}
+
+	asset, err := uc.client.CreateAsset(ctx, asset)
+	return asset, "", err
+}
+
+// createVersion creates a new version of an asset uploaded before, it
+// returns an empty ID when the asset was deleted in the meantime and the file
+// is uploaded as a new asset
+func (uc *AssetUseCase) createVersion(ctx context.Context, assetID string) (string, error) {
+	asset, err := uc.client.GetAsset(ctx, assetID)
+	if errors.Is(err, icnk_client.ErrNotFound) || (err == nil && asset.Status == icnk_client.AssetStatusDeleted) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	version, err := uc.client.CreateAssetVersion(ctx, assetID)
+	if err != nil {
+		return "", err
 	}
 
-	f.ID = file.ID
+	return version.ID, nil
+}
 
-	absolutePath := uc.config.Scanner.Dir + path
+// uploadFile creates a file of the asset in Iconik, uploads it and closes
+// it. The created file is returned even when the upload fails.
+func (uc *AssetUseCase) uploadFile(
+	ctx context.Context, iconikStorage storage.Storage, assetID string, file *icnk_client.File, absolutePath string,
+) (*icnk_client.File, error) {
+	created, err := uc.client.CreateFile(ctx, assetID, file)
+	if err != nil {
+		return nil, err
+	}
 
 	err = retry.Do(
 		func() error {
-			err = uc.client.Upload(ctx, iconikStorage, absolutePath, file)
+			err = uc.client.Upload(ctx, iconikStorage, absolutePath, created)
 			if err != nil {
 				return err
 			}
@@ -198,21 +385,57 @@ func (uc *AssetUseCase) UploadAsset(path string, info os.FileInfo) (*entity.File
 			return err != nil
 		}),
 	)
+	metrics.ObserveUpload(uc.storage.Method, file.Size, err)
 
 	if err != nil {
-		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error uploading file: %s", path)
-		return nil, err
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error uploading file: %s", absolutePath)
+		return created, err
 	}
 
-	err = uc.client.CloseFile(ctx, asset.ID, file.ID)
+	return created, uc.client.CloseFile(ctx, assetID, created.ID)
+}
+
+// DetectMimeType returns the media type of a file from its extension, or
+// from its content when the extension is unknown
+func DetectMimeType(path string) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		mediaType, _, err := mime.ParseMediaType(mimeType)
+		if err == nil {
+			return mediaType
+		}
+	}
+
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return defaultMimeType
 	}
+	defer file.Close()
 
-	_, err = uc.client.TriggerTranscoding(ctx, asset.ID, file.ID)
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if n == 0 && err != nil {
+		return defaultMimeType
+	}
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
 	if err != nil {
-		return nil, err
+		return defaultMimeType
 	}
+	return mediaType
+}
 
-	return f, nil
+func newUploadHTTPClient() *http.Client {
+	var netTransport = &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: 5 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   time.Minute * 10,
+		Transport: netTransport,
+	}
 }
@@ -1,12 +1,14 @@
 package usecase
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
 	"github.com/kgantsov/synconik/internal/iconik/client"
 	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
 	"github.com/stretchr/testify/assert"
@@ -110,11 +112,14 @@ func TestUploadAsset(t *testing.T) {
 		mock.Anything,
 		"47265105-BE2B-4C3F-8997-66BAB2893D0D",
 		"D025605F-CF64-4EE5-9F48-E6DD5D363473",
+		&icnk_client.TranscodeOptions{Priority: 5, UseStorageTranscodeIgnorePattern: true},
 	).Return("C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8", nil)
 
 	file, err := assetUseCase.UploadAsset(imagePath, imageFileInfo)
 	assert.NoError(t, err)
 	assert.Equal(t, file.ID, "D025605F-CF64-4EE5-9F48-E6DD5D363473")
+	assert.Equal(t, file.TranscodeJobID, "C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8")
+	assert.Equal(t, file.TranscodeStatus, "QUEUED")
 }
 
 func TestUploadIfNotExists(t *testing.T) {
@@ -214,8 +219,145 @@ func TestUploadIfNotExists(t *testing.T) {
 		mock.Anything,
 		"47265105-BE2B-4C3F-8997-66BAB2893D0D",
 		"D025605F-CF64-4EE5-9F48-E6DD5D363473",
+		&icnk_client.TranscodeOptions{Priority: 5, UseStorageTranscodeIgnorePattern: true},
 	).Return("C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8", nil)
 
 	err = assetUseCase.UploadIfNotExists(imagePath, imageFileInfo)
 	assert.NoError(t, err)
+
+	file, err := store.GetFile(imagePath)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "D025605F-CF64-4EE5-9F48-E6DD5D363473", file.ID)
+}
+
+func TestUploadIfNotExists_Failed(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+
+	imagePath := filepath.Join(dir, "image.jpg")
+	err := os.WriteFile(imagePath, []byte("test"), 0644)
+	assert.NoError(t, err)
+
+	imageFileInfo, err := os.Stat(imagePath)
+	assert.NoError(t, err)
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10}}
+
+	client := client.NewMockClient()
+	storage := &icnk_client.Storage{ID: "240E2FF6-0215-4F10-A0A4-37366C0F710B", Method: "S3"}
+	assetUseCase := NewAssetUseCase(cfg, client, store, storage)
+
+	// files queued by the scanner are pending
+	pending := NewFileRecord(imagePath, imageFileInfo)
+	pending.SetState(entity.StatePending, nil)
+	err = store.SaveFile(imagePath, pending)
+	assert.NoError(t, err)
+
+	client.On("CreateAsset", mock.Anything, mock.Anything).Return(nil, errors.New("request failed: forbidden")).Once()
+
+	err = assetUseCase.UploadIfNotExists(imagePath, imageFileInfo)
+	assert.EqualError(t, err, "request failed: forbidden")
+
+	file, err := store.GetFile(imagePath)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, file.State)
+	assert.Equal(t, "request failed: forbidden", file.Error)
+	assert.Equal(t, "image.jpg", file.Name)
+
+	// failed files are not retried until they are requeued
+	err = assetUseCase.UploadIfNotExists(imagePath, imageFileInfo)
+	assert.NoError(t, err)
+	client.AssertNumberOfCalls(t, "CreateAsset", 1)
+}
+
+func TestUploadIfNotExists_Changed(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir() + "/"
+	assert.NoError(t, os.WriteFile(dir+"clip.mov", []byte("edited"), 0644))
+	info, err := os.Stat(dir + "clip.mov")
+	assert.NoError(t, err)
+
+	synced := NewFileRecord("clip.mov", info)
+	synced.Size, synced.AssetID, synced.ID = 4, "A1", "F1"
+	synced.SetState(entity.StateSynced, nil)
+	assert.NoError(t, store.SaveFile("clip.mov", synced))
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10}}
+
+	client := client.NewMockClient()
+	client.On("GetAsset", mock.Anything, "A1").Return(&icnk_client.Asset{ID: "A1", Status: "ACTIVE"}, nil)
+	client.On("CreateAssetVersion", mock.Anything, "A1").Return(&icnk_client.AssetVersion{ID: "V2"}, nil)
+	client.On("CreateAssetFormat", mock.Anything, "A1", mock.MatchedBy(func(format *icnk_client.Format) bool {
+		return format.Name == "ORIGINAL" && format.VersionID == "V2"
+	})).Return(&icnk_client.Format{ID: "FMT2"}, nil)
+	client.On("CreateFileSet", mock.Anything, "A1", mock.MatchedBy(func(fileSet *icnk_client.FileSet) bool {
+		return fileSet.FormatID == "FMT2" && fileSet.VersionID == "V2"
+	})).Return(&icnk_client.FileSet{ID: "FS2"}, nil)
+	client.On("CreateFile", mock.Anything, "A1", mock.Anything).Return(&icnk_client.File{ID: "F2"}, nil)
+	client.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	client.On("CloseFile", mock.Anything, "A1", "F2").Return(nil)
+	client.On("TriggerTranscoding", mock.Anything, "A1", "F2", mock.Anything).Return("J2", nil)
+
+	assetUseCase := NewAssetUseCase(cfg, client, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+
+	// a synced file modified in place becomes a new version of its asset
+	assert.NoError(t, assetUseCase.UploadIfNotExists("clip.mov", info))
+	client.AssertNotCalled(t, "CreateAsset", mock.Anything, mock.Anything)
+
+	file, err := store.GetFile("clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "A1", file.AssetID)
+	assert.Equal(t, "F2", file.ID)
+	assert.Equal(t, 6, file.Size)
+
+	// unchanged files are not uploaded again
+	assert.NoError(t, assetUseCase.UploadIfNotExists("clip.mov", info))
+	client.AssertNumberOfCalls(t, "CreateAssetVersion", 1)
+}
+
+func TestUploadIfNotExists_ChangedDeletedAsset(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir() + "/"
+	assert.NoError(t, os.WriteFile(dir+"clip.mov", []byte("edited"), 0644))
+	info, err := os.Stat(dir + "clip.mov")
+	assert.NoError(t, err)
+
+	synced := NewFileRecord("clip.mov", info)
+	synced.Size, synced.AssetID = 4, "A1"
+	synced.SetState(entity.StateSynced, nil)
+	assert.NoError(t, store.SaveFile("clip.mov", synced))
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir, Interval: 10}}
+
+	client := client.NewMockClient()
+	client.On("GetAsset", mock.Anything, "A1").Return(&icnk_client.Asset{ID: "A1", Status: icnk_client.AssetStatusDeleted}, nil)
+	client.On("CreateAsset", mock.Anything, mock.Anything).Return(nil, errors.New("request failed: forbidden"))
+
+	assetUseCase := NewAssetUseCase(cfg, client, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+
+	// the file of a deleted asset is uploaded as a new asset
+	err = assetUseCase.UploadIfNotExists("clip.mov", info)
+	assert.EqualError(t, err, "request failed: forbidden")
+	client.AssertNotCalled(t, "CreateAssetVersion", mock.Anything, mock.Anything)
+}
+
+func TestDetectMimeType(t *testing.T) {
+	dir := t.TempDir()
+
+	png := filepath.Join(dir, "frame")
+	err := os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n0000"), 0644)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "image/jpeg", DetectMimeType(filepath.Join(dir, "photo.JPG")))
+	assert.Equal(t, "video/quicktime", DetectMimeType(filepath.Join(dir, "clip.mov")))
+	assert.Equal(t, "image/png", DetectMimeType(png))
+	assert.Equal(t, "application/octet-stream", DetectMimeType(filepath.Join(dir, "missing")))
 }
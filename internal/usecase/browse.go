@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"strings"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+// Entry is a stored record together with its page in the Iconik web app
+type Entry struct {
+	Path string       `json:"path"`
+	URL  string       `json:"url,omitempty"`
+	File *entity.File `json:"file"`
+}
+
+// NewEntry returns the entry of a record, the URL is empty until the asset or
+// collection was created in Iconik
+func NewEntry(iconikURL, path string, file *entity.File) Entry {
+	return Entry{
+		Path: path,
+		URL:  WebURL(iconikURL, file),
+		File: file,
+	}
+}
+
+// WebURL returns the Iconik web app URL of the collection of a directory or
+// the asset of a file
+func WebURL(iconikURL string, file *entity.File) string {
+	baseURL := strings.TrimRight(iconikURL, "/")
+
+	if file.IsDir() {
+		if file.ID == "" {
+			return ""
+		}
+		return baseURL + "/collection/" + file.ID
+	}
+
+	if file.AssetID == "" {
+		return ""
+	}
+	return baseURL + "/asset/" + file.AssetID
+}
+
+// BrowseUseCase reads the records of the local state database
+type BrowseUseCase struct {
+	config *config.Config
+	store  store.Store
+}
+
+func NewBrowseUseCase(config *config.Config, store store.Store) *BrowseUseCase {
+	return &BrowseUseCase{config: config, store: store}
+}
+
+// Key returns the store key of a path. Paths are relative to the scanned
+// directory and a leading slash is ignored, except for absolute paths in the
+// scanned directory and files pushed from outside of it, which are stored
+// under their absolute path.
+func (uc *BrowseUseCase) Key(path string) (string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return path, nil
+	}
+	if key := PushKey(uc.config, path); key != path {
+		return key, nil
+	}
+
+	exists, err := uc.store.ExistsFile(path)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return path, nil
+	}
+
+	return strings.TrimLeft(path, "/"), nil
+}
+
+// List returns the records whose path starts with prefix, only the records
+// in the given sync state are returned unless state is empty
+func (uc *BrowseUseCase) List(prefix, state string) ([]Entry, error) {
+	entries := []Entry{}
+
+	walk := func(path string, file *entity.File) error {
+		if state != "" && !strings.EqualFold(file.CurrentState(), state) {
+			return nil
+		}
+		entries = append(entries, NewEntry(uc.config.Iconik.URL, path, file))
+		return nil
+	}
+
+	prefixes := []string{strings.TrimLeft(prefix, "/")}
+	if strings.HasPrefix(prefix, "/") {
+		if key := PushKey(uc.config, prefix); key != prefix {
+			prefixes = []string{key}
+		} else if prefixes[0] != "" {
+			// the prefix may also be the absolute path of pushed files, their
+			// keys sort before the relative ones
+			prefixes = []string{prefix, prefixes[0]}
+		}
+	}
+
+	for _, prefix := range prefixes {
+		if err := uc.store.WalkFiles(prefix, walk); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Inspect returns the record of a single path
+func (uc *BrowseUseCase) Inspect(path string) (Entry, error) {
+	path, err := uc.Key(path)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	file, err := uc.store.GetFile(path)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return NewEntry(uc.config.Iconik.URL, path, file), nil
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebURL(t *testing.T) {
+	assert.Equal(
+		t, "https://app.iconik.io/asset/A1", WebURL("https://app.iconik.io/", &entity.File{AssetID: "A1", ID: "F1"}),
+	)
+	assert.Equal(
+		t, "https://app.iconik.io/collection/C1", WebURL("https://app.iconik.io", &entity.File{Type: "directory", ID: "C1"}),
+	)
+	assert.Equal(t, "", WebURL("https://app.iconik.io", &entity.File{Name: "clip.mov"}))
+}
+
+func TestBrowse(t *testing.T) {
+	badgerStore, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	files := map[string]*entity.File{
+		"rushes":         {Name: "rushes", Type: "directory", ID: "C1"},
+		"rushes/a.mov":   {Name: "a.mov", AssetID: "A1", ID: "F1"},
+		"rushes/b.mov":   {Name: "b.mov", State: entity.StateFailed, Error: "forbidden"},
+		"archive/c.mov":  {Name: "c.mov", AssetID: "A3", ID: "F3"},
+		"rushes2/d.mov":  {Name: "d.mov", State: entity.StatePending},
+		"rushes/e/f.mov": {Name: "f.mov", State: entity.StatePending},
+		// pushed from outside the scanned directory
+		"/delivery/g.mov": {Name: "g.mov", AssetID: "A7", ID: "F7"},
+	}
+	for path, file := range files {
+		assert.NoError(t, badgerStore.SaveFile(path, file))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{Dir: "/media/"},
+		Iconik:  config.Iconik{URL: "https://app.iconik.io"},
+	}
+	browseUseCase := NewBrowseUseCase(cfg, badgerStore)
+
+	entries, err := browseUseCase.List("/rushes/", "")
+	assert.NoError(t, err)
+	paths := []string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	assert.Equal(t, []string{"rushes/a.mov", "rushes/b.mov", "rushes/e/f.mov"}, paths)
+	assert.Equal(t, "https://app.iconik.io/asset/A1", entries[0].URL)
+
+	entries, err = browseUseCase.List("", "failed")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "rushes/b.mov", entries[0].Path)
+
+	entry, err := browseUseCase.Inspect("/rushes")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.iconik.io/collection/C1", entry.URL)
+
+	_, err = browseUseCase.Inspect("missing.mov")
+	assert.Equal(t, store.ErrFileNotFound, err)
+
+	// absolute paths in the scanned directory address the relative records
+	entry, err = browseUseCase.Inspect("/media/rushes/a.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "rushes/a.mov", entry.Path)
+
+	// pushed files keep their absolute path
+	entry, err = browseUseCase.Inspect("/delivery/g.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "/delivery/g.mov", entry.Path)
+	assert.Equal(t, "https://app.iconik.io/asset/A7", entry.URL)
+
+	entries, err = browseUseCase.List("/delivery/", "")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "/delivery/g.mov", entries[0].Path)
+
+	entries, err = browseUseCase.List("/", "")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 7)
+	assert.Equal(t, "/delivery/g.mov", entries[0].Path)
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/card"
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// UploadClipIfNotExists uploads a camera card clip as one asset, the clip is
+// tracked in the store under the key of its main video file
+func (uc *AssetUseCase) UploadClipIfNotExists(path string, clip *card.Clip) error {
+	reason, err := uc.filterUseCase.Skip(path, nil)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		log.Debug().Str("service", "asset_usecase").Msgf("Skipping %s clip: %s", reason, path)
+		return nil
+	}
+
+	file, uploadErr := uc.UploadClip(path, clip)
+	return uc.saveResult(path, file, uploadErr)
+}
+
+// UploadClip creates one asset for the clip in the collection of its card.
+// The ORIGINAL format has a component for each file of the clip and a single
+// file set holding all of them.
+func (uc *AssetUseCase) UploadClip(path string, clip *card.Clip) (*entity.File, error) {
+	iconikStorage, err := storage.NewStorage(uc.storage.Method, newUploadHTTPClient())
+	if err != nil {
+		return nil, err
+	}
+
+	assetID := uc.storedAssetID(path)
+
+	f := NewFileRecord(path, clip.Info())
+	f.Components = clip.Keys()
+	f.SetState(entity.StateUploading, nil)
+
+	log.Debug().
+		Str("service", "asset_usecase").
+		Msgf("Uploading %s clip: %s with %d files", clip.Layout, path, len(clip.Files))
+
+	err = uc.store.SaveFile(path, f)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msg("Error saving file")
+	}
+
+	absolutePath := LocalPath(uc.config, path)
+	f.MimeType = DetectMimeType(absolutePath)
+
+	assetMetadata, err := uc.metadataUseCase.Collect(path, absolutePath)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error collecting metadata: %s", path)
+	}
+
+	asset := &icnk_client.Asset{Title: clip.Name, Status: "ACTIVE", Type: "ASSET"}
+	uc.metadataUseCase.PrepareAsset(asset, assetMetadata)
+
+	cardDir, err := uc.store.GetFile(strings.TrimRight(clip.Root, "/"))
+	if err == nil {
+		asset.CollectionID = cardDir.ID
+	}
+
+	ctx := context.Background()
+
+	asset, versionID, err := uc.createAsset(ctx, assetID, asset)
+	if err != nil {
+		return f, err
+	}
+
+	f.AssetID = asset.ID
+	f.VersionID = versionID
+
+	err = uc.metadataUseCase.ApplyMetadata(ctx, asset.ID, assetMetadata)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error applying metadata: %s", path)
+	}
+
+	components := make([]icnk_client.Component, 0, len(clip.Files))
+	for _, file := range clip.Files {
+		components = append(components, icnk_client.Component{Name: file.Info.Name(), Type: file.Type})
+	}
+
+	format, err := uc.client.CreateAssetFormat(
+		ctx,
+		asset.ID,
+		&icnk_client.Format{
+			Name:           "ORIGINAL",
+			Status:         "ACTIVE",
+			Metadata:       []map[string]string{{"internet_media_type": f.MimeType}},
+			StorageMethods: []string{uc.storage.Method},
+			Components:     components,
+			VersionID:      versionID,
+		},
+	)
+	if err != nil {
+		return f, err
+	}
+
+	f.FormatID = format.ID
+
+	fileSet, err := uc.client.CreateFileSet(
+		ctx,
+		asset.ID, &icnk_client.FileSet{
+			FormatID:     format.ID,
+			StorageID:    uc.storage.ID,
+			BaseDir:      clip.Root,
+			Name:         clip.Name,
+			ComponentIds: componentIDs(format),
+			VersionID:    versionID,
+		},
+	)
+	if err != nil {
+		return f, err
+	}
+
+	f.FileSetID = fileSet.ID
+
+	for i, file := range clip.Files {
+		key := clip.Root + file.Path
+
+		created, err := uc.uploadFile(ctx, iconikStorage, asset.ID, &icnk_client.File{
+			StorageID:        uc.storage.ID,
+			FormatID:         format.ID,
+			FileSetID:        fileSet.ID,
+			Type:             "FILE",
+			DirectoryPath:    keyDir(key),
+			OriginalName:     file.Info.Name(),
+			Size:             file.Info.Size(),
+			FileDateCreated:  file.Info.ModTime().Format(time.RFC3339),
+			FileDateModified: file.Info.ModTime().Format(time.RFC3339),
+		}, LocalPath(uc.config, key))
+		// the main video file stands for the clip
+		if i == 0 && created != nil {
+			f.ID = created.ID
+		}
+		if err != nil {
+			return f, fmt.Errorf("%s: %w", file.Path, err)
+		}
+	}
+
+	err = uc.transcodeUseCase.Transcode(ctx, path, f)
+	if err != nil {
+		return f, err
+	}
+
+	return f, nil
+}
+
+// componentIDs returns the IDs of the components of a created format
+func componentIDs(format *icnk_client.Format) []string {
+	ids := []string{}
+	for _, component := range format.Components {
+		if component.ID != "" {
+			ids = append(ids, component.ID)
+		}
+	}
+	return ids
+}
+
+// ReadCard detects a camera card in a directory given by its store key with a
+// trailing slash, it returns nil for other directories
+func ReadCard(config *config.Config, dir string) *card.Card {
+	return card.Detect(dir, LocalPath(config, dir), config.Scanner.Cards.Layouts)
+}
+
+// DetectCard detects a camera card in a directory from its entries already
+// read, the subdirectories are only read for the likely roots of a card
+func DetectCard(config *config.Config, dir string, entries []os.DirEntry) *card.Card {
+	return card.DetectEntries(dir, LocalPath(config, dir), entries, config.Scanner.Cards.Layouts)
+}
+
+// FindClip detects the clip stored under the key of its main video file again
+// in the closest card above it, it returns nil when the clip is gone
+func FindClip(config *config.Config, key string) *card.Clip {
+	if !config.Scanner.Cards.Enabled {
+		return nil
+	}
+
+	dir := keyDir(key)
+	for {
+		if c := ReadCard(config, dir); c != nil {
+			for _, clip := range c.Clips {
+				if clip.Key() == key {
+					return clip
+				}
+			}
+			return nil
+		}
+
+		if dir == "" {
+			return nil
+		}
+		dir = keyDir(strings.TrimSuffix(dir, "/"))
+	}
+}
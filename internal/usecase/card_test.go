@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupCard(t *testing.T) (*config.Config, string) {
+	dir := t.TempDir() + "/"
+	for _, path := range []string{
+		"A001/CONTENTS/CLIP/0001AB.XML",
+		"A001/CONTENTS/VIDEO/0001AB.MXF",
+		"A001/CONTENTS/AUDIO/0001AB00.MXF",
+		"A001/CONTENTS/AUDIO/0001AB01.MXF",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("clip"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{Dir: dir, Cards: config.CardsConfig{Enabled: true}},
+	}
+
+	return cfg, dir
+}
+
+func TestUploadClipIfNotExists(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupCard(t)
+
+	clip := FindClip(cfg, "A001/CONTENTS/VIDEO/0001AB.MXF")
+	assert.NotNil(t, clip)
+	assert.Len(t, clip.Files, 4)
+
+	assert.NoError(t, store.SaveFile("A001", &entity.File{Name: "A001", Type: "directory", ID: "C1"}))
+
+	mockClient := client.NewMockClient()
+	storage := &icnk_client.Storage{ID: "S1", Method: "S3"}
+
+	mockClient.On("CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "0001AB", Status: "ACTIVE", Type: "ASSET", CollectionID: "C1",
+	}).Return(&icnk_client.Asset{ID: "A1"}, nil).Once()
+	mockClient.On("CreateAssetFormat", mock.Anything, "A1", mock.MatchedBy(func(format *icnk_client.Format) bool {
+		return format.Name == "ORIGINAL" && len(format.Components) == 4 &&
+			format.Components[0] == icnk_client.Component{Name: "0001AB.MXF", Type: icnk_client.ComponentVideo} &&
+			format.Components[1].Type == icnk_client.ComponentAudio
+	})).Return(&icnk_client.Format{ID: "FMT", Components: []icnk_client.Component{
+		{ID: "V1"}, {ID: "A1"}, {ID: "A2"}, {ID: "M1"},
+	}}, nil).Once()
+	mockClient.On("CreateFileSet", mock.Anything, "A1", &icnk_client.FileSet{
+		FormatID: "FMT", StorageID: "S1", BaseDir: "A001/", Name: "0001AB", ComponentIds: []string{"V1", "A1", "A2", "M1"},
+	}).Return(&icnk_client.FileSet{ID: "FS"}, nil).Once()
+	for _, file := range clip.Files {
+		name := file.Info.Name()
+		mockClient.On("CreateFile", mock.Anything, "A1", mock.MatchedBy(func(file *icnk_client.File) bool {
+			return file.OriginalName == name && file.FileSetID == "FS"
+		})).Return(&icnk_client.File{ID: "F-" + name}, nil).Once()
+	}
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, "A1", mock.Anything).Return(nil)
+	mockClient.On("TriggerTranscoding", mock.Anything, "A1", "F-0001AB.MXF", mock.Anything).Return("J1", nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, storage)
+	assert.NoError(t, assetUseCase.UploadClipIfNotExists(clip.Key(), clip))
+
+	mockClient.AssertNumberOfCalls(t, "CreateFile", 4)
+	mockClient.AssertNumberOfCalls(t, "CloseFile", 4)
+	mockClient.AssertCalled(t, "CreateFile", mock.Anything, "A1", mock.MatchedBy(func(file *icnk_client.File) bool {
+		return file.OriginalName == "0001AB00.MXF" && file.DirectoryPath == "A001/CONTENTS/AUDIO/"
+	}))
+
+	file, err := store.GetFile("A001/CONTENTS/VIDEO/0001AB.MXF")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "F-0001AB.MXF", file.ID)
+	assert.Equal(t, 16, file.Size)
+	assert.Equal(t, []string{
+		"A001/CONTENTS/AUDIO/0001AB00.MXF",
+		"A001/CONTENTS/AUDIO/0001AB01.MXF",
+		"A001/CONTENTS/CLIP/0001AB.XML",
+	}, file.Components)
+
+	// synced clips are not uploaded again
+	assert.NoError(t, assetUseCase.UploadClipIfNotExists(clip.Key(), clip))
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 1)
+}
+
+func TestUploadClipFailedFile(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupCard(t)
+	clip := FindClip(cfg, "A001/CONTENTS/VIDEO/0001AB.MXF")
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateAsset", mock.Anything, mock.Anything).Return(&icnk_client.Asset{ID: "A1"}, nil)
+	mockClient.On("CreateAssetFormat", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.Format{ID: "FMT"}, nil)
+	mockClient.On("CreateFileSet", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.FileSet{ID: "FS"}, nil)
+	mockClient.On("CreateFile", mock.Anything, "A1", mock.MatchedBy(func(file *icnk_client.File) bool {
+		return file.OriginalName == "0001AB01.MXF"
+	})).Return(nil, errors.New("request failed: forbidden"))
+	mockClient.On("CreateFile", mock.Anything, "A1", mock.Anything).Return(&icnk_client.File{ID: "F"}, nil)
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, "A1", mock.Anything).Return(nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+	err := assetUseCase.UploadClipIfNotExists(clip.Key(), clip)
+	assert.EqualError(t, err, "CONTENTS/AUDIO/0001AB01.MXF: request failed: forbidden")
+
+	file, err := store.GetFile(clip.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, file.State)
+	assert.Equal(t, "F", file.ID)
+}
+
+func TestFindClip(t *testing.T) {
+	cfg, dir := setupCard(t)
+
+	assert.Nil(t, FindClip(cfg, "A001/CONTENTS/AUDIO/0001AB00.MXF"))
+	assert.Nil(t, FindClip(cfg, "A001/CONTENTS/VIDEO/0002CD.MXF"))
+
+	cfg.Scanner.Cards.Enabled = false
+	assert.Nil(t, FindClip(cfg, "A001/CONTENTS/VIDEO/0001AB.MXF"))
+	cfg.Scanner.Cards.Enabled = true
+
+	assert.NoError(t, os.Remove(dir+"A001/CONTENTS/VIDEO/0001AB.MXF"))
+	assert.Nil(t, FindClip(cfg, "A001/CONTENTS/VIDEO/0001AB.MXF"))
+}
@@ -40,15 +40,22 @@ func (uc *CollectionUseCase) CreateCollectionIfNotExists(path string, info os.Fi
 		return nil
 	}
 
+	_, err = uc.createCollection(path, info.Name())
+	return err
+}
+
+// createCollection creates the collection of a directory, or of another path
+// holding files like an archive, in the collection of its parent directory
+func (uc *CollectionUseCase) createCollection(path, name string) (*entity.File, error) {
 	ctx := context.Background()
 
 	dirPath := ""
 	if len(path) > 1 {
-		dirPath = path[:len(path)-len(info.Name())]
+		dirPath = path[:len(path)-len(name)]
 	}
 
 	collection := &icnk_client.Collection{
-		Title: info.Name(),
+		Title: name,
 	}
 
 	parentDir, err := uc.store.GetFile(strings.TrimRight(dirPath, "/"))
@@ -58,20 +65,20 @@ func (uc *CollectionUseCase) CreateCollectionIfNotExists(path string, info os.Fi
 
 	collection, err = uc.client.CreateCollection(ctx, collection)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	file := &entity.File{
 		DirectoryPath: dirPath,
-		Name:          info.Name(),
+		Name:          name,
 		Type:          "directory",
 		ID:            collection.ID,
 	}
 
 	err = uc.store.SaveFile(path, file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return file, nil
 }
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+// Reasons for not uploading a file found by the scanner
+const (
+	SkipSidecar   = "sidecar"
+	SkipSynced    = "synced"
+	SkipUploading = "uploading"
+	SkipFailed    = "failed"
+	SkipStub      = "stub"
+
+	// Links, special files and mount points left out by the walk of the scanner
+	SkipSymlink = "symlink"
+	SkipCycle   = "cycle"
+	SkipSpecial = "special"
+	SkipMount   = "mount"
+
+	// SkipMarker and SkipIncomplete are only used in the drop folder mode
+	SkipMarker     = "marker"
+	SkipIncomplete = "incomplete"
+)
+
+// FilterUseCase decides which files found by the scanner are uploaded
+type FilterUseCase struct {
+	config *config.Config
+	store  store.Store
+
+	metadataUseCase *MetadataUseCase
+}
+
+func NewFilterUseCase(config *config.Config, client icnk_client.Client, store store.Store) *FilterUseCase {
+	return &FilterUseCase{
+		config: config,
+		store:  store,
+
+		metadataUseCase: NewMetadataUseCase(config, client),
+	}
+}
+
+// Skip returns why the file should not be uploaded, or an empty string for
+// files that are not in the store yet or pending. Failed files are skipped
+// until they are requeued, stubs of uploaded files are never uploaded. When
+// info is given, synced files that changed since their upload are uploaded
+// again.
+func (uc *FilterUseCase) Skip(path string, info os.FileInfo) (string, error) {
+	if strings.HasSuffix(path, StubSuffix) {
+		return SkipStub, nil
+	}
+	if uc.metadataUseCase.IsSidecar(LocalPath(uc.config, path)) {
+		return SkipSidecar, nil
+	}
+
+	stored, err := uc.store.GetFile(path)
+	if errors.Is(err, store.ErrFileNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch stored.CurrentState() {
+	case entity.StateSynced:
+		if info != nil && Changed(stored, info) {
+			return "", nil
+		}
+		return SkipSynced, nil
+	case entity.StateUploading:
+		return SkipUploading, nil
+	case entity.StateFailed:
+		return SkipFailed, nil
+	}
+
+	return "", nil
+}
+
+// Changed reports whether the local file differs in size or modification
+// date from its record. Records without a date, like the ones adopted from
+// Iconik, are only compared by size, records without a size either are
+// never changed.
+func Changed(file *entity.File, info os.FileInfo) bool {
+	modified, err := time.Parse(time.RFC3339, file.FileDateModified)
+	if err != nil {
+		return file.Size != 0 && int(info.Size()) != file.Size
+	}
+
+	return int(info.Size()) != file.Size || !modified.Equal(info.ModTime().Truncate(time.Second))
+}
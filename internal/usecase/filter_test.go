@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterSkip(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir() + "/"
+	for _, name := range []string{"new.mov", "new.mov.json"} {
+		assert.NoError(t, os.WriteFile(dir+name, []byte("test"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner:  config.ScannerConfig{Dir: dir},
+		Metadata: config.MetadataConfig{Sidecar: config.SidecarConfig{Patterns: []string{"{name}.json"}}},
+	}
+
+	records := map[string]*entity.File{
+		"synced.mov":    {Name: "synced.mov", ID: "F1"},
+		"pending.mov":   {Name: "pending.mov", State: entity.StatePending},
+		"uploading.mov": {Name: "uploading.mov", State: entity.StateUploading},
+		"failed.mov":    {Name: "failed.mov", State: entity.StateFailed},
+	}
+	for path, file := range records {
+		assert.NoError(t, store.SaveFile(path, file))
+	}
+
+	filterUseCase := NewFilterUseCase(cfg, client.NewMockClient(), store)
+
+	tests := map[string]string{
+		"new.mov":              "",
+		"pending.mov":          "",
+		"synced.mov":           SkipSynced,
+		"uploading.mov":        SkipUploading,
+		"failed.mov":           SkipFailed,
+		"new.mov.json":         SkipSidecar,
+		"old.mov" + StubSuffix: SkipStub,
+	}
+	for path, expected := range tests {
+		reason, err := filterUseCase.Skip(path, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, reason, path)
+	}
+}
+
+func TestFilterSkipChanged(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir() + "/"
+	assert.NoError(t, os.WriteFile(dir+"clip.mov", []byte("test"), 0644))
+	modified := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(dir+"clip.mov", modified, modified))
+
+	info, err := os.Stat(dir + "clip.mov")
+	assert.NoError(t, err)
+
+	file := NewFileRecord("clip.mov", info)
+	file.SetState(entity.StateSynced, nil)
+	assert.NoError(t, store.SaveFile("clip.mov", file))
+
+	filterUseCase := NewFilterUseCase(&config.Config{Scanner: config.ScannerConfig{Dir: dir}}, client.NewMockClient(), store)
+
+	reason, err := filterUseCase.Skip("clip.mov", info)
+	assert.NoError(t, err)
+	assert.Equal(t, SkipSynced, reason)
+
+	// a file modified in place is uploaded again
+	assert.NoError(t, os.Chtimes(dir+"clip.mov", modified, modified.Add(time.Minute)))
+	info, err = os.Stat(dir + "clip.mov")
+	assert.NoError(t, err)
+
+	reason, err = filterUseCase.Skip("clip.mov", info)
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+
+	// without the file info, synced files are skipped by their state
+	reason, err = filterUseCase.Skip("clip.mov", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, SkipSynced, reason)
+}
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(dir+"/clip.mov", []byte("test"), 0644))
+	info, err := os.Stat(dir + "/clip.mov")
+	assert.NoError(t, err)
+
+	file := NewFileRecord("clip.mov", info)
+	assert.False(t, Changed(file, info))
+
+	file.Size = 5
+	assert.True(t, Changed(file, info))
+
+	// records adopted without a date are compared by size
+	file.Size, file.FileDateModified = 4, ""
+	assert.False(t, Changed(file, info))
+
+	file.Size = 0
+	assert.False(t, Changed(file, info))
+
+	file.Size, file.FileDateModified = 4, "2001-01-01T00:00:00Z"
+	assert.True(t, Changed(file, info))
+}
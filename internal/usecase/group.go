@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/group"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// UploadGroupIfNotExists uploads a group of files as one asset, the group is
+// tracked in the store under the key of its primary file
+func (uc *AssetUseCase) UploadGroupIfNotExists(path string, g *group.Group) error {
+	reason, err := uc.filterUseCase.Skip(path, nil)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		log.Debug().Str("service", "asset_usecase").Msgf("Skipping %s group: %s", reason, path)
+		return nil
+	}
+
+	file, uploadErr := uc.UploadGroup(path, g)
+	return uc.saveResult(path, file, uploadErr)
+}
+
+// UploadGroup uploads the primary file of the group as the ORIGINAL format of
+// a new asset and each secondary file as another format of the same asset
+func (uc *AssetUseCase) UploadGroup(path string, g *group.Group) (*entity.File, error) {
+	f, err := uc.uploadAsset(path, g.Primary, UploadOptions{AssetID: uc.storedAssetID(path)})
+	if f == nil || err != nil {
+		return f, err
+	}
+
+	f.Members = g.Names()
+	f.Size = int(g.Info().Size())
+
+	iconikStorage, err := storage.NewStorage(uc.storage.Method, newUploadHTTPClient())
+	if err != nil {
+		return f, err
+	}
+
+	ctx := context.Background()
+
+	for _, member := range g.Members {
+		err := uc.uploadFormat(ctx, iconikStorage, f, g.Dir, member)
+		if err != nil {
+			return f, fmt.Errorf("format %s: %w", member.Format, err)
+		}
+	}
+
+	return f, nil
+}
+
+// uploadFormat creates a format of the asset with a file set holding the
+// secondary file
+func (uc *AssetUseCase) uploadFormat(
+	ctx context.Context, iconikStorage storage.Storage, f *entity.File, dir string, member group.Member,
+) error {
+	absolutePath := LocalPath(uc.config, dir+member.Info.Name())
+
+	format, err := uc.client.CreateAssetFormat(
+		ctx,
+		f.AssetID,
+		&icnk_client.Format{
+			Name:           member.Format,
+			Status:         "ACTIVE",
+			Metadata:       []map[string]string{{"internet_media_type": DetectMimeType(absolutePath)}},
+			StorageMethods: []string{uc.storage.Method},
+			VersionID:      f.VersionID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	fileSet, err := uc.client.CreateFileSet(
+		ctx,
+		f.AssetID, &icnk_client.FileSet{
+			FormatID:     format.ID,
+			StorageID:    uc.storage.ID,
+			BaseDir:      f.DirectoryPath,
+			Name:         member.Info.Name(),
+			ComponentIds: []string{},
+			VersionID:    f.VersionID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = uc.uploadFile(ctx, iconikStorage, f.AssetID, &icnk_client.File{
+		StorageID:        uc.storage.ID,
+		FormatID:         format.ID,
+		FileSetID:        fileSet.ID,
+		Type:             "FILE",
+		DirectoryPath:    f.DirectoryPath,
+		OriginalName:     member.Info.Name(),
+		Size:             member.Info.Size(),
+		FileDateCreated:  member.Info.ModTime().Format(time.RFC3339),
+		FileDateModified: member.Info.ModTime().Format(time.RFC3339),
+	}, absolutePath)
+
+	return err
+}
+
+// ReadGroups detects the groups of a directory given by its store key with a
+// trailing slash
+func ReadGroups(config *config.Config, dir string) []*group.Group {
+	if len(config.Scanner.Groups) == 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(LocalPath(config, dir))
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error reading directory: %s", dir)
+		return nil
+	}
+
+	return DetectGroups(config, dir, entries)
+}
+
+// DetectGroups detects the groups of a directory from its entries already
+// read
+func DetectGroups(config *config.Config, dir string, entries []os.DirEntry) []*group.Group {
+	rules := groupRules(config)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return group.DetectEntries(dir, entries, rules)
+}
+
+// FindGroup detects the group stored under the key of its primary file again,
+// it returns nil when the file is no longer grouped
+func FindGroup(config *config.Config, key string) *group.Group {
+	if len(config.Scanner.Groups) == 0 {
+		return nil
+	}
+
+	for _, g := range ReadGroups(config, keyDir(key)) {
+		if g.Key() == key {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// MemberKeys returns the store keys of the secondary files of a group record
+func MemberKeys(path string, file *entity.File) []string {
+	keys := make([]string, 0, len(file.Members))
+	for _, name := range file.Members {
+		keys = append(keys, keyDir(path)+name)
+	}
+	return keys
+}
+
+// keyDir returns the directory of a store key with a trailing slash
+func keyDir(key string) string {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return key[:i+1]
+	}
+	return ""
+}
+
+func groupRules(config *config.Config) []group.Rule {
+	rules := make([]group.Rule, 0, len(config.Scanner.Groups))
+	for _, ruleConfig := range config.Scanner.Groups {
+		rule := group.Rule{Primary: ruleConfig.Primary}
+		for _, format := range ruleConfig.Secondary {
+			rule.Secondary = append(rule.Secondary, group.Secondary{Extension: format.Extension, Format: format.Format})
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupGroup(t *testing.T) (*config.Config, string) {
+	dir := t.TempDir() + "/"
+	assert.NoError(t, os.MkdirAll(dir+"card", 0755))
+	for _, name := range []string{"IMG_0001.CR3", "IMG_0001.JPG", "IMG_0002.JPG"} {
+		assert.NoError(t, os.WriteFile(dir+"card/"+name, []byte("image"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{
+			Dir: dir,
+			Groups: []config.GroupRuleConfig{{
+				Primary:   []string{".cr3"},
+				Secondary: []config.GroupFormatConfig{{Extension: ".jpg", Format: "JPEG"}},
+			}},
+		},
+	}
+
+	return cfg, dir
+}
+
+func TestUploadGroupIfNotExists(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupGroup(t)
+
+	g := FindGroup(cfg, "card/IMG_0001.CR3")
+	assert.NotNil(t, g)
+	assert.Equal(t, []string{"IMG_0001.JPG"}, g.Names())
+
+	assert.NoError(t, store.SaveFile("card", &entity.File{Name: "card", Type: "directory", ID: "C1"}))
+
+	mockClient := client.NewMockClient()
+	storage := &icnk_client.Storage{ID: "S1", Method: "S3"}
+
+	mockClient.On("CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "IMG_0001.CR3", Status: "ACTIVE", Type: "ASSET", CollectionID: "C1",
+	}).Return(&icnk_client.Asset{ID: "A1"}, nil).Once()
+	for _, name := range []string{"ORIGINAL", "JPEG"} {
+		name := name
+		mockClient.On("CreateAssetFormat", mock.Anything, "A1", mock.MatchedBy(func(format *icnk_client.Format) bool {
+			return format.Name == name
+		})).Return(&icnk_client.Format{ID: "FMT-" + name}, nil).Once()
+	}
+	for _, name := range []string{"IMG_0001.CR3", "IMG_0001.JPG"} {
+		name := name
+		mockClient.On("CreateFileSet", mock.Anything, "A1", mock.MatchedBy(func(fileSet *icnk_client.FileSet) bool {
+			return fileSet.Name == name && fileSet.BaseDir == "card/"
+		})).Return(&icnk_client.FileSet{ID: "FS-" + name}, nil).Once()
+		mockClient.On("CreateFile", mock.Anything, "A1", mock.MatchedBy(func(file *icnk_client.File) bool {
+			return file.OriginalName == name && file.FileSetID == "FS-"+name
+		})).Return(&icnk_client.File{ID: "F-" + name}, nil).Once()
+	}
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, "A1", mock.Anything).Return(nil)
+	mockClient.On("TriggerTranscoding", mock.Anything, "A1", "F-IMG_0001.CR3", mock.Anything).Return("J1", nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, storage)
+	assert.NoError(t, assetUseCase.UploadGroupIfNotExists(g.Key(), g))
+
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 1)
+	mockClient.AssertNumberOfCalls(t, "CreateAssetFormat", 2)
+	mockClient.AssertNumberOfCalls(t, "CloseFile", 2)
+
+	file, err := store.GetFile("card/IMG_0001.CR3")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "F-IMG_0001.CR3", file.ID)
+	assert.Equal(t, "FMT-ORIGINAL", file.FormatID)
+	assert.Equal(t, []string{"IMG_0001.JPG"}, file.Members)
+	assert.Equal(t, 10, file.Size)
+	assert.Equal(t, []string{"card/IMG_0001.JPG"}, MemberKeys("card/IMG_0001.CR3", file))
+
+	// synced groups are not uploaded again
+	assert.NoError(t, assetUseCase.UploadGroupIfNotExists(g.Key(), g))
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 1)
+}
+
+func TestUploadGroupIfNotExists_Requeued(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupGroup(t)
+	g := FindGroup(cfg, "card/IMG_0001.CR3")
+
+	requeued := &entity.File{Name: "IMG_0001.CR3", AssetID: "A1", ID: "F1"}
+	requeued.SetState(entity.StatePending, nil)
+	assert.NoError(t, store.SaveFile(g.Key(), requeued))
+
+	mockClient := client.NewMockClient()
+	mockClient.On("GetAsset", mock.Anything, "A1").Return(&icnk_client.Asset{ID: "A1", Status: "ACTIVE"}, nil)
+	mockClient.On("CreateAssetVersion", mock.Anything, "A1").Return(&icnk_client.AssetVersion{ID: "V2"}, nil)
+	mockClient.On("CreateAssetFormat", mock.Anything, "A1", mock.MatchedBy(func(format *icnk_client.Format) bool {
+		return format.VersionID == "V2"
+	})).Return(&icnk_client.Format{ID: "FMT"}, nil)
+	mockClient.On("CreateFileSet", mock.Anything, "A1", mock.MatchedBy(func(fileSet *icnk_client.FileSet) bool {
+		return fileSet.VersionID == "V2"
+	})).Return(&icnk_client.FileSet{ID: "FS"}, nil)
+	mockClient.On("CreateFile", mock.Anything, "A1", mock.Anything).Return(&icnk_client.File{ID: "F2"}, nil)
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, "A1", mock.Anything).Return(nil)
+	mockClient.On("TriggerTranscoding", mock.Anything, "A1", "F2", mock.Anything).Return("J1", nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+
+	// every format of a group uploaded again belongs to the new version
+	assert.NoError(t, assetUseCase.UploadGroupIfNotExists(g.Key(), g))
+	mockClient.AssertNotCalled(t, "CreateAsset", mock.Anything, mock.Anything)
+	mockClient.AssertNumberOfCalls(t, "CreateAssetFormat", 2)
+
+	file, err := store.GetFile(g.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "A1", file.AssetID)
+	assert.Equal(t, "V2", file.VersionID)
+}
+
+func TestUploadGroupFailedFormat(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupGroup(t)
+	g := FindGroup(cfg, "card/IMG_0001.CR3")
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateAsset", mock.Anything, mock.Anything).Return(&icnk_client.Asset{ID: "A1"}, nil)
+	mockClient.On("CreateAssetFormat", mock.Anything, "A1", mock.MatchedBy(func(format *icnk_client.Format) bool {
+		return format.Name == "JPEG"
+	})).Return(nil, errors.New("request failed: forbidden"))
+	mockClient.On("CreateAssetFormat", mock.Anything, "A1", mock.Anything).Return(&icnk_client.Format{ID: "FMT"}, nil)
+	mockClient.On("CreateFileSet", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.FileSet{ID: "FS"}, nil)
+	mockClient.On("CreateFile", mock.Anything, "A1", mock.Anything).Return(&icnk_client.File{ID: "F"}, nil)
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, "A1", mock.Anything).Return(nil)
+	mockClient.On("TriggerTranscoding", mock.Anything, "A1", "F", mock.Anything).Return("J1", nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+	err := assetUseCase.UploadGroupIfNotExists(g.Key(), g)
+	assert.EqualError(t, err, "format JPEG: request failed: forbidden")
+
+	file, err := store.GetFile(g.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, file.State)
+	assert.Equal(t, "A1", file.AssetID)
+}
+
+func TestFindGroup(t *testing.T) {
+	cfg, dir := setupGroup(t)
+
+	assert.Nil(t, FindGroup(cfg, "card/IMG_0001.JPG"))
+	assert.Nil(t, FindGroup(cfg, "card/IMG_0002.JPG"))
+
+	assert.NoError(t, os.Remove(dir+"card/IMG_0001.JPG"))
+	assert.Nil(t, FindGroup(cfg, "card/IMG_0001.CR3"))
+
+	cfg.Scanner.Groups = nil
+	assert.Nil(t, FindGroup(cfg, "card/IMG_0001.CR3"))
+}
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/metadata"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultTitleLayout = "2006-01-02 15:04:05"
+
+// AssetMetadata is the metadata collected for a file before its asset is
+// created, grouped by Iconik metadata view
+type AssetMetadata struct {
+	Views       map[string]metadata.Values
+	CaptureTime time.Time
+}
+
+type MetadataUseCase struct {
+	config    *config.Config
+	client    icnk_client.Client
+	sidecar   *metadata.Sidecar
+	pathRules []*metadata.PathRule
+}
+
+func NewMetadataUseCase(config *config.Config, client icnk_client.Client) *MetadataUseCase {
+	// the rules were checked by config.Validate when the config was loaded
+	pathRules := []*metadata.PathRule{}
+	for _, ruleConfig := range config.Metadata.Path.Rules {
+		rule, err := ruleConfig.Compile()
+		if err != nil {
+			continue
+		}
+		pathRules = append(pathRules, rule)
+	}
+
+	return &MetadataUseCase{
+		config:    config,
+		client:    client,
+		sidecar:   metadata.NewSidecar(config.Metadata.Sidecar.Patterns),
+		pathRules: pathRules,
+	}
+}
+
+// IsSidecar reports whether the file is a sidecar that should not be uploaded
+// as a separate asset
+func (uc *MetadataUseCase) IsSidecar(absolutePath string) bool {
+	if len(uc.config.Metadata.Sidecar.Patterns) == 0 {
+		return false
+	}
+
+	return uc.sidecar.IsSidecar(absolutePath)
+}
+
+// Collect gathers metadata for the file from its sidecar, the path rules and
+// the metadata embedded in the file. Sources that fail are reported in the
+// error while the values of the other sources are still returned.
+func (uc *MetadataUseCase) Collect(path, absolutePath string) (*AssetMetadata, error) {
+	assetMetadata := &AssetMetadata{Views: map[string]metadata.Values{}}
+	var errs []error
+
+	sidecarValues, err := uc.sidecarValues(absolutePath)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	assetMetadata.addValues(uc.config.Metadata.Sidecar.ViewID, sidecarValues)
+
+	pathValues, err := uc.pathValues(path)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	assetMetadata.addValues(uc.config.Metadata.Path.ViewID, pathValues)
+
+	embeddedValues, err := uc.embeddedValues(absolutePath)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if captureTime, ok := metadata.CaptureTime(embeddedValues); ok {
+		assetMetadata.CaptureTime = captureTime
+	}
+	embeddedConfig := uc.config.Metadata.Embedded
+	assetMetadata.addValues(embeddedConfig.ViewID, mapValues(embeddedValues, embeddedConfig.Mapping))
+
+	return assetMetadata, errors.Join(errs...)
+}
+
+// PrepareAsset sets the title and date of the asset from the capture time
+// when configured
+func (uc *MetadataUseCase) PrepareAsset(asset *icnk_client.Asset, assetMetadata *AssetMetadata) {
+	embeddedConfig := uc.config.Metadata.Embedded
+	if assetMetadata.CaptureTime.IsZero() {
+		return
+	}
+
+	if embeddedConfig.TitleFromCaptureTime {
+		layout := embeddedConfig.TitleLayout
+		if layout == "" {
+			layout = defaultTitleLayout
+		}
+		asset.Title = assetMetadata.CaptureTime.Format(layout)
+	}
+
+	if embeddedConfig.DateFromCaptureTime {
+		asset.DateCreated = assetMetadata.CaptureTime.Format(time.RFC3339)
+	}
+}
+
+// ApplyMetadata writes the collected metadata to the views of the asset
+func (uc *MetadataUseCase) ApplyMetadata(
+	ctx context.Context, assetID string, assetMetadata *AssetMetadata,
+) error {
+	for viewID, values := range assetMetadata.Views {
+		if err := uc.updateMetadata(ctx, assetID, viewID, values); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *MetadataUseCase) sidecarValues(absolutePath string) (metadata.Values, error) {
+	sidecarConfig := uc.config.Metadata.Sidecar
+	if sidecarConfig.ViewID == "" || len(sidecarConfig.Patterns) == 0 {
+		return nil, nil
+	}
+
+	sidecarPath, ok := uc.sidecar.Find(absolutePath)
+	if !ok {
+		return nil, nil
+	}
+
+	values, err := metadata.ParseSidecar(sidecarPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapValues(values, sidecarConfig.Mapping), nil
+}
+
+// pathValues applies every matching path rule in order, later rules override
+// fields set by earlier ones
+func (uc *MetadataUseCase) pathValues(path string) (metadata.Values, error) {
+	if uc.config.Metadata.Path.ViewID == "" {
+		return nil, nil
+	}
+
+	// a rule that fails does not discard the values of the other rules
+	values := metadata.Values{}
+	var errs []error
+	for _, rule := range uc.pathRules {
+		ruleValues, matched, err := rule.Extract(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("path rule %q: %w", rule.Pattern, err))
+			continue
+		}
+		if matched {
+			values.Merge(ruleValues)
+		}
+	}
+
+	return values, errors.Join(errs...)
+}
+
+func (uc *MetadataUseCase) embeddedValues(absolutePath string) (metadata.Values, error) {
+	if !uc.config.Metadata.Embedded.Enabled {
+		return nil, nil
+	}
+
+	values, err := metadata.ReadEmbedded(absolutePath)
+	if errors.Is(err, metadata.ErrUnsupportedFormat) {
+		return nil, nil
+	}
+
+	return values, err
+}
+
+func (uc *MetadataUseCase) updateMetadata(
+	ctx context.Context, assetID, viewID string, values metadata.Values,
+) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	log.Debug().
+		Str("service", "metadata_usecase").
+		Str("asset_id", assetID).
+		Msgf("Updating metadata fields %v", values.Fields())
+
+	_, err := uc.client.UpdateAssetMetadata(ctx, assetID, viewID, icnk_client.NewMetadata(values))
+	return err
+}
+
+func (m *AssetMetadata) addValues(viewID string, values metadata.Values) {
+	if viewID == "" || len(values) == 0 {
+		return
+	}
+
+	if _, ok := m.Views[viewID]; !ok {
+		m.Views[viewID] = metadata.Values{}
+	}
+	m.Views[viewID].Merge(values)
+}
+
+// mapValues renames extracted keys to Iconik field names, keys are compared
+// case-insensitively
+func mapValues(values metadata.Values, mapping []config.FieldMappingConfig) metadata.Values {
+	mapped := metadata.Values{}
+
+	for _, fieldMapping := range mapping {
+		for key, fieldValues := range values {
+			if strings.EqualFold(key, fieldMapping.Key) {
+				mapped.Add(fieldMapping.Field, fieldValues...)
+			}
+		}
+	}
+
+	return mapped
+}
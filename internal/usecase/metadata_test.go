@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestApplyMetadataFromSidecar(t *testing.T) {
+	dir := t.TempDir()
+
+	clipPath := filepath.Join(dir, "clip.mov")
+	err := os.WriteFile(clipPath, []byte("test"), 0644)
+	assert.NoError(t, err)
+	err = os.WriteFile(
+		filepath.Join(dir, "clip.mov.json"),
+		[]byte(`{"Scene": "12", "take": "3", "unmapped": "x"}`),
+		0644,
+	)
+	assert.NoError(t, err)
+
+	cfg := &config.Config{
+		Metadata: config.MetadataConfig{
+			Sidecar: config.SidecarConfig{
+				Patterns: []string{"{name}.json"},
+				ViewID:   "1E4A1E5A-9DAD-11D1-80B4-00C04FD430C8",
+				Mapping: []config.FieldMappingConfig{
+					{Key: "scene", Field: "Scene"},
+					{Key: "take", Field: "Take"},
+				},
+			},
+		},
+	}
+
+	client := client.NewMockClient()
+	client.On(
+		"UpdateAssetMetadata",
+		mock.Anything,
+		"47265105-BE2B-4C3F-8997-66BAB2893D0D",
+		"1E4A1E5A-9DAD-11D1-80B4-00C04FD430C8",
+		icnk_client.NewMetadata(map[string][]string{"Scene": {"12"}, "Take": {"3"}}),
+	).Return(&icnk_client.Metadata{}, nil)
+
+	metadataUseCase := NewMetadataUseCase(cfg, client)
+
+	assert.True(t, metadataUseCase.IsSidecar(filepath.Join(dir, "clip.mov.json")))
+	assert.False(t, metadataUseCase.IsSidecar(clipPath))
+
+	assetMetadata, err := metadataUseCase.Collect("clip.mov", clipPath)
+	assert.NoError(t, err)
+
+	err = metadataUseCase.ApplyMetadata(
+		context.Background(), "47265105-BE2B-4C3F-8997-66BAB2893D0D", assetMetadata,
+	)
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+}
+
+func TestApplyMetadataFromPathRules(t *testing.T) {
+	cfg := &config.Config{
+		Metadata: config.MetadataConfig{
+			Path: config.PathMetadataConfig{
+				ViewID: "1E4A1E5A-9DAD-11D1-80B4-00C04FD430C8",
+				Rules: []config.PathRuleConfig{
+					{
+						Pattern: `^(?P<show>[^/]+)/S(?P<season>\d+)/E(?P<episode>\d+)/(?P<date>\d{4}-\d{2}-\d{2})_cam-(?P<cam>\w+)/`,
+						Fields: []config.PathFieldConfig{
+							{Name: "show", Group: "show", Transform: "upper"},
+							{Name: "season", Group: "season", Transform: "int"},
+							{Name: "episode", Value: "S${season}E${episode}"},
+							{Name: "shoot_date", Group: "date", Transform: "date"},
+							{Name: "source", Value: "DIT"},
+						},
+					},
+					{
+						Pattern: `\.mov$`,
+						Fields:  []config.PathFieldConfig{{Name: "source", Value: "camera"}},
+					},
+				},
+			},
+		},
+	}
+
+	client := client.NewMockClient()
+	client.On(
+		"UpdateAssetMetadata",
+		mock.Anything,
+		"47265105-BE2B-4C3F-8997-66BAB2893D0D",
+		"1E4A1E5A-9DAD-11D1-80B4-00C04FD430C8",
+		icnk_client.NewMetadata(map[string][]string{
+			"show":       {"MYSHOW"},
+			"season":     {"1"},
+			"episode":    {"S01E03"},
+			"shoot_date": {"2024-05-01T00:00:00Z"},
+			"source":     {"camera"},
+		}),
+	).Return(&icnk_client.Metadata{}, nil)
+
+	metadataUseCase := NewMetadataUseCase(cfg, client)
+
+	assetMetadata, err := metadataUseCase.Collect(
+		"MyShow/S01/E03/2024-05-01_cam-A/clip.mov",
+		"/data/MyShow/S01/E03/2024-05-01_cam-A/clip.mov",
+	)
+	assert.NoError(t, err)
+
+	err = metadataUseCase.ApplyMetadata(
+		context.Background(), "47265105-BE2B-4C3F-8997-66BAB2893D0D", assetMetadata,
+	)
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+
+	assetMetadata, err = metadataUseCase.Collect("other/clip.mxf", "/data/other/clip.mxf")
+	assert.NoError(t, err)
+
+	err = metadataUseCase.ApplyMetadata(
+		context.Background(), "47265105-BE2B-4C3F-8997-66BAB2893D0D", assetMetadata,
+	)
+	assert.NoError(t, err)
+	client.AssertNumberOfCalls(t, "UpdateAssetMetadata", 1)
+}
+
+func TestCollectPathRuleError(t *testing.T) {
+	cfg := &config.Config{
+		Metadata: config.MetadataConfig{
+			Path: config.PathMetadataConfig{
+				ViewID: "1E4A1E5A-9DAD-11D1-80B4-00C04FD430C8",
+				Rules: []config.PathRuleConfig{
+					{
+						Pattern: `^(?P<show>[^/]+)/`,
+						Fields:  []config.PathFieldConfig{{Name: "show", Group: "show"}},
+					},
+					{
+						Pattern: `_(?P<take>\w+)\.mov$`,
+						Fields:  []config.PathFieldConfig{{Name: "take", Group: "take", Transform: "int"}},
+					},
+					{
+						Pattern: `\.mov$`,
+						Fields:  []config.PathFieldConfig{{Name: "source", Value: "camera"}},
+					},
+				},
+			},
+		},
+	}
+
+	metadataUseCase := NewMetadataUseCase(cfg, client.NewMockClient())
+
+	// the failing rule is reported and the values of the other rules are kept
+	assetMetadata, err := metadataUseCase.Collect("MyShow/clip_A.mov", "/data/MyShow/clip_A.mov")
+	assert.EqualError(
+		t, err, `path rule "_(?P<take>\\w+)\\.mov$": field take: failed to parse "A" as integer: strconv.Atoi: parsing "A": invalid syntax`,
+	)
+	assert.Equal(t, metadata.Values{
+		"show":   {"MyShow"},
+		"source": {"camera"},
+	}, assetMetadata.Views["1E4A1E5A-9DAD-11D1-80B4-00C04FD430C8"])
+}
+
+func TestPrepareAssetFromCaptureTime(t *testing.T) {
+	cfg := &config.Config{
+		Metadata: config.MetadataConfig{
+			Embedded: config.EmbeddedMetadataConfig{
+				Enabled:              true,
+				TitleFromCaptureTime: true,
+				DateFromCaptureTime:  true,
+			},
+		},
+	}
+
+	metadataUseCase := NewMetadataUseCase(cfg, client.NewMockClient())
+
+	asset := &icnk_client.Asset{Title: "IMG_0001.JPG"}
+	metadataUseCase.PrepareAsset(asset, &AssetMetadata{
+		CaptureTime: time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC),
+	})
+	assert.Equal(t, "2024-05-01 14:30:00", asset.Title)
+	assert.Equal(t, "2024-05-01T14:30:00Z", asset.DateCreated)
+
+	asset = &icnk_client.Asset{Title: "notes.txt"}
+	metadataUseCase.PrepareAsset(asset, &AssetMetadata{})
+	assert.Equal(t, "notes.txt", asset.Title)
+	assert.Equal(t, "", asset.DateCreated)
+}
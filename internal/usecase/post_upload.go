@@ -0,0 +1,322 @@
+package usecase
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/rs/zerolog/log"
+)
+
+// StubSuffix is appended to the name of the stub replacing an uploaded file
+const StubSuffix = ".synconik"
+
+// errNotSaved is returned when the record could not be saved before the
+// post-upload action, which is then not applied
+var errNotSaved = errors.New("record not saved before the post-upload action")
+
+// Stub is the content of the stub replacing an uploaded file
+type Stub struct {
+	Name     string `json:"name"`
+	Size     int    `json:"size"`
+	AssetID  string `json:"asset_id"`
+	FileID   string `json:"file_id"`
+	SyncedAt string `json:"synced_at"`
+}
+
+// PostUploadUseCase applies the configured action to local files once they
+// were uploaded and verified
+type PostUploadUseCase struct {
+	config *config.Config
+	client icnk_client.Client
+	store  store.Store
+}
+
+func NewPostUploadUseCase(config *config.Config, client icnk_client.Client, store store.Store) *PostUploadUseCase {
+	return &PostUploadUseCase{
+		config: config,
+		client: client,
+		store:  store,
+	}
+}
+
+// Apply verifies the upload of a synced file, applies the post-upload action
+// to its local file and saves the record. The record is saved with the
+// intended action before the local file is touched and again with the
+// outcome, so it never claims an upload the local file was removed for. A
+// failed action leaves the local file in place and is recorded in the file.
+// Files uploaded along with others are always kept. Only the errors of the
+// store are returned.
+func (uc *PostUploadUseCase) Apply(ctx context.Context, path string, file *entity.File) error {
+	action := uc.config.PostUpload.Action
+	if action == "" || action == config.PostUploadKeep || !removable(file) {
+		return uc.store.SaveFile(path, file)
+	}
+
+	err := uc.apply(ctx, path, file, action)
+	if errors.Is(err, errNotSaved) {
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Str("service", "post_upload_usecase").Msgf("Error applying %s to %s", action, path)
+		file.LocalAction = ""
+		file.LocalActionError = err.Error()
+	} else {
+		log.Info().Str("service", "post_upload_usecase").Msgf("Applied %s to %s", action, path)
+	}
+
+	return uc.store.SaveFile(path, file)
+}
+
+// removable reports whether the post-upload action may touch the local file
+// of a record. The frames of image sequences, grouped files and camera card
+// clips are kept together, archive members have no local file of their own.
+func removable(file *entity.File) bool {
+	return file.Frames == 0 && len(file.Members) == 0 && len(file.Components) == 0 && file.Archive == ""
+}
+
+func (uc *PostUploadUseCase) apply(ctx context.Context, path string, file *entity.File, action string) error {
+	localPath := LocalPath(uc.config, path)
+
+	if err := uc.verify(ctx, localPath, file); err != nil {
+		return err
+	}
+
+	switch action {
+	case config.PostUploadMove:
+		if err := uc.saveIntent(path, file, entity.LocalActionMoving); err != nil {
+			return err
+		}
+		archivePath, err := uc.move(path, localPath)
+		if err != nil {
+			return err
+		}
+		file.SetLocalAction(entity.LocalActionMoved)
+		file.ArchivePath = archivePath
+	case config.PostUploadDelete:
+		if uc.config.PostUpload.Retention > 0 {
+			file.SetLocalAction(entity.LocalActionDeleteScheduled)
+			file.DeleteAfter = time.Now().Add(uc.config.PostUpload.Retention).UTC().Format(time.RFC3339)
+			return nil
+		}
+		if err := uc.saveIntent(path, file, entity.LocalActionDeleting); err != nil {
+			return err
+		}
+		if err := os.Remove(localPath); err != nil {
+			return err
+		}
+		file.SetLocalAction(entity.LocalActionDeleted)
+	case config.PostUploadStub:
+		if err := uc.saveIntent(path, file, entity.LocalActionStubbing); err != nil {
+			return err
+		}
+		if err := uc.stub(localPath, file); err != nil {
+			return err
+		}
+		file.SetLocalAction(entity.LocalActionStubbed)
+	default:
+		return fmt.Errorf("unknown post-upload action: %s", action)
+	}
+
+	return nil
+}
+
+// saveIntent records the action in the store before it touches the local
+// file, the local file is left alone when the record can not be saved
+func (uc *PostUploadUseCase) saveIntent(path string, file *entity.File, action string) error {
+	file.SetLocalAction(action)
+	if err := uc.store.SaveFile(path, file); err != nil {
+		file.LocalAction = ""
+		return fmt.Errorf("%w: %w", errNotSaved, err)
+	}
+	return nil
+}
+
+// verify checks that the local file is the one uploaded and that Iconik has
+// it with the same size, or checksum
+func (uc *PostUploadUseCase) verify(ctx context.Context, localPath string, file *entity.File) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	if int(info.Size()) != file.Size {
+		return fmt.Errorf("local file changed since the upload: %d bytes instead of %d", info.Size(), file.Size)
+	}
+
+	iconikFile, err := uc.client.GetFile(ctx, file.AssetID, file.ID)
+	if err != nil {
+		return err
+	}
+	if iconikFile.Status != "" && iconikFile.Status != icnk_client.FileStatusClosed {
+		return fmt.Errorf("file %s is %s", file.ID, iconikFile.Status)
+	}
+	if iconikFile.Size != info.Size() {
+		return fmt.Errorf("size mismatch: %d bytes in Iconik, %d locally", iconikFile.Size, info.Size())
+	}
+
+	if uc.config.PostUpload.Verify != config.VerifyChecksum {
+		return nil
+	}
+
+	if iconikFile.Checksum == "" {
+		return errors.New("Iconik has no checksum of the file")
+	}
+
+	checksum, err := fileChecksum(localPath)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(checksum, iconikFile.Checksum) {
+		return fmt.Errorf("checksum mismatch: %s in Iconik, %s locally", iconikFile.Checksum, checksum)
+	}
+
+	return nil
+}
+
+// move moves the file to the archive directory under its store key
+func (uc *PostUploadUseCase) move(path, localPath string) (string, error) {
+	if uc.config.PostUpload.ArchiveDir == "" {
+		return "", errors.New("post_upload.archive_dir is not set")
+	}
+
+	archivePath := filepath.Join(uc.config.PostUpload.ArchiveDir, path)
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(localPath, archivePath); err == nil {
+		return archivePath, nil
+	}
+
+	// the archive may be on another file system
+	if err := copyFile(localPath, archivePath); err != nil {
+		os.Remove(archivePath)
+		return "", err
+	}
+
+	return archivePath, os.Remove(localPath)
+}
+
+// stub replaces the file with a small JSON file pointing at its asset
+func (uc *PostUploadUseCase) stub(localPath string, file *entity.File) error {
+	data, err := json.MarshalIndent(Stub{
+		Name:     file.Name,
+		Size:     file.Size,
+		AssetID:  file.AssetID,
+		FileID:   file.ID,
+		SyncedAt: file.UpdatedAt,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(localPath+StubSuffix, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Remove(localPath)
+}
+
+// DeleteExpired deletes the local files whose retention period is over,
+// unless they changed since the upload
+func (uc *PostUploadUseCase) DeleteExpired(now time.Time) error {
+	expired := map[string]*entity.File{}
+	err := uc.store.WalkFiles("", func(path string, file *entity.File) error {
+		if file.LocalAction != entity.LocalActionDeleteScheduled {
+			return nil
+		}
+
+		deleteAfter, err := time.Parse(time.RFC3339, file.DeleteAfter)
+		if err == nil && now.Before(deleteAfter) {
+			return nil
+		}
+
+		expired[path] = file
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for path, file := range expired {
+		err := uc.deleteExpired(path, file)
+		if err != nil {
+			log.Error().Err(err).Str("service", "post_upload_usecase").Msgf("Error deleting %s", path)
+			file.LocalActionError = err.Error()
+		} else {
+			log.Info().Str("service", "post_upload_usecase").Msgf("Deleted %s after the retention period", path)
+			file.SetLocalAction(entity.LocalActionDeleted)
+		}
+
+		if err := uc.store.SaveFile(path, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *PostUploadUseCase) deleteExpired(path string, file *entity.File) error {
+	localPath := LocalPath(uc.config, path)
+
+	info, err := os.Stat(localPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if Changed(file, info) {
+		return errors.New("local file changed since the upload")
+	}
+
+	return os.Remove(localPath)
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
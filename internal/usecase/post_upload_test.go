@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupPostUpload(t *testing.T, postUpload config.PostUploadConfig) (*PostUploadUseCase, *client.MockClient, string) {
+	store, _, cleanup := setupTestDB(t)
+	t.Cleanup(cleanup)
+
+	dir := t.TempDir() + "/"
+	assert.NoError(t, os.MkdirAll(dir+"rushes", 0755))
+	assert.NoError(t, os.WriteFile(dir+"rushes/clip.mov", []byte("data"), 0644))
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir}, PostUpload: postUpload}
+	mockClient := client.NewMockClient()
+
+	return NewPostUploadUseCase(cfg, mockClient, store), mockClient, dir
+}
+
+func syncedFile() *entity.File {
+	file := &entity.File{Name: "clip.mov", Size: 4, AssetID: "A1", ID: "F1"}
+	file.SetState(entity.StateSynced, nil)
+	return file
+}
+
+func TestPostUploadMove(t *testing.T) {
+	archiveDir := t.TempDir()
+	uc, mockClient, dir := setupPostUpload(t, config.PostUploadConfig{Action: "move", ArchiveDir: archiveDir})
+
+	mockClient.On("GetFile", mock.Anything, "A1", "F1").
+		Return(&icnk_client.File{ID: "F1", Size: 4, Status: icnk_client.FileStatusClosed}, nil)
+
+	file := syncedFile()
+	assert.NoError(t, uc.Apply(context.Background(), "rushes/clip.mov", file))
+
+	assert.Empty(t, file.LocalActionError)
+	assert.Equal(t, entity.LocalActionMoved, file.LocalAction)
+	assert.Equal(t, filepath.Join(archiveDir, "rushes/clip.mov"), file.ArchivePath)
+	assert.True(t, file.LocalFileRemoved())
+
+	_, err := os.Stat(dir + "rushes/clip.mov")
+	assert.True(t, os.IsNotExist(err))
+	data, err := os.ReadFile(file.ArchivePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+// recordingStore records the local action of every saved record and whether
+// the local file still existed at that time
+type recordingStore struct {
+	store.Store
+	localPath string
+	saved     []string
+	err       error
+}
+
+func (s *recordingStore) SaveFile(path string, file *entity.File) error {
+	_, err := os.Stat(s.localPath)
+	s.saved = append(s.saved, fmt.Sprintf("%s %s exists=%t", file.State, file.LocalAction, err == nil))
+	if s.err != nil {
+		return s.err
+	}
+	return s.Store.SaveFile(path, file)
+}
+
+func TestPostUploadSavesBeforeAction(t *testing.T) {
+	uc, mockClient, dir := setupPostUpload(t, config.PostUploadConfig{Action: "delete"})
+	recording := &recordingStore{Store: uc.store, localPath: dir + "rushes/clip.mov"}
+	uc.store = recording
+
+	mockClient.On("GetFile", mock.Anything, "A1", "F1").Return(&icnk_client.File{ID: "F1", Size: 4}, nil)
+
+	assert.NoError(t, uc.Apply(context.Background(), "rushes/clip.mov", syncedFile()))
+	assert.Equal(t, []string{"SYNCED DELETING exists=true", "SYNCED DELETED exists=false"}, recording.saved)
+
+	stored, err := recording.Store.GetFile("rushes/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.LocalActionDeleted, stored.LocalAction)
+
+	// the local file is kept when the record can not be saved
+	assert.NoError(t, os.WriteFile(dir+"rushes/clip.mov", []byte("data"), 0644))
+	recording.saved = nil
+	recording.err = errors.New("disk full")
+
+	file := syncedFile()
+	err = uc.Apply(context.Background(), "rushes/clip.mov", file)
+	assert.True(t, errors.Is(err, recording.err))
+	assert.Equal(t, []string{"SYNCED DELETING exists=true"}, recording.saved)
+	assert.Empty(t, file.LocalAction)
+
+	_, err = os.Stat(dir + "rushes/clip.mov")
+	assert.NoError(t, err)
+}
+
+func TestPostUploadDelete(t *testing.T) {
+	uc, mockClient, dir := setupPostUpload(t, config.PostUploadConfig{Action: "delete"})
+
+	mockClient.On("GetFile", mock.Anything, "A1", "F1").Return(&icnk_client.File{ID: "F1", Size: 4}, nil)
+
+	file := syncedFile()
+	assert.NoError(t, uc.Apply(context.Background(), "rushes/clip.mov", file))
+
+	assert.Equal(t, entity.LocalActionDeleted, file.LocalAction)
+	_, err := os.Stat(dir + "rushes/clip.mov")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPostUploadDeleteAfterRetention(t *testing.T) {
+	uc, mockClient, dir := setupPostUpload(t, config.PostUploadConfig{Action: "delete", Retention: time.Hour})
+
+	mockClient.On("GetFile", mock.Anything, "A1", "F1").Return(&icnk_client.File{ID: "F1", Size: 4}, nil)
+
+	info, err := os.Stat(dir + "rushes/clip.mov")
+	assert.NoError(t, err)
+
+	file := syncedFile()
+	file.FileDateModified = info.ModTime().Format(time.RFC3339)
+	assert.NoError(t, uc.Apply(context.Background(), "rushes/clip.mov", file))
+
+	assert.Equal(t, entity.LocalActionDeleteScheduled, file.LocalAction)
+	assert.False(t, file.LocalFileRemoved())
+	assert.NoError(t, uc.store.SaveFile("rushes/clip.mov", file))
+
+	// nothing expired yet
+	assert.NoError(t, uc.DeleteExpired(time.Now()))
+	_, err = os.Stat(dir + "rushes/clip.mov")
+	assert.NoError(t, err)
+
+	assert.NoError(t, uc.DeleteExpired(time.Now().Add(2*time.Hour)))
+	_, err = os.Stat(dir + "rushes/clip.mov")
+	assert.True(t, os.IsNotExist(err))
+
+	stored, err := uc.store.GetFile("rushes/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.LocalActionDeleted, stored.LocalAction)
+}
+
+func TestPostUploadDeleteExpiredChanged(t *testing.T) {
+	uc, _, dir := setupPostUpload(t, config.PostUploadConfig{Action: "delete", Retention: time.Hour})
+
+	file := syncedFile()
+	file.LocalAction = entity.LocalActionDeleteScheduled
+	file.DeleteAfter = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	file.FileDateModified = "2020-01-01T00:00:00Z"
+	assert.NoError(t, uc.store.SaveFile("rushes/clip.mov", file))
+
+	assert.NoError(t, uc.DeleteExpired(time.Now()))
+
+	_, err := os.Stat(dir + "rushes/clip.mov")
+	assert.NoError(t, err)
+
+	stored, err := uc.store.GetFile("rushes/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.LocalActionDeleteScheduled, stored.LocalAction)
+	assert.Equal(t, "local file changed since the upload", stored.LocalActionError)
+}
+
+func TestPostUploadStub(t *testing.T) {
+	uc, mockClient, dir := setupPostUpload(t, config.PostUploadConfig{Action: "stub", Verify: "checksum"})
+
+	// md5 of "data"
+	mockClient.On("GetFile", mock.Anything, "A1", "F1").
+		Return(&icnk_client.File{ID: "F1", Size: 4, Checksum: "8D777F385D3DFEC8815D20F7496026DC"}, nil)
+
+	file := syncedFile()
+	assert.NoError(t, uc.Apply(context.Background(), "rushes/clip.mov", file))
+
+	assert.Empty(t, file.LocalActionError)
+	assert.Equal(t, entity.LocalActionStubbed, file.LocalAction)
+
+	_, err := os.Stat(dir + "rushes/clip.mov")
+	assert.True(t, os.IsNotExist(err))
+
+	data, err := os.ReadFile(dir + "rushes/clip.mov" + StubSuffix)
+	assert.NoError(t, err)
+	stub := Stub{}
+	assert.NoError(t, json.Unmarshal(data, &stub))
+	assert.Equal(t, Stub{Name: "clip.mov", Size: 4, AssetID: "A1", FileID: "F1", SyncedAt: file.UpdatedAt}, stub)
+}
+
+func TestPostUploadVerifyFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		verify string
+		file   *icnk_client.File
+		err    string
+	}{
+		{
+			name: "size",
+			file: &icnk_client.File{ID: "F1", Size: 3},
+			err:  "size mismatch: 3 bytes in Iconik, 4 locally",
+		},
+		{
+			name: "not closed",
+			file: &icnk_client.File{ID: "F1", Size: 4, Status: "OPEN"},
+			err:  "file F1 is OPEN",
+		},
+		{
+			name:   "no checksum",
+			verify: "checksum",
+			file:   &icnk_client.File{ID: "F1", Size: 4},
+			err:    "Iconik has no checksum of the file",
+		},
+		{
+			name:   "checksum",
+			verify: "checksum",
+			file:   &icnk_client.File{ID: "F1", Size: 4, Checksum: "00"},
+			err:    "checksum mismatch: 00 in Iconik, 8d777f385d3dfec8815d20f7496026dc locally",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, mockClient, dir := setupPostUpload(t, config.PostUploadConfig{Action: "delete", Verify: tt.verify})
+			mockClient.On("GetFile", mock.Anything, "A1", "F1").Return(tt.file, nil)
+
+			file := syncedFile()
+			assert.NoError(t, uc.Apply(context.Background(), "rushes/clip.mov", file))
+
+			assert.Equal(t, tt.err, file.LocalActionError)
+			assert.Empty(t, file.LocalAction)
+
+			_, err := os.Stat(dir + "rushes/clip.mov")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPostUploadKeep(t *testing.T) {
+	uc, mockClient, dir := setupPostUpload(t, config.PostUploadConfig{Action: "keep"})
+
+	file := syncedFile()
+	assert.NoError(t, uc.Apply(context.Background(), "rushes/clip.mov", file))
+
+	assert.Empty(t, file.LocalAction)
+	mockClient.AssertNotCalled(t, "GetFile", mock.Anything, mock.Anything, mock.Anything)
+	_, err := os.Stat(dir + "rushes/clip.mov")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/imaging"
+	"github.com/kgantsov/synconik/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultProxyMaxSize    = 1024
+	defaultKeyframeMaxSize = 400
+	defaultProxyQuality    = 85
+	// defaultProxyMaxPixels keeps a decoded image under about 400 MB
+	defaultProxyMaxPixels = 100_000_000
+)
+
+type ProxyUseCase struct {
+	config *config.Config
+	client icnk_client.Client
+
+	// storage is the proxy storage once it was looked up
+	storageMu sync.Mutex
+	storage   *icnk_client.Storage
+}
+
+func NewProxyUseCase(config *config.Config, client icnk_client.Client) *ProxyUseCase {
+	return &ProxyUseCase{
+		config: config,
+		client: client,
+	}
+}
+
+// Supports reports whether proxies of the file are generated locally
+func (uc *ProxyUseCase) Supports(absolutePath string) bool {
+	return uc.config.Proxy.Enabled && imaging.IsSupported(absolutePath)
+}
+
+// CreateProxies generates a JPEG proxy and keyframe of the image and uploads
+// them to the proxy storage of the asset
+func (uc *ProxyUseCase) CreateProxies(ctx context.Context, assetID, absolutePath string) error {
+	proxyStorage, err := uc.getStorage(ctx)
+	if err != nil {
+		return err
+	}
+
+	backend, err := storage.NewStorage(proxyStorage.Method, newUploadHTTPClient())
+	if err != nil {
+		return err
+	}
+
+	img, err := imaging.Decode(absolutePath, valueOrDefault(uc.config.Proxy.MaxPixels, defaultProxyMaxPixels))
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "synconik-proxy-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	name := filepath.Base(absolutePath)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	proxyImage := imaging.Resize(img, valueOrDefault(uc.config.Proxy.MaxSize, defaultProxyMaxSize))
+	proxyPath := filepath.Join(tmpDir, name+".jpg")
+	if err := uc.save(proxyPath, proxyImage); err != nil {
+		return err
+	}
+
+	proxy, err := uc.client.CreateProxy(ctx, assetID, &icnk_client.Proxy{
+		StorageID:  proxyStorage.ID,
+		Name:       filepath.Base(proxyPath),
+		Filename:   filepath.Base(proxyPath),
+		Format:     "JPEG",
+		Codec:      "jpeg",
+		Resolution: resolution(proxyImage),
+		Status:     "OPEN",
+	})
+	if err != nil {
+		return fmt.Errorf("error creating proxy: %w", err)
+	}
+
+	err = uc.upload(ctx, backend, proxyPath, assetID, proxy.UploadURL, proxy.UploadCredentials)
+	if err != nil {
+		return fmt.Errorf("error uploading proxy: %w", err)
+	}
+
+	if err := uc.client.CloseProxy(ctx, assetID, proxy.ID); err != nil {
+		return err
+	}
+
+	keyframeImage := imaging.Resize(img, valueOrDefault(uc.config.Proxy.KeyframeMaxSize, defaultKeyframeMaxSize))
+	keyframePath := filepath.Join(tmpDir, name+"_keyframe.jpg")
+	if err := uc.save(keyframePath, keyframeImage); err != nil {
+		return err
+	}
+
+	keyframe, err := uc.client.CreateKeyframe(ctx, assetID, &icnk_client.Keyframe{
+		StorageID:  proxyStorage.ID,
+		Filename:   filepath.Base(keyframePath),
+		Type:       "KEYFRAME",
+		Resolution: resolution(keyframeImage),
+		Status:     "OPEN",
+	})
+	if err != nil {
+		return fmt.Errorf("error creating keyframe: %w", err)
+	}
+
+	err = uc.upload(ctx, backend, keyframePath, assetID, keyframe.UploadURL, keyframe.UploadCredentials)
+	if err != nil {
+		return fmt.Errorf("error uploading keyframe: %w", err)
+	}
+
+	if err := uc.client.CloseKeyframe(ctx, assetID, keyframe.ID); err != nil {
+		return err
+	}
+
+	log.Debug().Str("service", "proxy_usecase").Str("asset_id", assetID).Msgf("Created proxies for %s", absolutePath)
+
+	return nil
+}
+
+// getStorage looks up the proxy storage, a failed lookup is tried again on
+// the next call
+func (uc *ProxyUseCase) getStorage(ctx context.Context) (*icnk_client.Storage, error) {
+	uc.storageMu.Lock()
+	defer uc.storageMu.Unlock()
+
+	if uc.storage != nil {
+		return uc.storage, nil
+	}
+
+	storageID := uc.config.Proxy.StorageID
+	if storageID == "" {
+		return nil, fmt.Errorf("proxy storage is not configured")
+	}
+
+	proxyStorage, err := uc.client.GetStorage(ctx, storageID)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.storage = proxyStorage
+	return proxyStorage, nil
+}
+
+func (uc *ProxyUseCase) save(path string, img image.Image) error {
+	return imaging.SaveJPEG(path, img, valueOrDefault(uc.config.Proxy.Quality, defaultProxyQuality))
+}
+
+func (uc *ProxyUseCase) upload(
+	ctx context.Context,
+	backend storage.Storage,
+	path, assetID, uploadURL string,
+	uploadCredentials map[string]string,
+) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return uc.client.Upload(ctx, backend, path, &icnk_client.File{
+		Name:              info.Name(),
+		OriginalName:      info.Name(),
+		DirectoryPath:     assetID,
+		Size:              info.Size(),
+		UploadURL:         uploadURL,
+		UploadCredentials: uploadCredentials,
+	})
+}
+
+func resolution(img image.Image) *icnk_client.Resolution {
+	return &icnk_client.Resolution{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+}
+
+func valueOrDefault(value, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
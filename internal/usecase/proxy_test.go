@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateProxies(t *testing.T) {
+	dir := t.TempDir()
+
+	imagePath := filepath.Join(dir, "image.png")
+	f, err := os.Create(imagePath)
+	assert.NoError(t, err)
+	err = png.Encode(f, image.NewRGBA(image.Rect(0, 0, 2000, 1000)))
+	assert.NoError(t, err)
+	f.Close()
+
+	cfg := &config.Config{
+		Proxy: config.ProxyConfig{
+			Enabled:   true,
+			StorageID: "8C1E1F32-54D6-4D43-9A8A-2F2A1C6E1A10",
+		},
+	}
+
+	client := client.NewMockClient()
+	client.On("GetStorage", mock.Anything, "8C1E1F32-54D6-4D43-9A8A-2F2A1C6E1A10").Return(&icnk_client.Storage{
+		ID:     "8C1E1F32-54D6-4D43-9A8A-2F2A1C6E1A10",
+		Method: "S3",
+	}, nil)
+
+	client.On("CreateProxy", mock.Anything, "47265105-BE2B-4C3F-8997-66BAB2893D0D", mock.MatchedBy(
+		func(proxy *icnk_client.Proxy) bool {
+			return proxy.Filename == "image.jpg" &&
+				proxy.StorageID == "8C1E1F32-54D6-4D43-9A8A-2F2A1C6E1A10" &&
+				*proxy.Resolution == icnk_client.Resolution{Width: 1024, Height: 512}
+		},
+	)).Return(&icnk_client.Proxy{ID: "B6C4E0B4-3D44-4A53-9A8E-5B1B0F3C7E11", UploadURL: "https://proxy"}, nil)
+	client.On(
+		"CloseProxy", mock.Anything, "47265105-BE2B-4C3F-8997-66BAB2893D0D", "B6C4E0B4-3D44-4A53-9A8E-5B1B0F3C7E11",
+	).Return(nil)
+
+	client.On("CreateKeyframe", mock.Anything, "47265105-BE2B-4C3F-8997-66BAB2893D0D", mock.MatchedBy(
+		func(keyframe *icnk_client.Keyframe) bool {
+			return keyframe.Filename == "image_keyframe.jpg" &&
+				keyframe.Type == "KEYFRAME" &&
+				*keyframe.Resolution == icnk_client.Resolution{Width: 400, Height: 200}
+		},
+	)).Return(&icnk_client.Keyframe{ID: "0F2A7C3E-1B3F-4C9E-8E0D-9A6F4B2C1D22", UploadURL: "https://keyframe"}, nil)
+	client.On(
+		"CloseKeyframe", mock.Anything, "47265105-BE2B-4C3F-8997-66BAB2893D0D", "0F2A7C3E-1B3F-4C9E-8E0D-9A6F4B2C1D22",
+	).Return(nil)
+
+	client.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(
+		func(file *icnk_client.File) bool {
+			return file.DirectoryPath == "47265105-BE2B-4C3F-8997-66BAB2893D0D" && file.Size > 0
+		},
+	)).Return(nil)
+
+	proxyUseCase := NewProxyUseCase(cfg, client)
+
+	assert.True(t, proxyUseCase.Supports(imagePath))
+	assert.False(t, proxyUseCase.Supports(filepath.Join(dir, "clip.mov")))
+
+	err = proxyUseCase.CreateProxies(context.Background(), "47265105-BE2B-4C3F-8997-66BAB2893D0D", imagePath)
+	assert.NoError(t, err)
+
+	client.AssertExpectations(t)
+	client.AssertNumberOfCalls(t, "Upload", 2)
+}
+
+func TestCreateProxiesWithoutStorage(t *testing.T) {
+	cfg := &config.Config{Proxy: config.ProxyConfig{Enabled: true}}
+
+	proxyUseCase := NewProxyUseCase(cfg, client.NewMockClient())
+
+	err := proxyUseCase.CreateProxies(context.Background(), "47265105-BE2B-4C3F-8997-66BAB2893D0D", "image.jpg")
+	assert.Error(t, err)
+}
+
+func TestCreateProxiesStorageRetried(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.png")
+	f, err := os.Create(imagePath)
+	assert.NoError(t, err)
+	err = png.Encode(f, image.NewRGBA(image.Rect(0, 0, 200, 100)))
+	assert.NoError(t, err)
+	f.Close()
+
+	cfg := &config.Config{Proxy: config.ProxyConfig{Enabled: true, StorageID: "S1", MaxPixels: 200*100 - 1}}
+
+	client := client.NewMockClient()
+	client.On("GetStorage", mock.Anything, "S1").Return(nil, errors.New("request failed: bad gateway")).Once()
+	client.On("GetStorage", mock.Anything, "S1").Return(&icnk_client.Storage{ID: "S1", Method: "S3"}, nil).Once()
+
+	proxyUseCase := NewProxyUseCase(cfg, client)
+
+	// a failed storage lookup is not cached
+	err = proxyUseCase.CreateProxies(context.Background(), "A1", imagePath)
+	assert.EqualError(t, err, "request failed: bad gateway")
+
+	// images larger than max_pixels are not decoded
+	err = proxyUseCase.CreateProxies(context.Background(), "A1", imagePath)
+	assert.True(t, errors.Is(err, imaging.ErrTooLarge))
+
+	err = proxyUseCase.CreateProxies(context.Background(), "A1", imagePath)
+	assert.True(t, errors.Is(err, imaging.ErrTooLarge))
+	client.AssertNumberOfCalls(t, "GetStorage", 2)
+	client.AssertNotCalled(t, "CreateProxy", mock.Anything, mock.Anything, mock.Anything)
+}
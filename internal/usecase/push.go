@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/manifest"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/rs/zerolog/log"
+)
+
+// Results of pushing a file
+const (
+	PushUploaded = "uploaded"
+	PushSkipped  = "skipped"
+	PushFailed   = "failed"
+)
+
+// PushResult is the outcome of pushing one manifest entry
+type PushResult struct {
+	Path   string `json:"path"`
+	Key    string `json:"key,omitempty"`
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// PushReport lists the results of Push in the order of the entries
+type PushReport struct {
+	Uploaded      int          `json:"uploaded"`
+	UploadedBytes int64        `json:"uploaded_bytes"`
+	Skipped       int          `json:"skipped"`
+	Failed        int          `json:"failed"`
+	Results       []PushResult `json:"results"`
+}
+
+// PushUseCase uploads an explicit list of files
+type PushUseCase struct {
+	config *config.Config
+
+	assetUseCase *AssetUseCase
+}
+
+func NewPushUseCase(
+	config *config.Config, client icnk_client.Client, store store.Store, storage *icnk_client.Storage,
+) *PushUseCase {
+	return &PushUseCase{
+		config: config,
+
+		assetUseCase: NewAssetUseCase(config, client, store, storage),
+	}
+}
+
+// Push uploads the entries with the configured number of upload workers
+func (uc *PushUseCase) Push(entries []manifest.Entry) *PushReport {
+	if uc.config.Metadata.Manifest.ViewID == "" {
+		for _, entry := range entries {
+			if len(entry.Metadata) > 0 {
+				log.Warn().
+					Str("service", "push_usecase").
+					Msg("The manifest has metadata columns but metadata.manifest.view_id is not set")
+				break
+			}
+		}
+	}
+
+	report := &PushReport{Results: make([]PushResult, len(entries))}
+
+	workers := uc.config.Uploader.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				report.Results[index] = uc.push(entries[index])
+			}
+		}()
+	}
+
+	for i := range entries {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, result := range report.Results {
+		switch result.Result {
+		case PushUploaded:
+			report.Uploaded++
+			report.UploadedBytes += result.Size
+		case PushSkipped:
+			report.Skipped++
+		case PushFailed:
+			report.Failed++
+		}
+	}
+
+	return report
+}
+
+func (uc *PushUseCase) push(entry manifest.Entry) PushResult {
+	result := PushResult{Path: entry.Path, Result: PushFailed}
+
+	absolutePath, err := filepath.Abs(entry.Path)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	info, err := os.Stat(absolutePath)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !info.Mode().IsRegular() {
+		result.Error = fmt.Sprintf("%s is not a regular file", entry.Path)
+		return result
+	}
+
+	result.Key = PushKey(uc.config, absolutePath)
+	result.Size = info.Size()
+
+	reason, err := uc.assetUseCase.Push(result.Key, info, UploadOptions{
+		CollectionID: entry.CollectionID,
+		Title:        entry.Title,
+		Metadata:     entry.Metadata,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("service", "push_usecase").Msgf("Error pushing %s", entry.Path)
+		result.Error = err.Error()
+		return result
+	}
+
+	if reason != "" {
+		result.Result = PushSkipped
+		result.Reason = reason
+		return result
+	}
+
+	result.Result = PushUploaded
+	return result
+}
+
+// PushKey returns the store key of a pushed file, files in the scanned
+// directory share the record of the scanner
+func PushKey(config *config.Config, absolutePath string) string {
+	dir := config.Scanner.Dir
+	if dir != "" && strings.HasPrefix(absolutePath, dir) && strings.HasSuffix(dir, "/") {
+		return strings.TrimPrefix(absolutePath, dir)
+	}
+
+	return absolutePath
+}
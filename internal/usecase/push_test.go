@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/manifest"
+	"github.com/kgantsov/synconik/internal/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPush(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir() + "/"
+	scanned := dir + "media/"
+	assert.NoError(t, os.MkdirAll(scanned, 0755))
+	assert.NoError(t, os.MkdirAll(dir+"delivery", 0755))
+
+	files := map[string]string{
+		"delivery/clip.mov": "video",
+		"media/synced.mov":  "video",
+		"media/photo.jpg":   "photo!",
+	}
+	for path, content := range files {
+		assert.NoError(t, os.WriteFile(dir+path, []byte(content), 0644))
+	}
+
+	synced := &entity.File{Name: "synced.mov", AssetID: "A0", ID: "F0"}
+	synced.SetState(entity.StateSynced, nil)
+	assert.NoError(t, store.SaveFile("synced.mov", synced))
+
+	cfg := &config.Config{
+		Scanner:     config.ScannerConfig{Dir: scanned},
+		Uploader:    config.UploaderConfig{Workers: 2},
+		Metadata:    config.MetadataConfig{Manifest: config.ManifestMetadataConfig{ViewID: "V1"}},
+		Transcoding: config.TranscodingConfig{Action: TranscodeActionSkip},
+	}
+
+	mockClient := client.NewMockClient()
+	storage := &icnk_client.Storage{ID: "S1", Method: "S3"}
+
+	mockClient.On("CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "Opening", Status: "ACTIVE", Type: "ASSET", CollectionID: "C1",
+	}).Return(&icnk_client.Asset{ID: "A1"}, nil)
+	mockClient.On("CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "photo.jpg", Status: "ACTIVE", Type: "ASSET",
+	}).Return(&icnk_client.Asset{ID: "A2"}, nil)
+	mockClient.On(
+		"UpdateAssetMetadata", mock.Anything, "A1", "V1", icnk_client.NewMetadata(metadata.Values{"scene": {"1"}}),
+	).Return(&icnk_client.Metadata{}, nil)
+	mockClient.On("CreateAssetFormat", mock.Anything, mock.Anything, mock.Anything).
+		Return(&icnk_client.Format{ID: "FMT"}, nil)
+	mockClient.On("CreateFileSet", mock.Anything, mock.Anything, mock.Anything).
+		Return(&icnk_client.FileSet{ID: "FS"}, nil)
+	mockClient.On("CreateFile", mock.Anything, "A1", mock.Anything).Return(&icnk_client.File{ID: "F1"}, nil)
+	mockClient.On("CreateFile", mock.Anything, "A2", mock.Anything).Return(&icnk_client.File{ID: "F2"}, nil)
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	report := NewPushUseCase(cfg, mockClient, store, storage).Push([]manifest.Entry{
+		{
+			Path:         dir + "delivery/clip.mov",
+			CollectionID: "C1",
+			Title:        "Opening",
+			Metadata:     metadata.Values{"scene": {"1"}},
+		},
+		{Path: dir + "media/photo.jpg"},
+		{Path: dir + "media/synced.mov"},
+		{Path: dir + "media/missing.mov"},
+		{Path: dir + "media"},
+	})
+
+	assert.Equal(t, 2, report.Uploaded)
+	assert.Equal(t, int64(11), report.UploadedBytes)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 2, report.Failed)
+
+	assert.Equal(t, PushResult{
+		Path: dir + "delivery/clip.mov", Key: dir + "delivery/clip.mov", Result: PushUploaded, Size: 5,
+	}, report.Results[0])
+	assert.Equal(t, PushResult{
+		Path: dir + "media/photo.jpg", Key: "photo.jpg", Result: PushUploaded, Size: 6,
+	}, report.Results[1])
+	assert.Equal(t, PushResult{
+		Path: dir + "media/synced.mov", Key: "synced.mov", Result: PushSkipped, Reason: SkipSynced, Size: 5,
+	}, report.Results[2])
+	assert.Equal(t, PushFailed, report.Results[3].Result)
+	assert.Contains(t, report.Results[3].Error, "no such file or directory")
+	assert.Equal(t, PushFailed, report.Results[4].Result)
+	assert.Contains(t, report.Results[4].Error, "is not a regular file")
+
+	file, err := store.GetFile(dir + "delivery/clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "A1", file.AssetID)
+
+	file, err = store.GetFile("photo.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, "F2", file.ID)
+}
+
+func TestLocalPath(t *testing.T) {
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: "/media/"}}
+	assert.Equal(t, "/media/rushes/clip.mov", LocalPath(cfg, "rushes/clip.mov"))
+	assert.Equal(t, "/media/", LocalPath(cfg, ""))
+	assert.Equal(t, "/delivery/clip.mov", LocalPath(cfg, "/delivery/clip.mov"))
+
+	assert.Equal(t, "rushes/clip.mov", PushKey(cfg, "/media/rushes/clip.mov"))
+	assert.Equal(t, "/delivery/clip.mov", PushKey(cfg, "/delivery/clip.mov"))
+	assert.Equal(t, "/media/clip.mov", PushKey(&config.Config{}, "/media/clip.mov"))
+}
@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/rs/zerolog/log"
+)
+
+const collectionsPerPage = 500
+
+// RebuildReport counts what RebuildState restored and skipped
+type RebuildReport struct {
+	Files        int `json:"files"`
+	Directories  int `json:"directories"`
+	Stored       int `json:"already_stored"`
+	NotLocal     int `json:"not_local"`
+	SizeMismatch int `json:"size_mismatch"`
+	Duplicates   int `json:"duplicates"`
+}
+
+type RebuildUseCase struct {
+	config  *config.Config
+	client  icnk_client.Client
+	store   store.Store
+	storage *icnk_client.Storage
+}
+
+func NewRebuildUseCase(
+	config *config.Config, client icnk_client.Client, store store.Store, storage *icnk_client.Storage,
+) *RebuildUseCase {
+	return &RebuildUseCase{
+		config:  config,
+		client:  client,
+		store:   store,
+		storage: storage,
+	}
+}
+
+// RebuildState repopulates the store from Iconik. Files on the storage are
+// matched to local files by path and size, directories to the collections
+// created for them by title below the collection of the scanned directory.
+// Records of files that are already synced are kept.
+func (uc *RebuildUseCase) RebuildState(ctx context.Context) (*RebuildReport, error) {
+	report := &RebuildReport{}
+
+	if err := uc.rebuildDirectories(ctx, report); err != nil {
+		return nil, err
+	}
+
+	matched := map[string]bool{}
+
+	err := WalkStorageFiles(ctx, uc.client, uc.storage.ID, func(file *icnk_client.File) error {
+		if file.AssetID == "" || file.Status != icnk_client.FileStatusClosed {
+			return nil
+		}
+
+		path := StorageFilePath(file)
+
+		info, err := os.Stat(uc.config.Scanner.Dir + path)
+		if err != nil || info.IsDir() {
+			report.NotLocal++
+			return nil
+		}
+		if info.Size() != file.Size {
+			report.SizeMismatch++
+			return nil
+		}
+
+		// the first of several uploads of the same file wins
+		if matched[path] {
+			report.Duplicates++
+			return nil
+		}
+		matched[path] = true
+
+		restored, err := uc.restore(path, NewFileRecordFromIconik(file))
+		if err != nil {
+			return err
+		}
+		if restored {
+			report.Files++
+		} else {
+			report.Stored++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// rebuildDirectories walks the local directories and restores the records of
+// those with a matching collection
+func (uc *RebuildUseCase) rebuildDirectories(ctx context.Context, report *RebuildReport) error {
+	root, err := os.Stat(uc.config.Scanner.Dir)
+	if err != nil {
+		return err
+	}
+
+	rootID, err := uc.findRootCollection(ctx, root.Name())
+	if err != nil || rootID == "" {
+		return err
+	}
+
+	collections := map[string]string{"": rootID}
+	children := map[string]map[string]string{}
+
+	return filepath.Walk(uc.config.Scanner.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		relativePath := strings.TrimPrefix(path, uc.config.Scanner.Dir)
+
+		if relativePath != "" {
+			parentID, ok := collections[parentPath(relativePath)]
+			if !ok {
+				return filepath.SkipDir
+			}
+
+			if _, ok := children[parentID]; !ok {
+				children[parentID], err = uc.childCollections(ctx, parentID)
+				if err != nil {
+					return err
+				}
+			}
+
+			id, ok := children[parentID][info.Name()]
+			if !ok {
+				return filepath.SkipDir
+			}
+			collections[relativePath] = id
+		}
+
+		dirPath := ""
+		if len(relativePath) > 1 {
+			dirPath = relativePath[:len(relativePath)-len(info.Name())]
+		}
+
+		restored, err := uc.restore(relativePath, &entity.File{
+			DirectoryPath: dirPath,
+			Name:          info.Name(),
+			Type:          "directory",
+			ID:            collections[relativePath],
+		})
+		if err != nil {
+			return err
+		}
+		if restored {
+			report.Directories++
+		} else {
+			report.Stored++
+		}
+
+		return nil
+	})
+}
+
+// restore saves the record unless the path is already synced
+func (uc *RebuildUseCase) restore(path string, file *entity.File) (bool, error) {
+	stored, err := uc.store.GetFile(path)
+	if err != nil && !errors.Is(err, store.ErrFileNotFound) {
+		return false, err
+	}
+	if stored != nil && stored.CurrentState() == entity.StateSynced {
+		return false, nil
+	}
+
+	log.Debug().Str("service", "rebuild_usecase").Msgf("Restoring %s", path)
+
+	return true, uc.store.SaveFile(path, file)
+}
+
+// findRootCollection returns the ID of the top level collection with the
+// title of the scanned directory
+func (uc *RebuildUseCase) findRootCollection(ctx context.Context, title string) (string, error) {
+	for page := 1; ; page++ {
+		collections, err := uc.client.ListCollections(ctx, page, collectionsPerPage)
+		if err != nil {
+			return "", err
+		}
+
+		for _, collection := range collections.Objects {
+			if collection.ParentID == "" && collection.Title == title &&
+				collection.Status != icnk_client.CollectionStatusDeleted {
+				return collection.ID, nil
+			}
+		}
+
+		if page >= collections.Pages {
+			log.Warn().Str("service", "rebuild_usecase").Msgf("No collection found for %s", title)
+			return "", nil
+		}
+	}
+}
+
+// childCollections returns the IDs of the collections inside a collection by
+// title
+func (uc *RebuildUseCase) childCollections(ctx context.Context, id string) (map[string]string, error) {
+	children := map[string]string{}
+
+	for page := 1; ; page++ {
+		contents, err := uc.client.ListCollectionContents(ctx, id, page, collectionsPerPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, collection := range contents.Objects {
+			if _, ok := children[collection.Title]; !ok {
+				children[collection.Title] = collection.ID
+			}
+		}
+
+		if page >= contents.Pages {
+			return children, nil
+		}
+	}
+}
+
+// parentPath returns the store key of the parent directory
+func parentPath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
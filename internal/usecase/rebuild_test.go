@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRebuildState(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := filepath.Join(t.TempDir(), "media") + "/"
+	for _, path := range []string{"rushes/day1/a.mov", "rushes/b.mov", "rushes/changed.mov", "rushes/synced.mov", "stills/c.jpg"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("test"), 0644))
+	}
+
+	assert.NoError(t, store.SaveFile("rushes/synced.mov", &entity.File{Name: "synced.mov", AssetID: "A0", ID: "F0"}))
+	assert.NoError(t, store.SaveFile("rushes/b.mov", &entity.File{Name: "b.mov", State: entity.StateFailed}))
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir}}
+	storage := &icnk_client.Storage{ID: "S1", Method: "S3"}
+
+	client := client.NewMockClient()
+	client.On("ListCollections", mock.Anything, 1, collectionsPerPage).Return(&icnk_client.CollectionList{
+		Objects: []icnk_client.Collection{
+			{ID: "C0", Title: "media", Status: "DELETED"},
+			{ID: "C9", Title: "rushes", ParentID: "C7"},
+		},
+		Page:  1,
+		Pages: 2,
+	}, nil)
+	client.On("ListCollections", mock.Anything, 2, collectionsPerPage).Return(&icnk_client.CollectionList{
+		Objects: []icnk_client.Collection{{ID: "C1", Title: "media"}},
+		Page:    2,
+		Pages:   2,
+	}, nil)
+	client.On("ListCollectionContents", mock.Anything, "C1", 1, collectionsPerPage).Return(&icnk_client.CollectionList{
+		Objects: []icnk_client.Collection{{ID: "C2", Title: "rushes"}},
+		Pages:   1,
+	}, nil)
+	client.On("ListCollectionContents", mock.Anything, "C2", 1, collectionsPerPage).Return(&icnk_client.CollectionList{
+		Objects: []icnk_client.Collection{{ID: "C3", Title: "day1"}},
+		Pages:   1,
+	}, nil)
+	client.On("ListCollectionContents", mock.Anything, "C3", 1, collectionsPerPage).Return(&icnk_client.CollectionList{
+		Pages: 1,
+	}, nil)
+	client.On("ListStorageFiles", mock.Anything, "S1", 1, storageFilesPerPage).Return(&icnk_client.FileList{
+		Objects: []icnk_client.File{
+			{ID: "F1", AssetID: "A1", FormatID: "FM1", FileSetID: "FS1", DirectoryPath: "rushes/day1/", Name: "a.mov", Size: 4, Status: "CLOSED"},
+			{ID: "F8", AssetID: "A8", DirectoryPath: "rushes/day1/", Name: "a.mov", Size: 4, Status: "CLOSED"},
+			{ID: "F2", AssetID: "A2", DirectoryPath: "rushes/", Name: "b.mov", Size: 4, Status: "CLOSED"},
+			{ID: "F3", AssetID: "A3", DirectoryPath: "rushes/", Name: "changed.mov", Size: 100, Status: "CLOSED"},
+			{ID: "F4", AssetID: "A4", DirectoryPath: "rushes/", Name: "synced.mov", Size: 4, Status: "CLOSED"},
+			{ID: "F5", AssetID: "A5", DirectoryPath: "archive/", Name: "old.mov", Size: 4, Status: "CLOSED"},
+			{ID: "F6", AssetID: "A6", DirectoryPath: "stills/", Name: "c.jpg", Size: 4, Status: "OPEN"},
+		},
+		Page:  1,
+		Pages: 1,
+	}, nil)
+
+	report, err := NewRebuildUseCase(cfg, client, store, storage).RebuildState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &RebuildReport{
+		Files:        2,
+		Directories:  3,
+		Stored:       1,
+		NotLocal:     1,
+		SizeMismatch: 1,
+		Duplicates:   1,
+	}, report)
+
+	root, err := store.GetFile("")
+	assert.NoError(t, err)
+	assert.Equal(t, "C1", root.ID)
+
+	day1, err := store.GetFile("rushes/day1")
+	assert.NoError(t, err)
+	assert.Equal(t, "C3", day1.ID)
+	assert.Equal(t, "rushes/", day1.DirectoryPath)
+	assert.True(t, day1.IsDir())
+
+	exists, err := store.ExistsFile("stills")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	file, err := store.GetFile("rushes/day1/a.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "A1", file.AssetID)
+	assert.Equal(t, "FS1", file.FileSetID)
+	assert.Equal(t, entity.StateSynced, file.State)
+
+	file, err = store.GetFile("rushes/b.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "A2", file.AssetID)
+
+	file, err = store.GetFile("rushes/synced.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "A0", file.AssetID)
+}
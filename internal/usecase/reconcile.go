@@ -0,0 +1,467 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/sequence"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/rs/zerolog/log"
+)
+
+const storageFilesPerPage = 500
+
+// Kinds of discrepancies between the store and Iconik
+const (
+	DiscrepancyMissingCollection = "missing_collection"
+	DiscrepancyMissingAsset      = "missing_asset"
+	DiscrepancyMissingFile       = "missing_file"
+	DiscrepancyNotClosed         = "not_closed"
+	DiscrepancyDangling          = "dangling"
+	DiscrepancyOrphan            = "orphan"
+)
+
+// Actions fixing a discrepancy
+const (
+	ActionRecreateCollection = "recreate_collection"
+	ActionReupload           = "reupload"
+	ActionDrop               = "drop"
+	ActionAdopt              = "adopt"
+)
+
+// Discrepancy is a stored record that does not match Iconik, or an Iconik
+// file that matches a local file the store does not know about
+type Discrepancy struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+	Action string `json:"action"`
+	Fixed  bool   `json:"fixed"`
+	Error  string `json:"error,omitempty"`
+
+	// orphan is the Iconik file adopted by ActionAdopt
+	orphan *icnk_client.File
+}
+
+// ReconcileReport lists the discrepancies found by Reconcile
+type ReconcileReport struct {
+	Checked       int           `json:"checked"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+type ReconcileUseCase struct {
+	config  *config.Config
+	client  icnk_client.Client
+	store   store.Store
+	storage *icnk_client.Storage
+
+	assetUseCase      *AssetUseCase
+	collectionUseCase *CollectionUseCase
+}
+
+func NewReconcileUseCase(
+	config *config.Config, client icnk_client.Client, store store.Store, storage *icnk_client.Storage,
+) *ReconcileUseCase {
+	return &ReconcileUseCase{
+		config:  config,
+		client:  client,
+		store:   store,
+		storage: storage,
+
+		assetUseCase:      NewAssetUseCase(config, client, store, storage),
+		collectionUseCase: NewCollectionUseCase(config, client, store),
+	}
+}
+
+type storedRecord struct {
+	path string
+	file *entity.File
+}
+
+// Reconcile compares every stored record with Iconik and the local files,
+// the discrepancies are only fixed when fix is set
+func (uc *ReconcileUseCase) Reconcile(ctx context.Context, fix bool) (*ReconcileReport, error) {
+	records := []storedRecord{}
+	err := uc.store.WalkFiles("", func(path string, file *entity.File) error {
+		records = append(records, storedRecord{path: path, file: file})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orphans, err := uc.storageFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconcileReport{Checked: len(records), Discrepancies: []Discrepancy{}}
+	known := map[string]bool{}
+	sequences := []*sequence.Sequence{}
+
+	for _, record := range records {
+		known[record.path] = true
+		for _, key := range MemberKeys(record.path, record.file) {
+			known[key] = true
+		}
+		for _, key := range record.file.Components {
+			known[key] = true
+		}
+		if seq, ok := sequence.FromKey(record.path); ok && record.file.Frames > 0 {
+			sequences = append(sequences, seq)
+		}
+
+		discrepancy, err := uc.check(ctx, record.path, record.file, orphans)
+		if err != nil {
+			return nil, err
+		}
+		if discrepancy != nil {
+			report.Discrepancies = append(report.Discrepancies, *discrepancy)
+		}
+	}
+
+	paths := make([]string, 0, len(orphans))
+	for path := range orphans {
+		if !known[path] && !isFrame(sequences, path) && uc.existsLocally(path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		file := orphans[path]
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Path:   path,
+			Kind:   DiscrepancyOrphan,
+			Detail: "asset " + file.AssetID + " is not in the store",
+			Action: ActionAdopt,
+			orphan: file,
+		})
+	}
+
+	if fix {
+		for i := range report.Discrepancies {
+			uc.fix(&report.Discrepancies[i])
+		}
+	}
+
+	return report, nil
+}
+
+// check returns the discrepancy of a stored record or nil when it matches
+// Iconik
+func (uc *ReconcileUseCase) check(
+	ctx context.Context, path string, file *entity.File, orphans map[string]*icnk_client.File,
+) (*Discrepancy, error) {
+	exists := uc.existsLocally(path)
+	if file.Frames > 0 {
+		exists = FindSequence(uc.config, path) != nil
+	}
+	if file.Archive != "" {
+		exists = uc.existsLocally(file.Archive)
+	}
+
+	// the post-upload action removed the local file on purpose
+	if !file.LocalFileRemoved() && !exists {
+		return &Discrepancy{
+			Path: path, Kind: DiscrepancyDangling, Detail: "local file no longer exists", Action: ActionDrop,
+		}, nil
+	}
+
+	if file.IsDir() {
+		if file.ID == "" {
+			return nil, nil
+		}
+
+		collection, err := uc.client.GetCollection(ctx, file.ID)
+		if errors.Is(err, icnk_client.ErrNotFound) || (err == nil && collection.Status == icnk_client.CollectionStatusDeleted) {
+			return &Discrepancy{
+				Path:   path,
+				Kind:   DiscrepancyMissingCollection,
+				Detail: "collection " + file.ID + " does not exist",
+				Action: ActionRecreateCollection,
+			}, nil
+		}
+		return nil, err
+	}
+
+	// the scanner uploads files that were not synced yet
+	if file.AssetID == "" || file.ID == "" {
+		if orphan, ok := orphans[path]; ok {
+			return &Discrepancy{
+				Path:   path,
+				Kind:   DiscrepancyOrphan,
+				Detail: "asset " + orphan.AssetID + " is not in the store",
+				Action: ActionAdopt,
+				orphan: orphan,
+			}, nil
+		}
+		return nil, nil
+	}
+
+	discrepancy, err := uc.checkAsset(ctx, path, file)
+	if discrepancy == nil || err != nil {
+		return discrepancy, err
+	}
+
+	// prefer another asset of the same file over uploading it again
+	if orphan, ok := orphans[path]; ok && orphan.AssetID != file.AssetID {
+		discrepancy.Action = ActionAdopt
+		discrepancy.orphan = orphan
+	}
+
+	return discrepancy, nil
+}
+
+func (uc *ReconcileUseCase) checkAsset(ctx context.Context, path string, file *entity.File) (*Discrepancy, error) {
+	asset, err := uc.client.GetAsset(ctx, file.AssetID)
+	if errors.Is(err, icnk_client.ErrNotFound) || (err == nil && asset.Status == icnk_client.AssetStatusDeleted) {
+		return &Discrepancy{
+			Path:   path,
+			Kind:   DiscrepancyMissingAsset,
+			Detail: "asset " + file.AssetID + " does not exist",
+			Action: ActionReupload,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	iconikFile, err := uc.client.GetFile(ctx, file.AssetID, file.ID)
+	if errors.Is(err, icnk_client.ErrNotFound) || (err == nil && iconikFile.Status == icnk_client.FileStatusDeleted) {
+		return &Discrepancy{
+			Path:   path,
+			Kind:   DiscrepancyMissingFile,
+			Detail: "file " + file.ID + " of asset " + file.AssetID + " does not exist",
+			Action: ActionReupload,
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if iconikFile.Status != icnk_client.FileStatusClosed {
+		return &Discrepancy{
+			Path:   path,
+			Kind:   DiscrepancyNotClosed,
+			Detail: "file " + file.ID + " is " + iconikFile.Status,
+			Action: ActionReupload,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func (uc *ReconcileUseCase) fix(discrepancy *Discrepancy) {
+	var err error
+
+	switch discrepancy.Action {
+	case ActionDrop:
+		err = uc.store.DeleteFile(discrepancy.Path)
+	case ActionRecreateCollection:
+		err = uc.recreateCollection(discrepancy.Path)
+	case ActionReupload:
+		err = uc.reupload(discrepancy.Path, discrepancy.Kind)
+	case ActionAdopt:
+		err = uc.store.SaveFile(discrepancy.Path, NewFileRecordFromIconik(discrepancy.orphan))
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("service", "reconcile_usecase").Msgf("Error fixing %s", discrepancy.Path)
+		discrepancy.Error = err.Error()
+		return
+	}
+
+	log.Info().
+		Str("service", "reconcile_usecase").
+		Msgf("Fixed %s of %s: %s", discrepancy.Kind, discrepancy.Path, discrepancy.Action)
+	discrepancy.Fixed = true
+}
+
+// recreateCollection creates the collection of a directory record again, the
+// rest of the record like the state of an archive ingestion is kept
+func (uc *ReconcileUseCase) recreateCollection(path string) error {
+	file, err := uc.store.GetFile(path)
+	if err != nil {
+		return err
+	}
+
+	created, err := uc.collectionUseCase.createCollection(path, file.Name)
+	if err != nil {
+		return err
+	}
+
+	file.ID = created.ID
+	return uc.store.SaveFile(path, file)
+}
+
+func (uc *ReconcileUseCase) reupload(path, kind string) error {
+	// archive members are uploaded again by ingesting their archive
+	if file, err := uc.store.GetFile(path); err == nil && file.Archive != "" {
+		info, err := os.Stat(LocalPath(uc.config, file.Archive))
+		if err != nil {
+			return err
+		}
+		if err := uc.resetRecord(path, kind); err != nil {
+			return err
+		}
+		return uc.assetUseCase.IngestArchive(file.Archive, info)
+	}
+
+	if seq := FindSequence(uc.config, path); seq != nil {
+		if err := uc.resetRecord(path, kind); err != nil {
+			return err
+		}
+		return uc.assetUseCase.UploadSequenceIfNotExists(path, seq)
+	}
+
+	if clip := FindClip(uc.config, path); clip != nil {
+		if err := uc.resetRecord(path, kind); err != nil {
+			return err
+		}
+		return uc.assetUseCase.UploadClipIfNotExists(path, clip)
+	}
+
+	if g := FindGroup(uc.config, path); g != nil {
+		if err := uc.resetRecord(path, kind); err != nil {
+			return err
+		}
+		return uc.assetUseCase.UploadGroupIfNotExists(path, g)
+	}
+
+	info, err := os.Stat(LocalPath(uc.config, path))
+	if err != nil {
+		return err
+	}
+
+	if err := uc.resetRecord(path, kind); err != nil {
+		return err
+	}
+
+	return uc.assetUseCase.UploadIfNotExists(path, info)
+}
+
+// resetRecord prepares the record of a file to upload again. It is dropped
+// when its asset is gone, otherwise it is marked as pending and keeps its
+// asset, so the file becomes a new version of it rather than another asset.
+func (uc *ReconcileUseCase) resetRecord(path, kind string) error {
+	if kind == DiscrepancyMissingAsset {
+		return uc.store.DeleteFile(path)
+	}
+
+	return uc.store.UpdateFile(path, func(file *entity.File) error {
+		file.SetState(entity.StatePending, nil)
+		return nil
+	})
+}
+
+func (uc *ReconcileUseCase) existsLocally(path string) bool {
+	_, err := os.Stat(LocalPath(uc.config, path))
+	return err == nil
+}
+
+// isFrame reports whether the path is a frame of one of the sequences
+func isFrame(sequences []*sequence.Sequence, path string) bool {
+	dir, name := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		dir, name = path[:i+1], path[i+1:]
+	}
+
+	for _, seq := range sequences {
+		if seq.Dir == dir && seq.Match(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// storageFiles returns the closed files of the storage by the path relative
+// to the scanned directory
+func (uc *ReconcileUseCase) storageFiles(ctx context.Context) (map[string]*icnk_client.File, error) {
+	files := map[string]*icnk_client.File{}
+
+	err := WalkStorageFiles(ctx, uc.client, uc.storage.ID, func(file *icnk_client.File) error {
+		if file.AssetID == "" || file.Status != icnk_client.FileStatusClosed {
+			return nil
+		}
+		files[StorageFilePath(file)] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// WalkStorageFiles calls fn for every file on the storage
+func WalkStorageFiles(
+	ctx context.Context, client icnk_client.Client, storageID string, fn func(file *icnk_client.File) error,
+) error {
+	for page := 1; ; page++ {
+		files, err := client.ListStorageFiles(ctx, storageID, page, storageFilesPerPage)
+		if err != nil {
+			return err
+		}
+
+		for i := range files.Objects {
+			if err := fn(&files.Objects[i]); err != nil {
+				return err
+			}
+		}
+
+		if page >= files.Pages {
+			return nil
+		}
+	}
+}
+
+// StorageFilePath returns the path of an Iconik file relative to the scanned
+// directory, the form used as store key
+func StorageFilePath(file *icnk_client.File) string {
+	name := file.Name
+	if name == "" {
+		name = file.OriginalName
+	}
+
+	dir := strings.Trim(file.DirectoryPath, "/")
+	if dir == "" {
+		return name
+	}
+
+	return dir + "/" + name
+}
+
+// NewFileRecordFromIconik returns the store record of a file uploaded to
+// Iconik
+func NewFileRecordFromIconik(file *icnk_client.File) *entity.File {
+	name := file.Name
+	if name == "" {
+		name = file.OriginalName
+	}
+	path := StorageFilePath(file)
+
+	f := &entity.File{
+		DirectoryPath:    path[:len(path)-len(name)],
+		Name:             name,
+		Type:             "FILE",
+		AssetID:          file.AssetID,
+		StorageID:        file.StorageID,
+		FormatID:         file.FormatID,
+		FileSetID:        file.FileSetID,
+		ID:               file.ID,
+		Size:             int(file.Size),
+		FileDateCreated:  file.FileDateCreated,
+		FileDateModified: file.FileDateModified,
+	}
+	f.SetState(entity.StateSynced, nil)
+
+	return f
+}
@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/sequence"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStorageFilePath(t *testing.T) {
+	assert.Equal(t, "rushes/clip.mov", StorageFilePath(&icnk_client.File{DirectoryPath: "rushes/", Name: "clip.mov"}))
+	assert.Equal(t, "rushes/clip.mov", StorageFilePath(&icnk_client.File{DirectoryPath: "/rushes", OriginalName: "clip.mov"}))
+	assert.Equal(t, "clip.mov", StorageFilePath(&icnk_client.File{Name: "clip.mov"}))
+}
+
+func TestWalkStorageFiles(t *testing.T) {
+	client := client.NewMockClient()
+	client.On("ListStorageFiles", mock.Anything, "S1", 1, storageFilesPerPage).Return(&icnk_client.FileList{
+		Objects: []icnk_client.File{{ID: "F1"}, {ID: "F2"}}, Page: 1, Pages: 2,
+	}, nil)
+	client.On("ListStorageFiles", mock.Anything, "S1", 2, storageFilesPerPage).Return(&icnk_client.FileList{
+		Objects: []icnk_client.File{{ID: "F3"}}, Page: 2, Pages: 2,
+	}, nil)
+
+	ids := []string{}
+	err := WalkStorageFiles(context.Background(), client, "S1", func(file *icnk_client.File) error {
+		ids = append(ids, file.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"F1", "F2", "F3"}, ids)
+}
+
+func TestReconcile(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	dir := t.TempDir() + "/"
+	for _, path := range []string{"rushes/ok.mov", "rushes/deleted.mov", "rushes/open.mov", "rushes/pending.mov", "rushes/new.mov"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(dir+path), 0755))
+		assert.NoError(t, os.WriteFile(dir+path, []byte("test"), 0644))
+	}
+	assert.NoError(t, os.Mkdir(dir+"gone", 0755))
+
+	records := map[string]*entity.File{
+		"rushes":             {Name: "rushes", Type: "directory", ID: "C1"},
+		"gone":               {Name: "gone", Type: "directory", ID: "C2"},
+		"rushes/ok.mov":      {Name: "ok.mov", AssetID: "A1", ID: "F1"},
+		"rushes/deleted.mov": {Name: "deleted.mov", AssetID: "A2", ID: "F2"},
+		"rushes/open.mov":    {Name: "open.mov", AssetID: "A3", ID: "F3"},
+		"rushes/pending.mov": {Name: "pending.mov", State: entity.StatePending},
+		"rushes/removed.mov": {Name: "removed.mov", AssetID: "A5", ID: "F5"},
+	}
+	for path, file := range records {
+		assert.NoError(t, store.SaveFile(path, file))
+	}
+
+	cfg := &config.Config{Scanner: config.ScannerConfig{Dir: dir}}
+	storage := &icnk_client.Storage{ID: "S1", Method: "S3"}
+
+	client := client.NewMockClient()
+	client.On("ListStorageFiles", mock.Anything, "S1", 1, storageFilesPerPage).Return(&icnk_client.FileList{
+		Objects: []icnk_client.File{
+			{ID: "F1", AssetID: "A1", DirectoryPath: "rushes/", Name: "ok.mov", Status: "CLOSED"},
+			{ID: "F3", AssetID: "A3", DirectoryPath: "rushes/", Name: "open.mov", Status: "OPEN"},
+			{ID: "F4", AssetID: "A4", DirectoryPath: "rushes/", Name: "pending.mov", Status: "CLOSED", Size: 4},
+			{ID: "F6", AssetID: "A6", DirectoryPath: "rushes/", Name: "new.mov", Status: "CLOSED", FormatID: "FM6"},
+			{ID: "F7", AssetID: "A7", DirectoryPath: "other/", Name: "elsewhere.mov", Status: "CLOSED"},
+		},
+		Page:  1,
+		Pages: 1,
+	}, nil)
+	client.On("GetCollection", mock.Anything, "C1").Return(&icnk_client.Collection{ID: "C1", Status: "ACTIVE"}, nil)
+	client.On("GetCollection", mock.Anything, "C2").Return(
+		nil, fmt.Errorf("request failed: %w", icnk_client.ErrNotFound),
+	)
+	client.On("GetAsset", mock.Anything, "A1").Return(&icnk_client.Asset{ID: "A1", Status: "ACTIVE"}, nil)
+	client.On("GetFile", mock.Anything, "A1", "F1").Return(&icnk_client.File{ID: "F1", Status: "CLOSED"}, nil)
+	client.On("GetAsset", mock.Anything, "A2").Return(&icnk_client.Asset{ID: "A2", Status: "DELETED"}, nil)
+	client.On("GetAsset", mock.Anything, "A3").Return(&icnk_client.Asset{ID: "A3", Status: "ACTIVE"}, nil)
+	client.On("GetFile", mock.Anything, "A3", "F3").Return(&icnk_client.File{ID: "F3", Status: "OPEN"}, nil)
+
+	reconcileUseCase := NewReconcileUseCase(cfg, client, store, storage)
+
+	report, err := reconcileUseCase.Reconcile(context.Background(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, report.Checked)
+
+	found := map[string]string{}
+	for _, discrepancy := range report.Discrepancies {
+		assert.False(t, discrepancy.Fixed)
+		found[discrepancy.Path] = discrepancy.Kind + " " + discrepancy.Action
+	}
+	assert.Equal(t, map[string]string{
+		"gone":               "missing_collection recreate_collection",
+		"rushes/deleted.mov": "missing_asset reupload",
+		"rushes/open.mov":    "not_closed reupload",
+		"rushes/pending.mov": "orphan adopt",
+		"rushes/removed.mov": "dangling drop",
+		"rushes/new.mov":     "orphan adopt",
+	}, found)
+
+	// nothing changes without fix
+	exists, err := store.ExistsFile("rushes/removed.mov")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	client.On("CreateCollection", mock.Anything, &icnk_client.Collection{Title: "gone"}).Return(
+		&icnk_client.Collection{ID: "C8"}, nil,
+	)
+	client.On("CreateAsset", mock.Anything, mock.Anything).Return(nil, errors.New("request failed: forbidden"))
+	client.On("CreateAssetVersion", mock.Anything, "A3").Return(nil, errors.New("request failed: forbidden"))
+
+	report, err = reconcileUseCase.Reconcile(context.Background(), true)
+	assert.NoError(t, err)
+
+	for _, discrepancy := range report.Discrepancies {
+		if discrepancy.Action == ActionReupload {
+			assert.False(t, discrepancy.Fixed, discrepancy.Path)
+			assert.Equal(t, "request failed: forbidden", discrepancy.Error)
+		} else {
+			assert.True(t, discrepancy.Fixed, discrepancy.Path)
+		}
+	}
+
+	exists, err = store.ExistsFile("rushes/removed.mov")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	collection, err := store.GetFile("gone")
+	assert.NoError(t, err)
+	assert.Equal(t, "C8", collection.ID)
+
+	adopted, err := store.GetFile("rushes/new.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "A6", adopted.AssetID)
+	assert.Equal(t, "FM6", adopted.FormatID)
+	assert.Equal(t, "rushes/", adopted.DirectoryPath)
+	assert.Equal(t, entity.StateSynced, adopted.State)
+
+	adopted, err = store.GetFile("rushes/pending.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "A4", adopted.AssetID)
+	assert.Equal(t, 4, adopted.Size)
+
+	failed, err := store.GetFile("rushes/deleted.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, failed.State)
+	assert.Equal(t, "", failed.AssetID)
+
+	// a file not closed in an existing asset is uploaded as a new version of it
+	failed, err = store.GetFile("rushes/open.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, failed.State)
+	assert.Equal(t, "A3", failed.AssetID)
+	client.AssertNumberOfCalls(t, "CreateAsset", 1)
+	client.AssertNumberOfCalls(t, "CreateAssetVersion", 1)
+}
+
+func TestIsFrame(t *testing.T) {
+	shot, _ := sequence.FromKey("shots/shot_####.exr")
+	plate, _ := sequence.FromKey("plate.#.dpx")
+	sequences := []*sequence.Sequence{shot, plate}
+
+	assert.True(t, isFrame(sequences, "shots/shot_0001.exr"))
+	assert.True(t, isFrame(sequences, "plate.12.dpx"))
+	assert.False(t, isFrame(sequences, "shot_0001.exr"))
+	assert.False(t, isFrame(sequences, "shots/clip.mov"))
+}
@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/sequence"
+	"github.com/kgantsov/synconik/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultSequenceParallel = 4
+
+// UploadSequenceIfNotExists uploads an image sequence as one asset, the
+// sequence is tracked in the store under its key like a single file
+func (uc *AssetUseCase) UploadSequenceIfNotExists(path string, seq *sequence.Sequence) error {
+	reason, err := uc.filterUseCase.Skip(path, nil)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		log.Debug().Str("service", "asset_usecase").Msgf("Skipping %s sequence: %s", reason, path)
+		return nil
+	}
+
+	file, uploadErr := uc.UploadSequence(path, seq)
+	return uc.saveResult(path, file, uploadErr)
+}
+
+// UploadSequence creates one asset for the sequence. The ORIGINAL format has
+// an image component for each frame and a single file set holding all of
+// them, the frames are uploaded in parallel. Sequences are not transcoded.
+func (uc *AssetUseCase) UploadSequence(path string, seq *sequence.Sequence) (*entity.File, error) {
+	iconikStorage, err := storage.NewStorage(uc.storage.Method, newUploadHTTPClient())
+	if err != nil {
+		return nil, err
+	}
+
+	info := seq.Info()
+	assetID := uc.storedAssetID(path)
+
+	f := NewFileRecord(path, info)
+	f.Frames = len(seq.Frames)
+	f.SetState(entity.StateUploading, nil)
+	dirPath := f.DirectoryPath
+
+	log.Debug().
+		Str("service", "asset_usecase").
+		Msgf("Uploading sequence: %s with %d frames directory: %s", path, f.Frames, dirPath)
+
+	err = uc.store.SaveFile(path, f)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msg("Error saving file")
+	}
+
+	firstFrame := LocalPath(uc.config, seq.Dir+seq.First())
+	f.MimeType = DetectMimeType(firstFrame)
+
+	assetMetadata, err := uc.metadataUseCase.Collect(path, firstFrame)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error collecting metadata: %s", path)
+	}
+
+	asset := &icnk_client.Asset{Title: info.Name(), Status: "ACTIVE", Type: "ASSET"}
+	uc.metadataUseCase.PrepareAsset(asset, assetMetadata)
+
+	parentDir, err := uc.store.GetFile(strings.TrimRight(dirPath, "/"))
+	if err == nil {
+		asset.CollectionID = parentDir.ID
+	}
+
+	ctx := context.Background()
+
+	asset, versionID, err := uc.createAsset(ctx, assetID, asset)
+	if err != nil {
+		return f, err
+	}
+
+	f.AssetID = asset.ID
+	f.VersionID = versionID
+
+	err = uc.metadataUseCase.ApplyMetadata(ctx, asset.ID, assetMetadata)
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error applying metadata: %s", path)
+	}
+
+	components := make([]icnk_client.Component, 0, len(seq.Frames))
+	for _, frame := range seq.Frames {
+		components = append(components, icnk_client.Component{
+			Name: frame.Info.Name(), Type: icnk_client.ComponentImage,
+		})
+	}
+
+	format, err := uc.client.CreateAssetFormat(
+		ctx,
+		asset.ID,
+		&icnk_client.Format{
+			Name:           "ORIGINAL",
+			Status:         "ACTIVE",
+			Metadata:       []map[string]string{{"internet_media_type": f.MimeType}},
+			StorageMethods: []string{uc.storage.Method},
+			Components:     components,
+			VersionID:      versionID,
+		},
+	)
+	if err != nil {
+		return f, err
+	}
+
+	f.FormatID = format.ID
+
+	fileSet, err := uc.client.CreateFileSet(
+		ctx,
+		asset.ID, &icnk_client.FileSet{
+			FormatID:     format.ID,
+			StorageID:    uc.storage.ID,
+			BaseDir:      dirPath,
+			Name:         info.Name(),
+			ComponentIds: componentIDs(format),
+			VersionID:    versionID,
+		},
+	)
+	if err != nil {
+		return f, err
+	}
+
+	f.FileSetID = fileSet.ID
+
+	fileIDs, err := uc.uploadFrames(ctx, iconikStorage, asset.ID, format.ID, fileSet.ID, dirPath, seq)
+	// the first frame stands for the sequence
+	f.ID = fileIDs[0]
+	if err != nil {
+		return f, err
+	}
+
+	f.TranscodeStatus = entity.TranscodeStatusSkipped
+
+	return f, nil
+}
+
+// uploadFrames uploads the frames of the sequence in parallel and returns the
+// IDs of the created files in frame order
+func (uc *AssetUseCase) uploadFrames(
+	ctx context.Context,
+	iconikStorage storage.Storage,
+	assetID, formatID, fileSetID, dirPath string,
+	seq *sequence.Sequence,
+) ([]string, error) {
+	parallel := uc.config.Scanner.Sequences.Parallel
+	if parallel <= 0 {
+		parallel = defaultSequenceParallel
+	}
+
+	fileIDs := make([]string, len(seq.Frames))
+	errs := make([]error, len(seq.Frames))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range indexes {
+				frame := seq.Frames[index].Info
+
+				file, err := uc.uploadFile(ctx, iconikStorage, assetID, &icnk_client.File{
+					StorageID:        uc.storage.ID,
+					FormatID:         formatID,
+					FileSetID:        fileSetID,
+					Type:             "FILE",
+					DirectoryPath:    dirPath,
+					OriginalName:     frame.Name(),
+					Size:             frame.Size(),
+					FileDateCreated:  frame.ModTime().Format(time.RFC3339),
+					FileDateModified: frame.ModTime().Format(time.RFC3339),
+				}, LocalPath(uc.config, seq.Dir+frame.Name()))
+				if file != nil {
+					fileIDs[index] = file.ID
+				}
+				errs[index] = err
+			}
+		}()
+	}
+
+	for i := range seq.Frames {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	failed := 0
+	var firstErr error
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("frame %s: %w", seq.Frames[i].Info.Name(), err)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fileIDs, fmt.Errorf("%d of %d frames failed, %w", failed, len(seq.Frames), firstErr)
+	}
+
+	return fileIDs, nil
+}
+
+// ReadSequences detects the image sequences of a directory given by its store
+// key with a trailing slash
+func ReadSequences(config *config.Config, dir string) []*sequence.Sequence {
+	entries, err := os.ReadDir(LocalPath(config, dir))
+	if err != nil {
+		log.Error().Err(err).Str("service", "asset_usecase").Msgf("Error reading directory: %s", dir)
+		return nil
+	}
+
+	return DetectSequences(config, dir, entries)
+}
+
+// DetectSequences detects the image sequences of a directory from its entries
+// already read
+func DetectSequences(config *config.Config, dir string, entries []os.DirEntry) []*sequence.Sequence {
+	sequences := config.Scanner.Sequences
+	return sequence.DetectEntries(dir, entries, sequences.Extensions, sequences.MinFrames)
+}
+
+// FindSequence detects the sequence stored under a key again, it returns nil
+// when its frames are gone
+func FindSequence(config *config.Config, key string) *sequence.Sequence {
+	wanted, ok := sequence.FromKey(key)
+	if !ok {
+		return nil
+	}
+
+	for _, seq := range ReadSequences(config, wanted.Dir) {
+		if seq.Key() == key {
+			return seq
+		}
+	}
+
+	return nil
+}
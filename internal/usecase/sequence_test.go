@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupSequence(t *testing.T) (*config.Config, string) {
+	dir := t.TempDir() + "/"
+	assert.NoError(t, os.MkdirAll(dir+"shots", 0755))
+	for _, name := range []string{"shot_0001.exr", "shot_0002.exr", "shot_0003.exr", "notes.txt"} {
+		assert.NoError(t, os.WriteFile(dir+"shots/"+name, []byte("frame"), 0644))
+	}
+
+	cfg := &config.Config{
+		Scanner: config.ScannerConfig{Dir: dir, Sequences: config.SequencesConfig{Enabled: true, Parallel: 2}},
+	}
+
+	return cfg, dir
+}
+
+func TestUploadSequenceIfNotExists(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupSequence(t)
+
+	seq := FindSequence(cfg, "shots/shot_####.exr")
+	assert.NotNil(t, seq)
+	assert.Len(t, seq.Frames, 3)
+
+	assert.NoError(t, store.SaveFile("shots", &entity.File{Name: "shots", Type: "directory", ID: "C1"}))
+
+	mockClient := client.NewMockClient()
+	storage := &icnk_client.Storage{ID: "S1", Method: "S3"}
+
+	mockClient.On("CreateAsset", mock.Anything, &icnk_client.Asset{
+		Title: "shot_####.exr", Status: "ACTIVE", Type: "ASSET", CollectionID: "C1",
+	}).Return(&icnk_client.Asset{ID: "A1"}, nil).Once()
+	mockClient.On("CreateAssetFormat", mock.Anything, "A1", mock.MatchedBy(func(format *icnk_client.Format) bool {
+		return format.Name == "ORIGINAL" && len(format.Components) == 3 &&
+			format.Components[0] == icnk_client.Component{Name: "shot_0001.exr", Type: icnk_client.ComponentImage}
+	})).Return(&icnk_client.Format{ID: "FMT", Components: []icnk_client.Component{
+		{ID: "I1", Name: "shot_0001.exr"}, {ID: "I2", Name: "shot_0002.exr"}, {ID: "I3", Name: "shot_0003.exr"},
+	}}, nil).Once()
+	mockClient.On("CreateFileSet", mock.Anything, "A1", &icnk_client.FileSet{
+		FormatID: "FMT", StorageID: "S1", BaseDir: "shots/", Name: "shot_####.exr", ComponentIds: []string{"I1", "I2", "I3"},
+	}).Return(&icnk_client.FileSet{ID: "FS"}, nil).Once()
+	for _, frame := range seq.Frames {
+		name := frame.Info.Name()
+		mockClient.On("CreateFile", mock.Anything, "A1", mock.MatchedBy(func(file *icnk_client.File) bool {
+			return file.OriginalName == name && file.FileSetID == "FS" && file.DirectoryPath == "shots/"
+		})).Return(&icnk_client.File{ID: "F-" + name}, nil).Once()
+	}
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, "A1", mock.Anything).Return(nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, storage)
+	assert.NoError(t, assetUseCase.UploadSequenceIfNotExists(seq.Key(), seq))
+
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 1)
+	mockClient.AssertNumberOfCalls(t, "CreateFile", 3)
+	mockClient.AssertNumberOfCalls(t, "CloseFile", 3)
+
+	file, err := store.GetFile("shots/shot_####.exr")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateSynced, file.State)
+	assert.Equal(t, 3, file.Frames)
+	assert.Equal(t, 15, file.Size)
+	assert.Equal(t, "F-shot_0001.exr", file.ID)
+	assert.Equal(t, "shots/", file.DirectoryPath)
+	assert.Equal(t, entity.TranscodeStatusSkipped, file.TranscodeStatus)
+
+	// synced sequences are not uploaded again
+	assert.NoError(t, assetUseCase.UploadSequenceIfNotExists(seq.Key(), seq))
+	mockClient.AssertNumberOfCalls(t, "CreateAsset", 1)
+}
+
+func TestUploadSequenceFailedFrame(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg, _ := setupSequence(t)
+	seq := FindSequence(cfg, "shots/shot_####.exr")
+
+	mockClient := client.NewMockClient()
+	mockClient.On("CreateAsset", mock.Anything, mock.Anything).Return(&icnk_client.Asset{ID: "A1"}, nil)
+	mockClient.On("CreateAssetFormat", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.Format{ID: "FMT"}, nil)
+	mockClient.On("CreateFileSet", mock.Anything, mock.Anything, mock.Anything).Return(&icnk_client.FileSet{ID: "FS"}, nil)
+	mockClient.On("CreateFile", mock.Anything, "A1", mock.MatchedBy(func(file *icnk_client.File) bool {
+		return file.OriginalName == "shot_0002.exr"
+	})).Return(nil, errors.New("request failed: forbidden"))
+	mockClient.On("CreateFile", mock.Anything, "A1", mock.Anything).Return(&icnk_client.File{ID: "F"}, nil)
+	mockClient.On("Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockClient.On("CloseFile", mock.Anything, "A1", mock.Anything).Return(nil)
+
+	assetUseCase := NewAssetUseCase(cfg, mockClient, store, &icnk_client.Storage{ID: "S1", Method: "S3"})
+	err := assetUseCase.UploadSequenceIfNotExists(seq.Key(), seq)
+	assert.EqualError(t, err, "1 of 3 frames failed, frame shot_0002.exr: request failed: forbidden")
+
+	file, err := store.GetFile(seq.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StateFailed, file.State)
+	assert.Equal(t, "A1", file.AssetID)
+}
+
+func TestFindSequence(t *testing.T) {
+	cfg, dir := setupSequence(t)
+
+	assert.Nil(t, FindSequence(cfg, "shots/plate_####.exr"))
+	assert.Nil(t, FindSequence(cfg, "shots/notes.txt"))
+
+	for _, name := range []string{"shot_0001.exr", "shot_0002.exr", "shot_0003.exr"} {
+		assert.NoError(t, os.Remove(dir+"shots/"+name))
+	}
+	assert.Nil(t, FindSequence(cfg, "shots/shot_####.exr"))
+}
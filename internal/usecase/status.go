@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"sort"
+
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/progress"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+// FileStatus is the sync state of a single stored file
+type FileStatus struct {
+	Path      string `json:"path"`
+	State     string `json:"state"`
+	Size      int    `json:"size"`
+	Error     string `json:"error,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// StatusSummary summarises the sync state of the store
+type StatusSummary struct {
+	Files          map[string]int      `json:"files"`
+	Directories    map[string]int      `json:"directories"`
+	SyncedBytes    int64               `json:"synced_bytes"`
+	RecentFailures []FileStatus        `json:"recent_failures"`
+	OldestPending  *FileStatus         `json:"oldest_pending,omitempty"`
+	InFlight       []progress.Snapshot `json:"in_flight,omitempty"`
+	// Transcodes counts the uploaded files by transcode status
+	Transcodes              map[string]int `json:"transcodes"`
+	RecentTranscodeFailures []FileStatus   `json:"recent_transcode_failures"`
+}
+
+type StatusUseCase struct {
+	store store.Store
+}
+
+func NewStatusUseCase(store store.Store) *StatusUseCase {
+	return &StatusUseCase{store: store}
+}
+
+// Summary counts the stored files and directories by state and transcode
+// status and returns up to failuresLimit of the most recent upload and
+// transcode failures
+func (uc *StatusUseCase) Summary(failuresLimit int) (*StatusSummary, error) {
+	summary := &StatusSummary{
+		Files:                   newStateCounts(),
+		Directories:             newStateCounts(),
+		RecentFailures:          []FileStatus{},
+		Transcodes:              newTranscodeCounts(),
+		RecentTranscodeFailures: []FileStatus{},
+	}
+
+	failures := []FileStatus{}
+	transcodeFailures := []FileStatus{}
+
+	err := uc.store.WalkFiles("", func(path string, file *entity.File) error {
+		state := file.CurrentState()
+
+		if file.IsDir() {
+			summary.Directories[state]++
+			return nil
+		}
+		summary.Files[state]++
+
+		status := FileStatus{
+			Path:      path,
+			State:     state,
+			Size:      file.Size,
+			Error:     file.Error,
+			UpdatedAt: file.UpdatedAt,
+		}
+
+		switch state {
+		case entity.StateSynced:
+			summary.SyncedBytes += int64(file.Size)
+		case entity.StateFailed:
+			failures = append(failures, status)
+		case entity.StatePending:
+			if summary.OldestPending == nil || olderThan(status, *summary.OldestPending) {
+				summary.OldestPending = &status
+			}
+		}
+
+		if file.TranscodeStatus != "" {
+			summary.Transcodes[file.TranscodeStatus]++
+		}
+		if file.TranscodeStatus == entity.TranscodeStatusFailed {
+			status.Error = file.TranscodeError
+			transcodeFailures = append(transcodeFailures, status)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary.RecentFailures = recentFailures(failures, failuresLimit)
+	summary.RecentTranscodeFailures = recentFailures(transcodeFailures, failuresLimit)
+
+	return summary, nil
+}
+
+// recentFailures returns up to limit of the failures, most recent first, a
+// negative limit returns none
+func recentFailures(failures []FileStatus, limit int) []FileStatus {
+	if limit < 0 {
+		limit = 0
+	}
+
+	sort.SliceStable(failures, func(i, j int) bool {
+		return failures[i].UpdatedAt > failures[j].UpdatedAt
+	})
+	if len(failures) > limit {
+		failures = failures[:limit]
+	}
+	return failures
+}
+
+func newStateCounts() map[string]int {
+	return map[string]int{
+		entity.StatePending:   0,
+		entity.StateUploading: 0,
+		entity.StateSynced:    0,
+		entity.StateFailed:    0,
+	}
+}
+
+func newTranscodeCounts() map[string]int {
+	return map[string]int{
+		entity.TranscodeStatusQueued:   0,
+		entity.TranscodeStatusDeferred: 0,
+		entity.TranscodeStatusSkipped:  0,
+		entity.TranscodeStatusLocal:    0,
+		entity.TranscodeStatusFinished: 0,
+		entity.TranscodeStatusFailed:   0,
+	}
+}
+
+// olderThan compares the times of state changes, records without a time are
+// considered newer
+func olderThan(a, b FileStatus) bool {
+	if a.UpdatedAt == "" {
+		return false
+	}
+	if b.UpdatedAt == "" {
+		return true
+	}
+	return a.UpdatedAt < b.UpdatedAt
+}
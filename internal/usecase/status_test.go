@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusSummary(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	files := map[string]*entity.File{
+		"rushes":            {Name: "rushes", Type: "directory", ID: "C1"},
+		"rushes/a.mov":      {Name: "a.mov", Size: 100, State: entity.StateSynced, ID: "F1", TranscodeStatus: entity.TranscodeStatusFinished},
+		"rushes/b.mov":      {Name: "b.mov", Size: 50, ID: "F2"},
+		"rushes/c.mov":      {Name: "c.mov", Size: 10, State: entity.StatePending, UpdatedAt: "2024-05-01T12:00:00Z"},
+		"rushes/d.mov":      {Name: "d.mov", Size: 10, State: entity.StatePending, UpdatedAt: "2024-05-01T10:00:00Z"},
+		"rushes/e.mov":      {Name: "e.mov", Size: 20, State: entity.StateUploading},
+		"rushes/f.mov":      {Name: "f.mov", State: entity.StateFailed, Error: "forbidden", UpdatedAt: "2024-05-01T09:00:00Z"},
+		"rushes/g.mov":      {Name: "g.mov", State: entity.StateFailed, Error: "timeout", UpdatedAt: "2024-05-02T09:00:00Z"},
+		"rushes/h.mov":      {Name: "h.mov", State: entity.StateFailed, Error: "reset", UpdatedAt: "2024-05-03T09:00:00Z"},
+		"rushes/day1/i.wav": {Name: "i.wav", Size: 5, State: entity.StateSynced, TranscodeStatus: entity.TranscodeStatusQueued},
+		"rushes/day1/k.mxf": {
+			Name: "k.mxf", State: entity.StateSynced, UpdatedAt: "2024-05-01T08:00:00Z",
+			TranscodeStatus: entity.TranscodeStatusFailed, TranscodeError: "unsupported codec",
+		},
+		"rushes/day1/j.json": {Name: "j.json"},
+	}
+	for path, file := range files {
+		assert.NoError(t, store.SaveFile(path, file))
+	}
+
+	summary, err := NewStatusUseCase(store).Summary(2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]int{
+		entity.StatePending:   3,
+		entity.StateUploading: 1,
+		entity.StateSynced:    4,
+		entity.StateFailed:    3,
+	}, summary.Files)
+	assert.Equal(t, 1, summary.Directories[entity.StateSynced])
+	assert.Equal(t, int64(155), summary.SyncedBytes)
+
+	assert.Equal(t, []FileStatus{
+		{Path: "rushes/h.mov", State: entity.StateFailed, Error: "reset", UpdatedAt: "2024-05-03T09:00:00Z"},
+		{Path: "rushes/g.mov", State: entity.StateFailed, Error: "timeout", UpdatedAt: "2024-05-02T09:00:00Z"},
+	}, summary.RecentFailures)
+
+	assert.Equal(t, "rushes/d.mov", summary.OldestPending.Path)
+
+	assert.Equal(t, 1, summary.Transcodes[entity.TranscodeStatusFinished])
+	assert.Equal(t, 1, summary.Transcodes[entity.TranscodeStatusQueued])
+	assert.Equal(t, 1, summary.Transcodes[entity.TranscodeStatusFailed])
+	assert.Equal(t, 0, summary.Transcodes[entity.TranscodeStatusDeferred])
+	assert.Equal(t, []FileStatus{
+		{
+			Path: "rushes/day1/k.mxf", State: entity.StateSynced,
+			Error: "unsupported codec", UpdatedAt: "2024-05-01T08:00:00Z",
+		},
+	}, summary.RecentTranscodeFailures)
+}
+
+func TestStatusSummaryNegativeFailures(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	file := &entity.File{Name: "a.mov", State: entity.StateFailed, Error: "forbidden", TranscodeStatus: entity.TranscodeStatusFailed}
+	assert.NoError(t, store.SaveFile("a.mov", file))
+
+	summary, err := NewStatusUseCase(store).Summary(-1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, summary.Files[entity.StateFailed])
+	assert.Empty(t, summary.RecentFailures)
+	assert.Empty(t, summary.RecentTranscodeFailures)
+}
@@ -0,0 +1,331 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultTranscodeMaxAttempts = 3
+
+const (
+	TranscodeActionTranscode = "transcode"
+	TranscodeActionSkip      = "skip"
+	TranscodeActionDefer     = "defer"
+)
+
+// TranscodePolicy is the decision of the transcoding rules for a file
+type TranscodePolicy struct {
+	Action   string
+	Priority int
+}
+
+type TranscodeUseCase struct {
+	config *config.Config
+	client icnk_client.Client
+	store  store.Store
+}
+
+func NewTranscodeUseCase(
+	config *config.Config, client icnk_client.Client, store store.Store,
+) *TranscodeUseCase {
+	return &TranscodeUseCase{
+		config: config,
+		client: client,
+		store:  store,
+	}
+}
+
+// Policy returns the transcoding policy for the file, the first matching rule
+// wins and unset rule values fall back to the defaults
+func (uc *TranscodeUseCase) Policy(filePath string) TranscodePolicy {
+	transcodingConfig := uc.config.Transcoding
+
+	policy := TranscodePolicy{
+		Action:   transcodingConfig.Action,
+		Priority: transcodingConfig.Priority,
+	}
+
+	for _, rule := range transcodingConfig.Rules {
+		if !matchesRule(rule, filePath) {
+			continue
+		}
+
+		if rule.Action != "" {
+			policy.Action = rule.Action
+		}
+		if rule.Priority != 0 {
+			policy.Priority = rule.Priority
+		}
+		break
+	}
+
+	if policy.Action == "" {
+		policy.Action = TranscodeActionTranscode
+	}
+	if policy.Priority == 0 {
+		policy.Priority = icnk_client.DefaultTranscodeOptions.Priority
+	}
+
+	return policy
+}
+
+// Transcode applies the transcoding policy to an uploaded file and records
+// the outcome on it
+func (uc *TranscodeUseCase) Transcode(ctx context.Context, filePath string, file *entity.File) error {
+	policy := uc.Policy(filePath)
+	file.TranscodePriority = policy.Priority
+
+	switch policy.Action {
+	case TranscodeActionSkip:
+		file.TranscodeStatus = entity.TranscodeStatusSkipped
+		return nil
+	case TranscodeActionDefer:
+		if !uc.InWindow(time.Now()) {
+			file.TranscodeStatus = entity.TranscodeStatusDeferred
+			return nil
+		}
+	case TranscodeActionTranscode:
+	default:
+		return fmt.Errorf("unknown transcoding action: %s", policy.Action)
+	}
+
+	return uc.trigger(ctx, file)
+}
+
+// TriggerDeferred triggers transcoding of deferred files when the batch
+// window is open
+func (uc *TranscodeUseCase) TriggerDeferred(ctx context.Context) error {
+	if !uc.InWindow(time.Now()) {
+		return nil
+	}
+
+	deferred := map[string]*entity.File{}
+	err := uc.store.WalkFiles("", func(path string, file *entity.File) error {
+		if file.TranscodeStatus == entity.TranscodeStatusDeferred {
+			deferred[path] = file
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for path, file := range deferred {
+		if err := uc.trigger(ctx, file); err != nil {
+			log.Error().Err(err).Str("service", "transcode_usecase").Msgf("Error triggering transcoding: %s", path)
+			continue
+		}
+
+		if err := uc.saveTranscode(path, file); err != nil {
+			return err
+		}
+	}
+
+	if len(deferred) > 0 {
+		log.Info().Str("service", "transcode_usecase").Msgf("Triggered %d deferred transcodes", len(deferred))
+	}
+
+	return nil
+}
+
+// PollJobs follows the transcode jobs of queued files, records when they
+// finish or fail and retriggers failed jobs up to the configured attempts
+func (uc *TranscodeUseCase) PollJobs(ctx context.Context) error {
+	queued := map[string]*entity.File{}
+	err := uc.store.WalkFiles("", func(path string, file *entity.File) error {
+		if file.TranscodeStatus == entity.TranscodeStatusQueued && file.TranscodeJobID != "" {
+			queued[path] = file
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for path, file := range queued {
+		job, err := uc.client.GetJob(ctx, file.TranscodeJobID)
+		if err != nil {
+			log.Error().Err(err).Str("service", "transcode_usecase").Msgf("Error getting transcode job: %s", path)
+			continue
+		}
+
+		switch job.Status {
+		case icnk_client.JobStatusFinished:
+			file.TranscodeStatus = entity.TranscodeStatusFinished
+			file.TranscodeError = ""
+
+			log.Info().
+				Str("service", "transcode_usecase").
+				Str("job_id", job.ID).
+				Msgf("Transcoding finished: %s", path)
+		case icnk_client.JobStatusFailed, icnk_client.JobStatusAborted:
+			uc.retry(ctx, path, file, job)
+		default:
+			continue
+		}
+
+		if err := uc.saveTranscode(path, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uc *TranscodeUseCase) retry(ctx context.Context, path string, file *entity.File, job *icnk_client.Job) {
+	maxAttempts := uc.config.Transcoding.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultTranscodeMaxAttempts
+	}
+
+	file.TranscodeError = job.ErrorMessage
+	if file.TranscodeError == "" {
+		file.TranscodeError = fmt.Sprintf("job %s", strings.ToLower(job.Status))
+	}
+
+	if file.TranscodeAttempts < maxAttempts {
+		log.Warn().
+			Str("service", "transcode_usecase").
+			Str("job_id", job.ID).
+			Str("error", file.TranscodeError).
+			Msgf("Transcoding failed, retrying (attempt %d of %d): %s", file.TranscodeAttempts+1, maxAttempts, path)
+
+		err := uc.trigger(ctx, file)
+		if err == nil {
+			return
+		}
+
+		// the attempt counts and the failed job is retried on the next poll
+		file.TranscodeAttempts++
+		file.TranscodeError = err.Error()
+		if file.TranscodeAttempts < maxAttempts {
+			log.Warn().
+				Err(err).
+				Str("service", "transcode_usecase").
+				Msgf("Error retriggering transcoding: %s", path)
+			return
+		}
+	}
+
+	file.TranscodeStatus = entity.TranscodeStatusFailed
+
+	log.Error().
+		Str("service", "transcode_usecase").
+		Str("job_id", job.ID).
+		Str("error", file.TranscodeError).
+		Msgf("Transcoding failed after %d attempts: %s", file.TranscodeAttempts, path)
+}
+
+// errOtherFile is returned when a record refers to another Iconik file than
+// the one transcoded
+var errOtherFile = errors.New("record of another file")
+
+// saveTranscode writes the transcode fields of a file to its record, which may
+// have changed during the calls to Iconik, e.g. by a post-upload action or a
+// requeue. Records removed or uploaded again in the meantime are left alone.
+func (uc *TranscodeUseCase) saveTranscode(path string, file *entity.File) error {
+	err := uc.store.UpdateFile(path, func(stored *entity.File) error {
+		if stored.ID != file.ID {
+			return errOtherFile
+		}
+
+		stored.TranscodeStatus = file.TranscodeStatus
+		stored.TranscodeJobID = file.TranscodeJobID
+		stored.TranscodePriority = file.TranscodePriority
+		stored.TranscodeAttempts = file.TranscodeAttempts
+		stored.TranscodeError = file.TranscodeError
+		return nil
+	})
+	if errors.Is(err, store.ErrFileNotFound) || errors.Is(err, errOtherFile) {
+		log.Debug().Str("service", "transcode_usecase").Msgf("Not saving transcoding of a changed file: %s", path)
+		return nil
+	}
+
+	return err
+}
+
+// InWindow reports whether deferred transcoding may run at the given time. A
+// missing window is always open.
+func (uc *TranscodeUseCase) InWindow(now time.Time) bool {
+	window := uc.config.Transcoding.Window
+	if window.Start == "" || window.End == "" {
+		return true
+	}
+
+	start, errStart := time.Parse("15:04", window.Start)
+	end, errEnd := time.Parse("15:04", window.End)
+	if errStart != nil || errEnd != nil {
+		log.Error().Str("service", "transcode_usecase").Msg("Invalid transcoding window")
+		return false
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	if startMinutes <= endMinutes {
+		return minutes >= startMinutes && minutes < endMinutes
+	}
+	return minutes >= startMinutes || minutes < endMinutes
+}
+
+func (uc *TranscodeUseCase) trigger(ctx context.Context, file *entity.File) error {
+	options := icnk_client.DefaultTranscodeOptions
+	options.Priority = file.TranscodePriority
+	if uc.config.Transcoding.UseStorageIgnorePattern != nil {
+		options.UseStorageTranscodeIgnorePattern = *uc.config.Transcoding.UseStorageIgnorePattern
+	}
+
+	jobID, err := uc.client.TriggerTranscoding(ctx, file.AssetID, file.ID, &options)
+	if err != nil {
+		return err
+	}
+
+	file.TranscodeJobID = jobID
+	file.TranscodeStatus = entity.TranscodeStatusQueued
+	file.TranscodeAttempts++
+
+	return nil
+}
+
+func matchesRule(rule config.TranscodeRuleConfig, filePath string) bool {
+	if len(rule.Extensions) > 0 {
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), ".")
+
+		matched := false
+		for _, ruleExt := range rule.Extensions {
+			if strings.TrimPrefix(strings.ToLower(ruleExt), ".") == ext {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(rule.Paths) == 0 {
+		return true
+	}
+
+	for _, pattern := range rule.Paths {
+		for p := filePath; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
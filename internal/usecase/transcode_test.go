@@ -0,0 +1,269 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/iconik/client"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTranscodePolicy(t *testing.T) {
+	cfg := &config.Config{
+		Transcoding: config.TranscodingConfig{
+			Priority: 3,
+			Rules: []config.TranscodeRuleConfig{
+				{Extensions: []string{"jpg", ".CR3"}, Action: "skip"},
+				{Paths: []string{"archive"}, Action: "defer", Priority: 1},
+				{Paths: []string{"rushes/*"}, Extensions: []string{"mov"}, Priority: 9},
+			},
+		},
+	}
+
+	transcodeUseCase := NewTranscodeUseCase(cfg, client.NewMockClient(), nil)
+
+	tests := []struct {
+		path     string
+		expected TranscodePolicy
+	}{
+		{"photos/IMG_0001.JPG", TranscodePolicy{Action: "skip", Priority: 3}},
+		{"photos/IMG_0001.cr3", TranscodePolicy{Action: "skip", Priority: 3}},
+		{"archive/2020/clip.mov", TranscodePolicy{Action: "defer", Priority: 1}},
+		{"rushes/day1/clip.mov", TranscodePolicy{Action: "transcode", Priority: 9}},
+		{"rushes/day1/clip.mxf", TranscodePolicy{Action: "transcode", Priority: 3}},
+		{"other/clip.mov", TranscodePolicy{Action: "transcode", Priority: 3}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, transcodeUseCase.Policy(tt.path), tt.path)
+	}
+
+	defaultUseCase := NewTranscodeUseCase(&config.Config{}, client.NewMockClient(), nil)
+	assert.Equal(t, TranscodePolicy{Action: "transcode", Priority: 5}, defaultUseCase.Policy("clip.mov"))
+}
+
+func TestTranscodeInWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, time.Local)
+	}
+
+	cfg := &config.Config{}
+	transcodeUseCase := NewTranscodeUseCase(cfg, client.NewMockClient(), nil)
+	assert.True(t, transcodeUseCase.InWindow(at(12, 0)))
+
+	cfg.Transcoding.Window = config.TranscodeWindowConfig{Start: "09:00", End: "17:30"}
+	assert.True(t, transcodeUseCase.InWindow(at(9, 0)))
+	assert.True(t, transcodeUseCase.InWindow(at(17, 29)))
+	assert.False(t, transcodeUseCase.InWindow(at(17, 30)))
+	assert.False(t, transcodeUseCase.InWindow(at(8, 59)))
+
+	cfg.Transcoding.Window = config.TranscodeWindowConfig{Start: "22:00", End: "06:00"}
+	assert.True(t, transcodeUseCase.InWindow(at(23, 0)))
+	assert.True(t, transcodeUseCase.InWindow(at(1, 0)))
+	assert.False(t, transcodeUseCase.InWindow(at(12, 0)))
+}
+
+func TestTranscodeAndTriggerDeferred(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	useIgnorePattern := false
+	cfg := &config.Config{
+		Transcoding: config.TranscodingConfig{
+			UseStorageIgnorePattern: &useIgnorePattern,
+			Rules: []config.TranscodeRuleConfig{
+				{Extensions: []string{"jpg"}, Action: "skip"},
+				{Extensions: []string{"mov"}, Action: "defer", Priority: 2},
+			},
+		},
+	}
+
+	client := client.NewMockClient()
+	transcodeUseCase := NewTranscodeUseCase(cfg, client, store)
+
+	photo := &entity.File{AssetID: "A1", ID: "F1"}
+	err := transcodeUseCase.Transcode(context.Background(), "photo.jpg", photo)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusSkipped, photo.TranscodeStatus)
+
+	// deferral outside of the batch window
+	now := time.Now()
+	cfg.Transcoding.Window = config.TranscodeWindowConfig{
+		Start: now.Add(2 * time.Hour).Format("15:04"),
+		End:   now.Add(3 * time.Hour).Format("15:04"),
+	}
+
+	clip := &entity.File{AssetID: "A2", ID: "F2"}
+	err = transcodeUseCase.Transcode(context.Background(), "clip.mov", clip)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusDeferred, clip.TranscodeStatus)
+	assert.Equal(t, 2, clip.TranscodePriority)
+
+	err = store.SaveFile("clip.mov", clip)
+	assert.NoError(t, err)
+
+	err = transcodeUseCase.TriggerDeferred(context.Background())
+	assert.NoError(t, err)
+	client.AssertNotCalled(t, "TriggerTranscoding")
+
+	// the window opens
+	cfg.Transcoding.Window = config.TranscodeWindowConfig{}
+	client.On(
+		"TriggerTranscoding",
+		mock.Anything,
+		"A2",
+		"F2",
+		&icnk_client.TranscodeOptions{Priority: 2, UseStorageTranscodeIgnorePattern: false},
+	).Return("C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8", nil)
+
+	err = transcodeUseCase.TriggerDeferred(context.Background())
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+
+	clip, err = store.GetFile("clip.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusQueued, clip.TranscodeStatus)
+	assert.Equal(t, "C2BC2D18-FBF5-4D89-92B3-35E586ABCCD8", clip.TranscodeJobID)
+}
+
+func TestTranscodePollJobs(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Transcoding: config.TranscodingConfig{MaxAttempts: 2}}
+
+	client := client.NewMockClient()
+	transcodeUseCase := NewTranscodeUseCase(cfg, client, store)
+
+	files := map[string]*entity.File{
+		"done.mov":    {AssetID: "A1", ID: "F1", TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J1", TranscodeAttempts: 1},
+		"running.mov": {AssetID: "A2", ID: "F2", TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J2", TranscodeAttempts: 1},
+		"retry.mov":   {AssetID: "A3", ID: "F3", TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J3", TranscodeAttempts: 1, TranscodePriority: 4},
+		"failed.mov":  {AssetID: "A4", ID: "F4", TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J4", TranscodeAttempts: 2},
+		"skipped.mov": {AssetID: "A5", ID: "F5", TranscodeStatus: entity.TranscodeStatusSkipped},
+	}
+	for path, file := range files {
+		assert.NoError(t, store.SaveFile(path, file))
+	}
+
+	client.On("GetJob", mock.Anything, "J1").Return(&icnk_client.Job{ID: "J1", Status: icnk_client.JobStatusFinished}, nil)
+	client.On("GetJob", mock.Anything, "J2").Return(&icnk_client.Job{ID: "J2", Status: "STARTED"}, nil)
+	client.On("GetJob", mock.Anything, "J3").Return(&icnk_client.Job{ID: "J3", Status: icnk_client.JobStatusFailed}, nil)
+	client.On("GetJob", mock.Anything, "J4").Return(
+		&icnk_client.Job{ID: "J4", Status: icnk_client.JobStatusFailed, ErrorMessage: "unsupported codec"}, nil,
+	)
+	client.On(
+		"TriggerTranscoding",
+		mock.Anything,
+		"A3",
+		"F3",
+		&icnk_client.TranscodeOptions{Priority: 4, UseStorageTranscodeIgnorePattern: true},
+	).Return("J5", nil)
+
+	err := transcodeUseCase.PollJobs(context.Background())
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+	client.AssertNumberOfCalls(t, "GetJob", 4)
+
+	done, err := store.GetFile("done.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusFinished, done.TranscodeStatus)
+
+	running, err := store.GetFile("running.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusQueued, running.TranscodeStatus)
+
+	retried, err := store.GetFile("retry.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusQueued, retried.TranscodeStatus)
+	assert.Equal(t, "J5", retried.TranscodeJobID)
+	assert.Equal(t, 2, retried.TranscodeAttempts)
+	assert.Equal(t, "job failed", retried.TranscodeError)
+
+	failed, err := store.GetFile("failed.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusFailed, failed.TranscodeStatus)
+	assert.Equal(t, "unsupported codec", failed.TranscodeError)
+}
+
+func TestTranscodePollJobs_ConcurrentWrites(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	client := client.NewMockClient()
+	transcodeUseCase := NewTranscodeUseCase(&config.Config{}, client, store)
+
+	files := map[string]*entity.File{
+		"done.mov":     {AssetID: "A1", ID: "F1", TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J1"},
+		"replaced.mov": {AssetID: "A2", ID: "F2", TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J2"},
+	}
+	for path, file := range files {
+		assert.NoError(t, store.SaveFile(path, file))
+	}
+
+	// the records change while the jobs are fetched
+	client.On("GetJob", mock.Anything, "J1").Run(func(args mock.Arguments) {
+		assert.NoError(t, store.SaveFile("done.mov", &entity.File{
+			AssetID: "A1", ID: "F1", State: entity.StateSynced, LocalAction: "delete",
+			TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J1",
+		}))
+	}).Return(&icnk_client.Job{ID: "J1", Status: icnk_client.JobStatusFinished}, nil)
+	client.On("GetJob", mock.Anything, "J2").Run(func(args mock.Arguments) {
+		assert.NoError(t, store.SaveFile("replaced.mov", &entity.File{AssetID: "A3", ID: "F3", State: entity.StatePending}))
+	}).Return(&icnk_client.Job{ID: "J2", Status: icnk_client.JobStatusFinished}, nil)
+
+	assert.NoError(t, transcodeUseCase.PollJobs(context.Background()))
+
+	done, err := store.GetFile("done.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusFinished, done.TranscodeStatus)
+	assert.Equal(t, "delete", done.LocalAction)
+
+	// the transcoding of a file uploaded again is not recorded on its new record
+	replaced, err := store.GetFile("replaced.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, "F3", replaced.ID)
+	assert.Equal(t, "", replaced.TranscodeStatus)
+}
+
+func TestTranscodePollJobs_TriggerError(t *testing.T) {
+	store, _, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Transcoding: config.TranscodingConfig{MaxAttempts: 3}}
+
+	client := client.NewMockClient()
+	transcodeUseCase := NewTranscodeUseCase(cfg, client, store)
+
+	assert.NoError(t, store.SaveFile("retry.mov", &entity.File{
+		AssetID: "A1", ID: "F1", TranscodeStatus: entity.TranscodeStatusQueued, TranscodeJobID: "J1", TranscodeAttempts: 1,
+	}))
+
+	client.On("GetJob", mock.Anything, "J1").Return(&icnk_client.Job{ID: "J1", Status: icnk_client.JobStatusFailed}, nil)
+	client.On("TriggerTranscoding", mock.Anything, "A1", "F1", mock.Anything).Return("", errors.New("service unavailable"))
+
+	// a failed trigger counts as an attempt and the file stays queued
+	assert.NoError(t, transcodeUseCase.PollJobs(context.Background()))
+
+	file, err := store.GetFile("retry.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusQueued, file.TranscodeStatus)
+	assert.Equal(t, "J1", file.TranscodeJobID)
+	assert.Equal(t, 2, file.TranscodeAttempts)
+	assert.Equal(t, "service unavailable", file.TranscodeError)
+
+	// it fails once the attempts are used up
+	assert.NoError(t, transcodeUseCase.PollJobs(context.Background()))
+
+	file, err = store.GetFile("retry.mov")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TranscodeStatusFailed, file.TranscodeStatus)
+	assert.Equal(t, 3, file.TranscodeAttempts)
+	client.AssertNumberOfCalls(t, "TriggerTranscoding", 2)
+}
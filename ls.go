@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/admin"
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+func newLsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls [prefix]",
+		Short: "List the files and directories stored under a path prefix",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   RunLs,
+	}
+
+	cmd.Flags().Bool("json", false, "Print the entries as JSON")
+	cmd.Flags().String("state", "", "Only list entries in this state (synced, pending, uploading, failed)")
+
+	return cmd
+}
+
+func RunLs(cmd *cobra.Command, args []string) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	state, _ := cmd.Flags().GetString("state")
+
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var entries []usecase.Entry
+
+	err = readState(
+		config,
+		func(store store.Store) (err error) {
+			entries, err = usecase.NewBrowseUseCase(config, store).List(prefix, state)
+			return err
+		},
+		func(ctx context.Context, client *admin.Client) (err error) {
+			entries, err = client.Entries(ctx, prefix, state)
+			return err
+		},
+	)
+	if err != nil {
+		fmt.Printf("Error reading the sync state: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(entries)
+		return
+	}
+
+	printEntries(os.Stdout, entries)
+}
+
+func printEntries(w io.Writer, entries []usecase.Entry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "STATE\tSIZE\tID\tPATH")
+	for _, entry := range entries {
+		file := entry.File
+
+		size := "-"
+		id := file.AssetID
+		if file.IsDir() {
+			id = file.ID
+			entry.Path += "/"
+		} else {
+			size = humanize.Bytes(uint64(file.Size))
+		}
+		if id == "" {
+			id = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", file.CurrentState(), size, id, entry.Path)
+	}
+}
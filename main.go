@@ -11,10 +11,16 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/kgantsov/synconik/internal/admin"
 	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/health"
 	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/janitor"
+	"github.com/kgantsov/synconik/internal/progress"
 	"github.com/kgantsov/synconik/internal/scanner"
+	"github.com/kgantsov/synconik/internal/server"
 	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/transcoder"
 	"github.com/kgantsov/synconik/internal/uploader"
 )
 
@@ -34,6 +40,11 @@ func Run(cmd *cobra.Command, args []string) {
 
 	client := icnk_client.NewClient(httpClient, config.Iconik.URL, config.Iconik.AppID, config.Iconik.Token)
 
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		RunDryRun(config, client)
+		return
+	}
+
 	badgerStore, err := store.NewBadgerStore(config.Store.DataDir)
 
 	if err != nil {
@@ -41,6 +52,11 @@ func Run(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	progressReporter := progress.NewReporter(
+		progress.Default, time.Duration(config.Uploader.ProgressInterval)*time.Second,
+	)
+	progressReporter.Start()
+
 	var uploadJobQueue chan uploader.Job
 	uploadJobQueue = make(chan uploader.Job)
 
@@ -57,6 +73,36 @@ func Run(cmd *cobra.Command, args []string) {
 		log.Error().Msgf("Error creating scanner: %v", err)
 		return
 	}
+
+	transcoder := transcoder.NewTranscoder(config, badgerStore, client)
+	transcoder.Start()
+
+	janitor := janitor.NewJanitor(config, badgerStore, client)
+	janitor.Start()
+
+	checker := health.NewChecker()
+	checker.AddLiveness("scanner", health.RunningCheck("scanner", scanner.Running))
+	checker.AddLiveness("uploader", health.RunningCheck("uploader", uploader.Running))
+	checker.AddLiveness("transcoder", health.RunningCheck("transcoder", transcoder.Running))
+	checker.AddLiveness("janitor", health.RunningCheck("janitor", janitor.Running))
+	addReadinessChecks(checker, config, client, badgerStore)
+
+	server := server.NewServer(config, checker)
+	err = server.Start()
+	if err != nil {
+		log.Error().Msgf("Error starting HTTP server: %v", err)
+		return
+	}
+
+	adminServer := admin.NewServer(config, scanner, uploader, badgerStore, progress.Default)
+	err = adminServer.Start()
+	if err != nil {
+		log.Error().Msgf("Error starting admin API: %v", err)
+		return
+	}
+
+	// the first scan runs before Start returns, which takes long on large
+	// trees, the health checks and the admin API answer in the meantime
 	scanner.Start()
 
 	done := make(chan struct{})
@@ -72,14 +118,31 @@ func Run(cmd *cobra.Command, args []string) {
 
 	<-done
 
+	adminServer.Stop()
+
+	// queued jobs are drained on shutdown, so a paused uploader must resume
+	uploader.Resume()
 	scanner.Stop()
+	transcoder.Stop()
+	janitor.Stop()
 	uploader.Stop()
+	progressReporter.Stop()
+	server.Stop()
 
 	time.Sleep(time.Second * 1)
 }
 
 func main() {
 	rootCmd := config.InitCobraCommand(Run)
+	rootCmd.Flags().Bool("dry-run", false, "Print what a scan would upload without changing anything")
+	rootCmd.AddCommand(newDoctorCommand())
+	rootCmd.AddCommand(newStatusCommand())
+	rootCmd.AddCommand(newLsCommand())
+	rootCmd.AddCommand(newInspectCommand())
+	rootCmd.AddCommand(newReconcileCommand())
+	rootCmd.AddCommand(newRebuildStateCommand())
+	rootCmd.AddCommand(newSyncCommand())
+	rootCmd.AddCommand(newPushCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		log.Warn().Err(err)
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/manifest"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+func newPushCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push [path...]",
+		Short: "Upload an explicit list of files",
+		Run:   RunPush,
+	}
+
+	cmd.Flags().String("list", "", "File with one path per line, - for stdin")
+	cmd.Flags().String("manifest", "", "CSV manifest with path, collection_id, title and metadata columns, - for stdin")
+	cmd.Flags().Bool("json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+func RunPush(cmd *cobra.Command, args []string) {
+	listPath, _ := cmd.Flags().GetString("list")
+	manifestPath, _ := cmd.Flags().GetString("manifest")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	entries := []manifest.Entry{}
+	for _, path := range args {
+		entries = append(entries, manifest.Entry{Path: path})
+	}
+
+	if listPath != "" {
+		list, err := readManifest(listPath, manifest.ReadList)
+		if err != nil {
+			fmt.Printf("Error reading the list: %v\n", err)
+			os.Exit(1)
+		}
+		entries = append(entries, list...)
+	}
+
+	if manifestPath != "" {
+		csvEntries, err := readManifest(manifestPath, manifest.ReadCSV)
+		if err != nil {
+			fmt.Printf("Error reading the manifest: %v\n", err)
+			os.Exit(1)
+		}
+		entries = append(entries, csvEntries...)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Nothing to push, give paths, --list or --manifest")
+		os.Exit(1)
+	}
+
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.ConfigureLogger()
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	client := icnk_client.NewClient(httpClient, config.Iconik.URL, config.Iconik.AppID, config.Iconik.Token)
+
+	storage, err := client.GetStorage(context.Background(), config.Iconik.StorageID)
+	if err != nil {
+		fmt.Printf("Error getting storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	badgerStore, err := store.NewBadgerStore(config.Store.DataDir)
+	if err != nil {
+		fmt.Printf("Error opening the database, stop synconik before pushing files: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := usecase.NewPushUseCase(config, client, badgerStore, storage).Push(entries)
+	badgerStore.Close()
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printPushReport(os.Stdout, report)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// readManifest parses the file at path, or stdin for -
+func readManifest(path string, read func(r io.Reader) ([]manifest.Entry, error)) ([]manifest.Entry, error) {
+	if path == "-" {
+		return read(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return read(file)
+}
+
+func printPushReport(w io.Writer, report *usecase.PushReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "RESULT\tSIZE\tPATH\tDETAIL")
+	for _, result := range report.Results {
+		detail := result.Reason
+		if result.Error != "" {
+			detail = result.Error
+		}
+		fmt.Fprintf(
+			tw, "%s\t%s\t%s\t%s\n",
+			result.Result, humanize.Bytes(uint64(result.Size)), result.Path, detail,
+		)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintf(tw, "Uploaded:\t%d (%s)\n", report.Uploaded, humanize.Bytes(uint64(report.UploadedBytes)))
+	fmt.Fprintf(tw, "Skipped:\t%d\n", report.Skipped)
+	fmt.Fprintf(tw, "Failed:\t%d\n", report.Failed)
+
+	tw.Flush()
+}
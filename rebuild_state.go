@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+func newRebuildStateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rebuild-state",
+		Short: "Repopulate the local state database from the files and collections in Iconik",
+		Run:   RunRebuildState,
+	}
+
+	cmd.Flags().Bool("json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+func RunRebuildState(cmd *cobra.Command, args []string) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.ConfigureLogger()
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	client := icnk_client.NewClient(httpClient, config.Iconik.URL, config.Iconik.AppID, config.Iconik.Token)
+
+	badgerStore, err := store.NewBadgerStore(config.Store.DataDir)
+	if err != nil {
+		fmt.Printf("Error opening the database, stop synconik before rebuilding it: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	storage, err := client.GetStorage(ctx, config.Iconik.StorageID)
+	if err != nil {
+		badgerStore.Close()
+		fmt.Printf("Error getting storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	report, err := usecase.NewRebuildUseCase(config, client, badgerStore, storage).RebuildState(ctx)
+	badgerStore.Close()
+	if err != nil {
+		fmt.Printf("Error rebuilding the state: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+
+	fmt.Printf("Restored %d files and %d directories\n", report.Files, report.Directories)
+	fmt.Printf("Already synced:          %d\n", report.Stored)
+	fmt.Printf("Not found locally:       %d\n", report.NotLocal)
+	fmt.Printf("Size differs:            %d\n", report.SizeMismatch)
+	fmt.Printf("Uploaded more than once: %d\n", report.Duplicates)
+}
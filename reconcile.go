@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+func newReconcileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare the local state database with Iconik and report or fix discrepancies",
+		Run:   RunReconcile,
+	}
+
+	cmd.Flags().Bool("fix", false, "Fix the discrepancies instead of only reporting them")
+	cmd.Flags().Bool("json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+func RunReconcile(cmd *cobra.Command, args []string) {
+	fix, _ := cmd.Flags().GetBool("fix")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.ConfigureLogger()
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	client := icnk_client.NewClient(httpClient, config.Iconik.URL, config.Iconik.AppID, config.Iconik.Token)
+
+	// fixing writes to the database, which is locked while synconik is running
+	var badgerStore *store.BadgerStore
+	if fix {
+		badgerStore, err = store.NewBadgerStore(config.Store.DataDir)
+	} else {
+		badgerStore, err = store.NewReadOnlyBadgerStore(config.Store.DataDir)
+	}
+	if err != nil {
+		fmt.Printf("Error opening the database, stop synconik before reconciling: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	storage, err := client.GetStorage(ctx, config.Iconik.StorageID)
+	if err != nil {
+		badgerStore.Close()
+		fmt.Printf("Error getting storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	report, err := usecase.NewReconcileUseCase(config, client, badgerStore, storage).Reconcile(ctx, fix)
+	badgerStore.Close()
+	if err != nil {
+		fmt.Printf("Error reconciling: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printReconcileReport(os.Stdout, report, fix)
+	}
+
+	for _, discrepancy := range report.Discrepancies {
+		if fix && !discrepancy.Fixed {
+			os.Exit(1)
+		}
+	}
+}
+
+func printReconcileReport(w io.Writer, report *usecase.ReconcileReport, fix bool) {
+	if len(report.Discrepancies) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(tw, "KIND\tACTION\tRESULT\tPATH\tDETAIL")
+		for _, discrepancy := range report.Discrepancies {
+			result := "planned"
+			if discrepancy.Fixed {
+				result = "fixed"
+			} else if discrepancy.Error != "" {
+				result = "error: " + discrepancy.Error
+			}
+
+			fmt.Fprintf(
+				tw, "%s\t%s\t%s\t%s\t%s\n",
+				discrepancy.Kind, discrepancy.Action, result, discrepancy.Path, discrepancy.Detail,
+			)
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Checked %d records, found %d discrepancies\n", report.Checked, len(report.Discrepancies))
+	if !fix && len(report.Discrepancies) > 0 {
+		fmt.Fprintln(w, "Run with --fix to apply the actions")
+	}
+}
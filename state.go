@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kgantsov/synconik/internal/admin"
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+// readState runs local against the database opened read-only. The database
+// is locked while synconik is running, in that case remote is run against the
+// admin API of the running instance.
+func readState(
+	config *config.Config,
+	local func(store store.Store) error,
+	remote func(ctx context.Context, client *admin.Client) error,
+) error {
+	badgerStore, err := store.NewReadOnlyBadgerStore(config.Store.DataDir)
+	if err == nil {
+		defer badgerStore.Close()
+		return local(badgerStore)
+	}
+
+	client, clientErr := admin.NewClient(config)
+	if clientErr != nil {
+		return fmt.Errorf(
+			"%w (if synconik is running, enable the admin API to query its state)", err,
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return remote(ctx, client)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/admin"
+	"github.com/kgantsov/synconik/internal/config"
+	"github.com/kgantsov/synconik/internal/entity"
+	"github.com/kgantsov/synconik/internal/progress"
+	"github.com/kgantsov/synconik/internal/store"
+	"github.com/kgantsov/synconik/internal/usecase"
+)
+
+func newStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Summarise the sync state stored in the local database",
+		Run:   RunStatus,
+	}
+
+	cmd.Flags().Bool("json", false, "Print the summary as JSON")
+	cmd.Flags().Int("failures", 10, "Number of recent failures to show")
+
+	return cmd
+}
+
+func RunStatus(cmd *cobra.Command, args []string) {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	failures, _ := cmd.Flags().GetInt("failures")
+	if failures < 0 {
+		fmt.Printf("Error: --failures must not be negative, got %d\n", failures)
+		cmd.Usage()
+		os.Exit(1)
+	}
+
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	summary, err := loadStatus(config, failures)
+	if err != nil {
+		fmt.Printf("Error reading the sync state: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(summary)
+		return
+	}
+
+	printStatus(os.Stdout, summary)
+}
+
+func loadStatus(config *config.Config, failures int) (*usecase.StatusSummary, error) {
+	var summary *usecase.StatusSummary
+
+	err := readState(
+		config,
+		func(store store.Store) (err error) {
+			summary, err = usecase.NewStatusUseCase(store).Summary(failures)
+			return err
+		},
+		func(ctx context.Context, client *admin.Client) (err error) {
+			summary, err = client.Status(ctx, failures)
+			return err
+		},
+	)
+
+	return summary, err
+}
+
+func printStatus(w io.Writer, summary *usecase.StatusSummary) {
+	states := []string{entity.StateSynced, entity.StatePending, entity.StateUploading, entity.StateFailed}
+
+	fmt.Fprintf(w, "%-12s %10s %12s\n", "STATE", "FILES", "DIRECTORIES")
+	for _, state := range states {
+		fmt.Fprintf(w, "%-12s %10d %12d\n", state, summary.Files[state], summary.Directories[state])
+	}
+
+	fmt.Fprintf(w, "\nSynced: %s\n", humanize.Bytes(uint64(summary.SyncedBytes)))
+
+	if summary.OldestPending != nil {
+		fmt.Fprintf(w, "Oldest pending: %s", summary.OldestPending.Path)
+		if summary.OldestPending.UpdatedAt != "" {
+			fmt.Fprintf(w, " (since %s)", summary.OldestPending.UpdatedAt)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(summary.InFlight) > 0 {
+		fmt.Fprintln(w, "\nIn flight:")
+		for _, snapshot := range summary.InFlight {
+			fmt.Fprintf(
+				w, "  %s %.1f%% of %s at %s/s, ETA %s\n",
+				snapshot.Path,
+				snapshot.Percent,
+				humanize.Bytes(uint64(snapshot.Size)),
+				humanize.Bytes(uint64(snapshot.Rate)),
+				progress.FormatETA(snapshot.ETA),
+			)
+		}
+	}
+
+	if len(summary.RecentFailures) > 0 {
+		fmt.Fprintln(w, "\nRecent failures:")
+		for _, failure := range summary.RecentFailures {
+			fmt.Fprintf(w, "  %s  %s: %s\n", failure.UpdatedAt, failure.Path, failure.Error)
+		}
+	}
+
+	printTranscodes(w, summary)
+}
+
+// printTranscodes prints the transcode counts once any uploaded file has a
+// transcode status
+func printTranscodes(w io.Writer, summary *usecase.StatusSummary) {
+	statuses := []string{
+		entity.TranscodeStatusFinished,
+		entity.TranscodeStatusQueued,
+		entity.TranscodeStatusDeferred,
+		entity.TranscodeStatusLocal,
+		entity.TranscodeStatusSkipped,
+		entity.TranscodeStatusFailed,
+	}
+
+	total := 0
+	for _, status := range statuses {
+		total += summary.Transcodes[status]
+	}
+	if total == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%-12s %10s\n", "TRANSCODE", "FILES")
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%-12s %10d\n", status, summary.Transcodes[status])
+	}
+
+	if len(summary.RecentTranscodeFailures) > 0 {
+		fmt.Fprintln(w, "\nRecent transcode failures:")
+		for _, failure := range summary.RecentTranscodeFailures {
+			fmt.Fprintf(w, "  %s  %s: %s\n", failure.UpdatedAt, failure.Path, failure.Error)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/kgantsov/synconik/internal/config"
+	icnk_client "github.com/kgantsov/synconik/internal/iconik/client"
+	"github.com/kgantsov/synconik/internal/pipeline"
+	"github.com/kgantsov/synconik/internal/store"
+)
+
+func newSyncCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync the scanned directory with Iconik",
+		Run:   RunSync,
+	}
+
+	cmd.Flags().Bool("once", false, "Run a single scan, wait for the uploads and exit")
+	cmd.Flags().Bool("json", false, "Print the summary of --once as JSON")
+
+	return cmd
+}
+
+func RunSync(cmd *cobra.Command, args []string) {
+	once, _ := cmd.Flags().GetBool("once")
+	if !once {
+		Run(cmd, args)
+		return
+	}
+
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	config, err := config.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.ConfigureLogger()
+
+	httpClient := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+
+	client := icnk_client.NewClient(httpClient, config.Iconik.URL, config.Iconik.AppID, config.Iconik.Token)
+
+	badgerStore, err := store.NewBadgerStore(config.Store.DataDir)
+	if err != nil {
+		fmt.Printf("Error opening the database, stop synconik before a one-shot sync: %v\n", err)
+		os.Exit(1)
+	}
+
+	summary, err := pipeline.RunOnce(config, badgerStore, client, false)
+	badgerStore.Close()
+	if err != nil {
+		fmt.Printf("Error syncing: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(summary)
+	} else {
+		printSyncSummary(os.Stdout, summary)
+	}
+
+	if len(summary.Failed) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printSyncSummary(w io.Writer, summary *pipeline.Summary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if len(summary.Failed) > 0 {
+		fmt.Fprintln(tw, "Failed:")
+		for _, file := range summary.Failed {
+			fmt.Fprintf(tw, "  %s\t%s\n", file.Path, file.Error)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	skipped := map[string]int{}
+	reasons := []string{}
+	for _, file := range summary.Skipped {
+		if skipped[file.Reason] == 0 {
+			reasons = append(reasons, file.Reason)
+		}
+		skipped[file.Reason]++
+	}
+	sort.Strings(reasons)
+
+	fmt.Fprintf(tw, "Uploaded:\t%d (%s)\n", summary.Uploaded, humanize.Bytes(uint64(summary.UploadedBytes)))
+	fmt.Fprintf(tw, "Skipped:\t%d\n", len(summary.Skipped))
+	for _, reason := range reasons {
+		fmt.Fprintf(tw, "  %s:\t%d\n", reason, skipped[reason])
+	}
+	fmt.Fprintf(tw, "Failed:\t%d\n", len(summary.Failed))
+	fmt.Fprintf(tw, "Duration:\t%s\n", time.Duration(summary.Duration*float64(time.Second)).Round(time.Millisecond))
+
+	tw.Flush()
+}